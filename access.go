@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// accessCheck probes a single IAM action used by the plugin
+type accessCheck struct {
+	Action  string
+	UsedFor string
+	Check   func(ctx context.Context, cfg aws.Config) error
+}
+
+// accessChecks lists every AWS API call the plugin makes. Keep it in sync when adding AWS features.
+var accessChecks = []accessCheck{
+	{
+		Action:  "ec2:DescribeInstances",
+		UsedFor: "-o wide/cost/json/yaml/table, --at-max, --spot-only, --open-asg, wait, join-lag, why-gone, label, taint, asgs, problems, daemon, describe, audit, amis, spot-pools, pods",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+				DryRun: aws.Bool(true),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "autoscaling:DescribeAutoScalingGroups",
+		UsedFor: "-o wide/cost/json/yaml/table, --at-max, rollout-status, asgs, daemon, describe, problems, audit, spot-pools",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := autoscaling.NewFromConfig(cfg).DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+				MaxRecords: aws.Int32(1),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeVolumes",
+		UsedFor: "-o wide/json/yaml, describe",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+				DryRun: aws.Bool(true),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeLaunchTemplateVersions",
+		UsedFor: "asgs, spot-pools",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeLaunchTemplateVersions(ctx, &ec2.DescribeLaunchTemplateVersionsInput{
+				DryRun:   aws.Bool(true),
+				Versions: []string{"$Latest"},
+			})
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeSpotInstanceRequests",
+		UsedFor: "why-gone",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeSpotInstanceRequests(ctx, &ec2.DescribeSpotInstanceRequestsInput{
+				DryRun: aws.Bool(true),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "autoscaling:DescribeScalingActivities",
+		UsedFor: "why-gone, -o asg",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := autoscaling.NewFromConfig(cfg).DescribeScalingActivities(ctx, &autoscaling.DescribeScalingActivitiesInput{
+				MaxRecords: aws.Int32(1),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeLaunchTemplates",
+		UsedFor: "-o wide/json/yaml/table, rollout-status, asgs",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeLaunchTemplates(ctx, &ec2.DescribeLaunchTemplatesInput{
+				DryRun: aws.Bool(true),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeSpotPriceHistory",
+		UsedFor: "-o cost, cost, consolidation",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeSpotPriceHistory(ctx, &ec2.DescribeSpotPriceHistoryInput{
+				DryRun: aws.Bool(true),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "pricing:GetProducts",
+		UsedFor: "-o cost, cost, consolidation",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			var output struct{}
+			return newPricingClient(cfg).call(ctx, "GetProducts", map[string]interface{}{
+				"ServiceCode": "AmazonEC2",
+				"MaxResults":  1,
+			}, &output)
+		},
+	},
+	{
+		Action:  "autoscaling:DescribeAutoScalingInstances",
+		UsedFor: "recycle",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := autoscaling.NewFromConfig(cfg).DescribeAutoScalingInstances(ctx, &autoscaling.DescribeAutoScalingInstancesInput{
+				MaxRecords: aws.Int32(1),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "autoscaling:TerminateInstanceInAutoScalingGroup",
+		UsedFor: "recycle",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// There is no dry run: an unknown instance fails validation only after authorization
+			_, err := autoscaling.NewFromConfig(cfg).TerminateInstanceInAutoScalingGroup(ctx, &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+				InstanceId:                     aws.String("i-00000000000000000"),
+				ShouldDecrementDesiredCapacity: aws.Bool(false),
+			})
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		Action:  "autoscaling:SetDesiredCapacity",
+		UsedFor: "--scale-asg",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// Like TerminateInstanceInAutoScalingGroup, an unknown group fails validation after authorization
+			_, err := autoscaling.NewFromConfig(cfg).SetDesiredCapacity(ctx, &autoscaling.SetDesiredCapacityInput{
+				AutoScalingGroupName: aws.String("kubectl-aws-nodes-access-check"),
+				DesiredCapacity:      aws.Int32(0),
+			})
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		Action:  "autoscaling:DescribeInstanceRefreshes",
+		UsedFor: "-o wide/json/yaml/table, --start-instance-refresh",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := autoscaling.NewFromConfig(cfg).DescribeInstanceRefreshes(ctx, &autoscaling.DescribeInstanceRefreshesInput{
+				AutoScalingGroupName: aws.String("kubectl-aws-nodes-access-check"),
+				MaxRecords:           aws.Int32(1),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "autoscaling:StartInstanceRefresh",
+		UsedFor: "--start-instance-refresh",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// Like SetDesiredCapacity, an unknown group fails validation after authorization
+			_, err := autoscaling.NewFromConfig(cfg).StartInstanceRefresh(ctx, &autoscaling.StartInstanceRefreshInput{
+				AutoScalingGroupName: aws.String("kubectl-aws-nodes-access-check"),
+			})
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationError" {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeInstanceTypes",
+		UsedFor: "cpu-topology, confidential",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+				DryRun: aws.Bool(true),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeImages",
+		UsedFor: "-o wide/json/yaml/table, plan-upgrade, amis",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeImages(ctx, &ec2.DescribeImagesInput{
+				DryRun: aws.Bool(true),
+				Owners: []string{"self"},
+			})
+			return err
+		},
+	},
+	{
+		Action:  "ssm:GetParameter",
+		UsedFor: "plan-upgrade",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := getSSMParameter(ctx, newSSMClient(cfg), "/aws/service/eks/optimized-ami/1.30/amazon-linux-2023/x86_64/standard/recommended/image_id")
+			return err
+		},
+	},
+	{
+		Action:  "ec2:DescribeSecurityGroups",
+		UsedFor: "sg-check",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+				DryRun: aws.Bool(true),
+			})
+			return err
+		},
+	},
+	{
+		Action:  "eks:DescribeCluster",
+		UsedFor: "sg-check",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// An unknown cluster is only reported after authorization
+			_, err := describeEKSClusterVPCConfig(ctx, newEKSClient(cfg), "check-access")
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ResourceNotFoundException" {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		Action:  "eks:DescribeNodegroup",
+		UsedFor: "-o wide, json, yaml (managed node groups)",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// An unknown cluster is only reported after authorization
+			_, err := describeEKSNodegroup(ctx, newEKSClient(cfg), "check-access", "check-access")
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ResourceNotFoundException" {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		Action:  "ssm:SendCommand",
+		UsedFor: "clock-drift --chrony, cpu-topology --numa",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// An unknown document is only reported after authorization
+			var output struct{}
+			err := newSSMClient(cfg).call(ctx, "SendCommand", map[string]interface{}{
+				"DocumentName": "check-access-nonexistent",
+				"InstanceIds":  []string{"i-00000000000000000"},
+			}, &output)
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "InvalidDocument" || apiErr.ErrorCode() == "InvalidInstanceId") {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		Action:  "ssm:GetCommandInvocation",
+		UsedFor: "clock-drift --chrony, cpu-topology --numa",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// An unknown command is only reported after authorization
+			var output struct{}
+			err := newSSMClient(cfg).call(ctx, "GetCommandInvocation", map[string]string{
+				"CommandId":  "00000000-0000-0000-0000-000000000000",
+				"InstanceId": "i-00000000000000000",
+			}, &output)
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvocationDoesNotExist" {
+				return nil
+			}
+			return err
+		},
+	},
+	{
+		Action:  "health:DescribeEvents",
+		UsedFor: "health, --health",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			var output struct{}
+			return newHealthClient(cfg).call(ctx, "DescribeEvents", map[string]interface{}{"maxResults": 10}, &output)
+		},
+	},
+	{
+		Action:  "health:DescribeAffectedEntities",
+		UsedFor: "health, --health",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// An unknown event ARN returns no entities but still goes through authorization
+			var output struct{}
+			arn := "arn:aws:health:us-east-1::event/EC2/AWS_EC2_OPERATIONAL_ISSUE/check-access"
+			return newHealthClient(cfg).call(ctx, "DescribeAffectedEntities", map[string]interface{}{
+				"filter": map[string]interface{}{"eventArns": []string{arn}},
+			}, &output)
+		},
+	},
+	{
+		Action:  "s3:PutObject",
+		UsedFor: "daemon",
+		Check: func(ctx context.Context, cfg aws.Config) error {
+			// There is no dry run and the daemon's bucket isn't known here, so the upload goes to a
+			// bucket that doesn't exist: NoSuchBucket means the signed request was accepted
+			err := putS3Object(ctx, cfg, "kubectl-aws-nodes-access-check", "check-access", nil, "text/plain", "")
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchBucket" {
+				return nil
+			}
+			return err
+		},
+	},
+}
+
+func runCheckAccess(args []string) {
+	fs := flag.NewFlagSet("check-access", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check-access\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Verify that the current AWS identity may call every AWS API the plugin uses.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.TODO()
+	identity, err := sts.NewFromConfig(awsConfig).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting caller identity: %v\n", classifyAWSError(err))
+		os.Exit(1)
+	}
+	fmt.Printf("Identity: %s\n", aws.ToString(identity.Arn))
+	fmt.Printf("Region:   %s\n\n", awsConfig.Region)
+
+	denied := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tRESULT\tUSED-FOR\tDETAIL")
+	for _, check := range accessChecks {
+		result, detail := accessResult(check.Check(ctx, awsConfig))
+		if result != "allowed" {
+			denied++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.Action, result, check.UsedFor, detail)
+	}
+	w.Flush()
+
+	if denied > 0 {
+		os.Exit(1)
+	}
+}
+
+// accessResult interprets the outcome of a probe call
+func accessResult(err error) (result, detail string) {
+	if err == nil {
+		return "allowed", ""
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "DryRunOperation":
+			return "allowed", ""
+		case "UnauthorizedOperation", "AccessDenied", "AccessDeniedException":
+			return "denied", ""
+		}
+	}
+
+	var classified *awsError
+	if errors.As(classifyAWSError(err), &classified) {
+		return "error", classified.Msg
+	}
+	return "error", err.Error()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultCriticalDaemonSets are the node addons without which pods scheduled to a node can't
+// work: CNI plugins, kube-proxy and the CSI node plugins. Those not installed are skipped.
+var defaultCriticalDaemonSets = []string{
+	"aws-node", "cilium", "calico-node", "kube-proxy", "ebs-csi-node", "efs-csi-node",
+}
+
+// addonGracePeriod is how long after becoming Ready a node may still be starting its addon pods
+const addonGracePeriod = 2 * time.Minute
+
+// daemonSetTolerations are added to every DaemonSet pod by the DaemonSet controller
+var daemonSetTolerations = []v1.Toleration{
+	{Key: v1.TaintNodeNotReady, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+	{Key: v1.TaintNodeUnreachable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+	{Key: v1.TaintNodeDiskPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+	{Key: v1.TaintNodeMemoryPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+	{Key: v1.TaintNodePIDPressure, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+	{Key: v1.TaintNodeUnschedulable, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+}
+
+// criticalDaemonSets returns the DaemonSet names checked on every node, from the config or the defaults
+func (c *Config) criticalDaemonSets() []string {
+	if c != nil && len(c.CriticalDaemonSets) > 0 {
+		return c.CriticalDaemonSets
+	}
+	return defaultCriticalDaemonSets
+}
+
+// notServiceableProblems reports Ready nodes lacking a Ready pod of a critical addon DaemonSet
+// that should run there. Such nodes accept pods that then fail for lack of networking or storage.
+func notServiceableProblems(clientset *kubernetes.Clientset, nodes []v1.Node, pods []v1.Pod, names []string) ([]problem, error) {
+	daemonSets, err := clientset.AppsV1().DaemonSets("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var critical []appsv1.DaemonSet
+	for _, ds := range daemonSets.Items {
+		if containsString(names, ds.Name) {
+			critical = append(critical, ds)
+		}
+	}
+	sort.Slice(critical, func(i, j int) bool {
+		return critical[i].Namespace+"/"+critical[i].Name < critical[j].Namespace+"/"+critical[j].Name
+	})
+
+	// Ready addon pods per node, by namespace/name of their DaemonSet
+	readyAddons := make(map[string]map[string]bool)
+	for _, pod := range pods {
+		for _, owner := range pod.OwnerReferences {
+			if owner.Kind != "DaemonSet" || !isPodReady(pod) {
+				continue
+			}
+			if readyAddons[pod.Spec.NodeName] == nil {
+				readyAddons[pod.Spec.NodeName] = make(map[string]bool)
+			}
+			readyAddons[pod.Spec.NodeName][pod.Namespace+"/"+owner.Name] = true
+		}
+	}
+
+	var problems []problem
+	for _, node := range nodes {
+		if getNodeStatus(node) != "Ready" || time.Since(readySince(node)) < addonGracePeriod {
+			continue
+		}
+		var missing []string
+		for _, ds := range critical {
+			key := ds.Namespace + "/" + ds.Name
+			if runsOnNode(ds, node) && !readyAddons[node.Name][key] {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, problem{"node", node.Name, "NotServiceable: no Ready pod of " + strings.Join(missing, ", ")})
+		}
+	}
+	return problems, nil
+}
+
+// runsOnNode reports whether the DaemonSet places a pod on the node, from its template's
+// nodeSelector, node affinity and tolerations
+func runsOnNode(ds appsv1.DaemonSet, node v1.Node) bool {
+	pod := v1.Pod{Spec: *ds.Spec.Template.Spec.DeepCopy()}
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, daemonSetTolerations...)
+	return matchesNodeSelector(pod, node) && matchesNodeAffinity(pod, node) && toleratesNodeTaints(pod, node)
+}
+
+// readySince returns when the node's Ready condition last changed
+func readySince(node v1.Node) time.Time {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// eksAMIRelease matches the Kubernetes version and release date at the end of EKS-optimized AMI
+// names, e.g. amazon-eks-node-al2023-x86_64-standard-1.29-v20240227
+var eksAMIRelease = regexp.MustCompile(`-(\d+\.\d+)-(v\d{8})$`)
+
+// amiUsage is one AMI and the nodes running it
+type amiUsage struct {
+	ImageID string
+	Nodes   int
+	Image   *types.Image
+}
+
+func runAMIs(args []string) {
+	fs := flag.NewFlagSet("amis", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s amis\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Summarize how many nodes run each AMI, with the AMI name, age and EKS release, newest first,\n")
+		fmt.Fprintf(os.Stderr, "to follow an AMI rollout and spot the nodes it has not reached.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+
+	images, err := getNodeImages(awsConfig, nodes.Items, instanceMap)
+	if err != nil {
+		warnf("could not describe AMIs, names and ages are not shown: %v\n", err)
+	}
+
+	usage := make(map[string]*amiUsage)
+	for _, node := range nodes.Items {
+		imageID := "<unknown>"
+		if instance, exists := instanceMap[getInstanceID(node)]; exists {
+			imageID = aws.ToString(instance.ImageId)
+		}
+		if usage[imageID] == nil {
+			usage[imageID] = &amiUsage{ImageID: imageID}
+			if image, exists := images[imageID]; exists {
+				usage[imageID].Image = &image
+			}
+		}
+		usage[imageID].Nodes++
+	}
+
+	if len(usage) == 0 {
+		fmt.Println("No nodes found")
+		return
+	}
+
+	var rows []*amiUsage
+	for _, u := range usage {
+		rows = append(rows, u)
+	}
+	// Newest AMI first, so the rollout target leads and stragglers trail
+	sort.Slice(rows, func(i, j int) bool {
+		ci, cj := imageCreationTime(rows[i].Image), imageCreationTime(rows[j].Image)
+		if !ci.Equal(cj) {
+			return ci.After(cj)
+		}
+		return rows[i].ImageID < rows[j].ImageID
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "AMI\tNAME\tNODES\tAGE\tEKS-RELEASE")
+	for _, u := range rows {
+		name, age, release := "-", "-", "-"
+		if u.Image != nil {
+			name = aws.ToString(u.Image.Name)
+			if created := imageCreationTime(u.Image); !created.IsZero() {
+				age = formatAge(time.Since(created))
+			}
+			release = eksRelease(name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d (%.0f%%)\t%s\t%s\n", u.ImageID, name, u.Nodes,
+			float64(u.Nodes)*100/float64(len(nodes.Items)), age, release)
+	}
+	w.Flush()
+}
+
+// applyAMI sets the node's AMI and its age, marking it stale when older than staleDays (if set)
+func applyAMI(nodeInfo *NodeInfo, instance types.Instance, images map[string]types.Image, staleDays int) {
+	nodeInfo.AMI = aws.ToString(instance.ImageId)
+	image, exists := images[nodeInfo.AMI]
+	if !exists {
+		return
+	}
+	created := imageCreationTime(&image)
+	if created.IsZero() {
+		return
+	}
+	age := time.Since(created)
+	nodeInfo.AMIAge = formatAge(age)
+	nodeInfo.StaleAMI = staleDays > 0 && age > time.Duration(staleDays)*24*time.Hour
+}
+
+// getNodeImages describes the AMIs of the nodes' instances in the region each node lives in.
+// It filters by image ID rather than asking for the IDs, so deregistered AMIs are left out
+// instead of failing the whole call.
+func getNodeImages(cfg aws.Config, nodes []v1.Node, instanceMap map[string]types.Instance) (map[string]types.Image, error) {
+	idsByRegion := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		instance, exists := instanceMap[getInstanceID(node)]
+		imageID := aws.ToString(instance.ImageId)
+		if !exists || imageID == "" || seen[imageID] {
+			continue
+		}
+		seen[imageID] = true
+		region := nodeRegion(node, cfg.Region)
+		idsByRegion[region] = append(idsByRegion[region], imageID)
+	}
+
+	images := make(map[string]types.Image)
+	for region, ids := range idsByRegion {
+		client := ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+		for start := 0; start < len(ids); start += describeInstancesChunkSize {
+			end := start + describeInstancesChunkSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+			paginator := ec2.NewDescribeImagesPaginator(client, &ec2.DescribeImagesInput{
+				Filters: []types.Filter{{Name: aws.String("image-id"), Values: ids[start:end]}},
+			})
+			for paginator.HasMorePages() {
+				result, err := paginator.NextPage(context.TODO())
+				if err != nil {
+					return images, fmt.Errorf("in %s: %w", region, classifyAWSError(err))
+				}
+				for _, image := range result.Images {
+					images[aws.ToString(image.ImageId)] = image
+				}
+			}
+		}
+	}
+	return images, nil
+}
+
+// imageCreationTime parses the AMI's creation date, zero if unknown
+func imageCreationTime(image *types.Image) time.Time {
+	if image == nil {
+		return time.Time{}
+	}
+	created, err := time.Parse(time.RFC3339, aws.ToString(image.CreationDate))
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// eksRelease returns the Kubernetes version and release of an EKS-optimized AMI name, e.g.
+// "1.29 v20240227", or "-" for other AMIs
+func eksRelease(imageName string) string {
+	match := eksAMIRelease.FindStringSubmatch(imageName)
+	if match == nil || detectAMIFamily(imageName) == nil {
+		return "-"
+	}
+	return match[1] + " " + match[2]
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const clusterTagPrefix = "kubernetes.io/cluster/"
+
+func runASGs(args []string) {
+	fs := flag.NewFlagSet("asgs", flag.ExitOnError)
+	clusterName := fs.String("cluster", "", "Cluster name used in ASG tags (default: detected from node instance tags)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s asgs [--cluster NAME]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List the cluster's Auto Scaling Groups, including empty ones, with how many members are registered nodes.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+	registered := make(map[string]bool)
+	for _, node := range nodes.Items {
+		registered[getInstanceID(node)] = true
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	if *clusterName == "" {
+		instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+			os.Exit(1)
+		}
+		*clusterName = detectClusterName(instanceMap, registered)
+	}
+
+	// Groups are listed in the regions of the nodes, and the configured one for groups without any
+	var groups []astypes.AutoScalingGroup
+	ec2Clients := make(map[string]*ec2.Client) // by ASG name
+	for _, region := range nodeRegions(nodes.Items, awsConfig.Region) {
+		asgClient := autoscaling.NewFromConfig(awsConfig, func(o *autoscaling.Options) { o.Region = region })
+		ec2Client := ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = region })
+		regionGroups, err := getAutoScalingGroups(context.TODO(), asgClient)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting Auto Scaling Groups in %s: %v\n", region, err)
+			os.Exit(1)
+		}
+		for _, asg := range regionGroups {
+			ec2Clients[aws.ToString(asg.AutoScalingGroupName)] = ec2Client
+		}
+		groups = append(groups, regionGroups...)
+	}
+
+	if *clusterName == "" {
+		warnf("could not detect the cluster name, showing ASGs with registered nodes only (use --cluster)\n")
+	}
+
+	var selected []astypes.AutoScalingGroup
+	for _, asg := range groups {
+		if belongsToCluster(asg, *clusterName, registered) {
+			selected = append(selected, asg)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return aws.ToString(selected[i].AutoScalingGroupName) < aws.ToString(selected[j].AutoScalingGroupName)
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCAPACITY\tINSTANCES\tNODES\tINSTANCE-TYPES\tLAUNCH-TEMPLATE")
+	for _, asg := range selected {
+		nodeCount := 0
+		for _, instance := range asg.Instances {
+			if registered[aws.ToString(instance.InstanceId)] {
+				nodeCount++
+			}
+		}
+		capacity := ASGCapacity{
+			Min:     aws.ToInt32(asg.MinSize),
+			Max:     aws.ToInt32(asg.MaxSize),
+			Desired: aws.ToInt32(asg.DesiredCapacity),
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n",
+			aws.ToString(asg.AutoScalingGroupName), capacity, len(asg.Instances), nodeCount,
+			strings.Join(asgInstanceTypes(ec2Clients[aws.ToString(asg.AutoScalingGroupName)], asg), ","),
+			asgLaunchTemplateVersion(ec2Clients[aws.ToString(asg.AutoScalingGroupName)], asg))
+	}
+	w.Flush()
+}
+
+// clusterRegions lists the configured region and the regions of the cluster's nodes, or just the
+// configured one when the nodes can't be listed
+func clusterRegions(cfg aws.Config) []string {
+	clientset, err := getClientset()
+	if err != nil {
+		return []string{cfg.Region}
+	}
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return []string{cfg.Region}
+	}
+	return nodeRegions(nodes.Items, cfg.Region)
+}
+
+// locateASG describes an ASG given by name in the first of the cluster's regions that has it,
+// for commands that are given a group rather than a node
+func locateASG(cfg aws.Config, name string) (*astypes.AutoScalingGroup, string, error) {
+	regions := clusterRegions(cfg)
+	for _, region := range regions {
+		client := autoscaling.NewFromConfig(cfg, func(o *autoscaling.Options) { o.Region = region })
+		result, err := client.DescribeAutoScalingGroups(context.TODO(), &autoscaling.DescribeAutoScalingGroupsInput{
+			AutoScalingGroupNames: []string{name},
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("in %s: %w", region, classifyAWSError(err))
+		}
+		if len(result.AutoScalingGroups) > 0 {
+			return &result.AutoScalingGroups[0], region, nil
+		}
+	}
+	return nil, "", fmt.Errorf("ASG '%s' not found in %s", name, strings.Join(regions, ", "))
+}
+
+func getAutoScalingGroups(ctx context.Context, client *autoscaling.Client) ([]astypes.AutoScalingGroup, error) {
+	var groups []astypes.AutoScalingGroup
+	paginator := autoscaling.NewDescribeAutoScalingGroupsPaginator(client, &autoscaling.DescribeAutoScalingGroupsInput{})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, classifyAWSError(err)
+		}
+		groups = append(groups, result.AutoScalingGroups...)
+	}
+	return groups, nil
+}
+
+// detectClusterName reads the cluster name from the kubernetes.io/cluster/<name> tag of registered node instances
+func detectClusterName(instanceMap map[string]types.Instance, registered map[string]bool) string {
+	for instanceID, instance := range instanceMap {
+		if !registered[instanceID] {
+			continue
+		}
+		for _, tag := range instance.Tags {
+			if key := aws.ToString(tag.Key); strings.HasPrefix(key, clusterTagPrefix) {
+				return strings.TrimPrefix(key, clusterTagPrefix)
+			}
+		}
+	}
+	return ""
+}
+
+// belongsToCluster matches ASGs tagged for the cluster, or with at least one registered member
+func belongsToCluster(asg astypes.AutoScalingGroup, clusterName string, registered map[string]bool) bool {
+	if clusterName != "" {
+		for _, tag := range asg.Tags {
+			key := aws.ToString(tag.Key)
+			if key == clusterTagPrefix+clusterName || key == "k8s.io/cluster-autoscaler/"+clusterName {
+				return true
+			}
+		}
+	}
+	for _, instance := range asg.Instances {
+		if registered[aws.ToString(instance.InstanceId)] {
+			return true
+		}
+	}
+	return false
+}
+
+// asgInstanceTypes lists the instance types an ASG can launch
+func asgInstanceTypes(client *ec2.Client, asg astypes.AutoScalingGroup) []string {
+	seen := make(map[string]bool)
+	var instanceTypes []string
+	add := func(instanceType string) {
+		if instanceType != "" && !seen[instanceType] {
+			seen[instanceType] = true
+			instanceTypes = append(instanceTypes, instanceType)
+		}
+	}
+
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		for _, override := range asg.MixedInstancesPolicy.LaunchTemplate.Overrides {
+			add(aws.ToString(override.InstanceType))
+		}
+	}
+	if len(instanceTypes) == 0 {
+		if spec := asgLaunchTemplate(asg); spec != nil {
+			add(launchTemplateInstanceType(client, spec))
+		}
+	}
+	for _, instance := range asg.Instances {
+		add(aws.ToString(instance.InstanceType))
+	}
+	return instanceTypes
+}
+
+func launchTemplateInstanceType(client *ec2.Client, spec *astypes.LaunchTemplateSpecification) string {
+	input := &ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateId:   spec.LaunchTemplateId,
+		LaunchTemplateName: spec.LaunchTemplateName,
+		Versions:           []string{aws.ToString(spec.Version)},
+	}
+	if spec.LaunchTemplateId != nil {
+		input.LaunchTemplateName = nil
+	}
+	if aws.ToString(spec.Version) == "" {
+		input.Versions = []string{"$Default"}
+	}
+
+	result, err := client.DescribeLaunchTemplateVersions(context.TODO(), input)
+	if err != nil || len(result.LaunchTemplateVersions) == 0 {
+		return ""
+	}
+	data := result.LaunchTemplateVersions[0].LaunchTemplateData
+	if data == nil {
+		return ""
+	}
+	return string(data.InstanceType)
+}
+
+func asgLaunchTemplateVersion(client *ec2.Client, asg astypes.AutoScalingGroup) string {
+	spec := asgLaunchTemplate(asg)
+	if spec == nil {
+		if name := aws.ToString(asg.LaunchConfigurationName); name != "" {
+			return "launch-config:" + name
+		}
+		return "-"
+	}
+
+	name := aws.ToString(spec.LaunchTemplateName)
+	if name == "" {
+		name = aws.ToString(spec.LaunchTemplateId)
+	}
+	version, err := resolveLaunchTemplateVersion(client, spec)
+	if err != nil {
+		return name + ":" + aws.ToString(spec.Version)
+	}
+	return name + ":" + version
+}
+
+// asgSummary aggregates the nodes of one Auto Scaling Group for the asg view
+type asgSummary struct {
+	nodes         int
+	asgNames      map[string]bool // real ASGs of the group's nodes, for sizing and history
+	instanceTypes map[string]int
+	cpuCapacity   resource.Quantity
+	cpuRequested  resource.Quantity
+	memCapacity   resource.Quantity
+	memRequested  resource.Quantity
+}
+
+// printASGSummary writes one row per Auto Scaling Group with its sizing, recent desired capacity,
+// instance types and the total capacity and requests of its nodes. Nodes outside an ASG are grouped
+// under <none>. With a headroom policy in the config file, groups violating it are flagged as the
+// problems command reports them.
+func printASGSummary(out io.Writer, nodes []NodeInfo, asgMap map[string]ASGCapacity, histories map[string]desiredHistory, cfg *Config) {
+	byGroup := make(map[string]*asgSummary)
+	headroom := make(map[string]*groupUsage)
+	for _, n := range nodes {
+		// Like problems, only Ready nodes that are not cordoned count toward headroom
+		if n.ASG != "" && n.Status == "Ready" && n.DrainPods == nil {
+			u := headroom[n.ASG]
+			if u == nil {
+				u = &groupUsage{}
+				headroom[n.ASG] = u
+			}
+			u.Nodes++
+			addQuantity(&u.CPUCapacity, n.CPUCapacity)
+			addQuantity(&u.CPURequested, n.CPURequested)
+			addQuantity(&u.MemCapacity, n.MemCapacity)
+			addQuantity(&u.MemRequested, n.MemRequested)
+		}
+
+		group := n.ASG
+		if group == "" {
+			group = "<none>"
+		}
+		summary := byGroup[group]
+		if summary == nil {
+			summary = &asgSummary{instanceTypes: make(map[string]int), asgNames: make(map[string]bool)}
+			byGroup[group] = summary
+		}
+		summary.nodes++
+		if n.ASGName != "" {
+			summary.asgNames[n.ASGName] = true
+		}
+		if n.InstanceType != "" {
+			summary.instanceTypes[n.InstanceType]++
+		}
+		addQuantity(&summary.cpuCapacity, n.CPUCapacity)
+		addQuantity(&summary.cpuRequested, n.CPURequested)
+		addQuantity(&summary.memCapacity, n.MemCapacity)
+		addQuantity(&summary.memRequested, n.MemRequested)
+	}
+
+	var groups []string
+	for group := range byGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	showHeadroom := cfg != nil && cfg.Headroom != nil
+	violations := make(map[string][]string)
+	if showHeadroom {
+		for _, p := range headroomProblems(headroom, cfg) {
+			violations[p.Name] = append(violations[p.Name], p.Problem)
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	header := "ASG\tNODES\tMIN/MAX/DESIRED\tDESIRED-24H\tCHANGES-24H\tINSTANCE-TYPES\tCPU-CAP\tCPU-REQ\tCPU-FREE%\tMEM-CAP\tMEM-REQ\tMEM-FREE%"
+	if showHeadroom {
+		header += "\tHEADROOM"
+	}
+	fmt.Fprintln(w, header)
+	for _, group := range groups {
+		s := byGroup[group]
+		// A --group-tag group only has ASG sizing when its nodes all come from one ASG
+		capacity := "-"
+		trend, changes := "-", "-"
+		if len(s.asgNames) == 1 {
+			var asgName string
+			for name := range s.asgNames {
+				asgName = name
+			}
+			if c, exists := asgMap[asgName]; exists {
+				capacity = c.String()
+			}
+			if history, exists := histories[asgName]; exists {
+				trend, changes = history.Sparkline, strconv.Itoa(history.Changes)
+			}
+		}
+
+		// Most common instance type first, with counts when the group is mixed
+		var types []string
+		for t := range s.instanceTypes {
+			types = append(types, t)
+		}
+		sort.Slice(types, func(i, j int) bool {
+			if s.instanceTypes[types[i]] != s.instanceTypes[types[j]] {
+				return s.instanceTypes[types[i]] > s.instanceTypes[types[j]]
+			}
+			return types[i] < types[j]
+		})
+		if len(types) > 1 {
+			for i, t := range types {
+				types[i] = fmt.Sprintf("%s(%d)", t, s.instanceTypes[t])
+			}
+		}
+		instanceTypes := strings.Join(types, ",")
+		if instanceTypes == "" {
+			instanceTypes = "-"
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+			group, s.nodes, capacity, trend, changes, instanceTypes,
+			formatResource(&s.cpuCapacity), formatResource(&s.cpuRequested), formatPercent(calculateFreePercentage(&s.cpuCapacity, &s.cpuRequested)),
+			formatMemory(&s.memCapacity), formatMemory(&s.memRequested), formatPercent(calculateFreePercentage(&s.memCapacity, &s.memRequested)))
+		if showHeadroom {
+			status := "ok"
+			if group == "<none>" {
+				status = "-"
+			} else if len(violations[group]) > 0 {
+				status = strings.Join(violations[group], "; ")
+			}
+			fmt.Fprintf(w, "\t%s", status)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+// desiredHistoryWindow and desiredHistoryBuckets size the desired capacity sparkline of the asg view
+const (
+	desiredHistoryWindow  = 24 * time.Hour
+	desiredHistoryBuckets = 24
+)
+
+// desiredChange matches the desired capacity change in a scaling activity's cause
+var desiredChange = regexp.MustCompile(`changing the desired capacity from (\d+) to (\d+)`)
+
+// capacityChange is a change of an ASG's desired capacity
+type capacityChange struct {
+	Time time.Time
+	From int32
+	To   int32
+}
+
+// desiredHistory is an ASG's desired capacity over the window, as a sparkline and a change count
+type desiredHistory struct {
+	Sparkline string
+	Changes   int
+}
+
+// getDesiredHistories reads the desired capacity changes of the ASGs from their scaling activities,
+// in the region of each ASG, and renders them as sparklines ending at the current desired capacity
+func getDesiredHistories(cfg aws.Config, asgRegions map[string]string, asgMap map[string]ASGCapacity) map[string]desiredHistory {
+	now := time.Now()
+	histories := make(map[string]desiredHistory)
+	for asgName, region := range asgRegions {
+		capacity, exists := asgMap[asgName]
+		if !exists {
+			continue
+		}
+		client := autoscaling.NewFromConfig(cfg, func(o *autoscaling.Options) { o.Region = region })
+		changes, err := getCapacityChanges(client, asgName, now.Add(-desiredHistoryWindow))
+		if err != nil {
+			warnf("could not get scaling activities of ASG '%s': %v\n", asgName, err)
+			continue
+		}
+		histories[asgName] = desiredHistory{
+			Sparkline: sparkline(bucketDesired(capacity.Desired, changes, now), desiredHistoryBuckets),
+			Changes:   len(changes),
+		}
+	}
+	return histories
+}
+
+// getCapacityChanges returns the desired capacity changes since the given time, newest first
+func getCapacityChanges(client *autoscaling.Client, asgName string, since time.Time) ([]capacityChange, error) {
+	var changes []capacityChange
+	paginator := autoscaling.NewDescribeScalingActivitiesPaginator(client, &autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(asgName),
+	})
+	for page := 0; page < maxActivityPages && paginator.HasMorePages(); page++ {
+		result, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, classifyAWSError(err)
+		}
+		for _, activity := range result.Activities {
+			start := aws.ToTime(activity.StartTime)
+			if start.Before(since) {
+				return changes, nil
+			}
+			match := desiredChange.FindStringSubmatch(aws.ToString(activity.Cause))
+			if match == nil {
+				continue
+			}
+			from, _ := strconv.Atoi(match[1])
+			to, _ := strconv.Atoi(match[2])
+			changes = append(changes, capacityChange{Time: start, From: int32(from), To: int32(to)})
+		}
+	}
+	return changes, nil
+}
+
+// bucketDesired replays the changes backwards from the current desired capacity and returns the
+// highest desired capacity of each bucket of the window, oldest first
+func bucketDesired(current int32, changes []capacityChange, now time.Time) []float64 {
+	bucketSize := desiredHistoryWindow / desiredHistoryBuckets
+	buckets := make([]float64, desiredHistoryBuckets)
+	value := current
+	next := 0 // index of the newest change not replayed yet
+	for i := desiredHistoryBuckets - 1; i >= 0; i-- {
+		start := now.Add(-time.Duration(desiredHistoryBuckets-i) * bucketSize)
+		highest := value
+		for next < len(changes) && changes[next].Time.After(start) {
+			value = changes[next].From
+			if value > highest {
+				highest = value
+			}
+			next++
+		}
+		buckets[i] = float64(highest)
+	}
+	return buckets
+}
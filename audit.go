@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func runAudit(args []string, cfg *Config) {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	clusterName := fs.String("cluster", "", "Cluster name used in ASG tags (default: detected from node instance tags)")
+	var requiredLabels stringSliceFlag
+	fs.Var(&requiredLabels, "required-label", "Label key every node must carry, can be repeated (default: requiredLabels from the config file)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s audit [--cluster NAME] [--required-label KEY]...\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Check the nodes and the AWS resources behind them for misconfigurations: ASGs whose instances\n")
+		fmt.Fprintf(os.Stderr, "joined the cluster but which lack the kubernetes.io/cluster/<name> tag, so cluster-autoscaler\n")
+		fmt.Fprintf(os.Stderr, "and other tag-based discovery miss them, and nodes missing any of the required labels.\n")
+		fmt.Fprintf(os.Stderr, "Exits with status 1 if anything is found.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+	if len(requiredLabels) == 0 {
+		requiredLabels = cfg.RequiredLabels
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+	registered := make(map[string]bool)
+	for _, node := range nodes.Items {
+		registered[getInstanceID(node)] = true
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *clusterName == "" {
+		instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+			os.Exit(1)
+		}
+		*clusterName = detectClusterName(instanceMap, registered)
+		if *clusterName == "" {
+			fmt.Fprintf(os.Stderr, "Error: could not detect the cluster name from node instance tags, use --cluster\n")
+			os.Exit(1)
+		}
+	}
+
+	asgs, err := getNodeASGs(awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting Auto Scaling Groups: %v\n", err)
+		os.Exit(1)
+	}
+
+	findings := untaggedASGs(asgs, *clusterName, registered)
+	findings = append(findings, missingLabels(nodes.Items, requiredLabels)...)
+	if len(findings) == 0 {
+		fmt.Printf("No findings for cluster '%s'\n", *clusterName)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SCOPE\tNAME\tFINDING")
+	for _, f := range findings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", f.Scope, f.Name, f.Problem)
+	}
+	w.Flush()
+	os.Exit(1)
+}
+
+// untaggedASGs reports ASGs with registered members that lack the cluster's kubernetes.io/cluster/<name> tag
+func untaggedASGs(asgs map[string]astypes.AutoScalingGroup, clusterName string, registered map[string]bool) []problem {
+	var names []string
+	for name := range asgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings []problem
+	for _, name := range names {
+		asg := asgs[name]
+		members := 0
+		for _, instance := range asg.Instances {
+			if registered[aws.ToString(instance.InstanceId)] {
+				members++
+			}
+		}
+		if members == 0 || hasASGTag(asg, clusterTagPrefix+clusterName) {
+			continue
+		}
+		findings = append(findings, problem{"asg", name,
+			fmt.Sprintf("%d registered nodes but no %s%s tag", members, clusterTagPrefix, clusterName)})
+	}
+	return findings
+}
+
+// missingLabels reports nodes without one of the required label keys, or with it set empty
+func missingLabels(nodes []v1.Node, required []string) []problem {
+	if len(required) == 0 {
+		return nil
+	}
+	sorted := append([]v1.Node(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var findings []problem
+	for _, node := range sorted {
+		var missing []string
+		for _, key := range required {
+			if node.Labels[key] == "" {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			findings = append(findings, problem{"node", node.Name, "missing required labels: " + strings.Join(missing, ",")})
+		}
+	}
+	return findings
+}
+
+func hasASGTag(asg astypes.AutoScalingGroup, key string) bool {
+	for _, tag := range asg.Tags {
+		if aws.ToString(tag.Key) == key {
+			return true
+		}
+	}
+	return false
+}
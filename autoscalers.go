@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// clusterAutoscalerTagPrefix covers the auto-discovery tags k8s.io/cluster-autoscaler/enabled
+	// and k8s.io/cluster-autoscaler/<cluster>, and the node template tags below them
+	clusterAutoscalerTagPrefix = "k8s.io/cluster-autoscaler/"
+	clusterAutoscalerEnabled   = "k8s.io/cluster-autoscaler/enabled"
+	asgNameTag                 = "aws:autoscaling:groupName"
+)
+
+// isClusterAutoscalerTag reports whether the tag is read by cluster-autoscaler
+func isClusterAutoscalerTag(key string) bool {
+	return strings.HasPrefix(key, clusterAutoscalerTagPrefix)
+}
+
+// clusterAutoscalerRunning looks for a cluster-autoscaler Deployment in any namespace
+func clusterAutoscalerRunning(clientset *kubernetes.Clientset) (bool, error) {
+	deployments, err := clientset.AppsV1().Deployments("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	for _, deployment := range deployments.Items {
+		if strings.Contains(deployment.Name, "cluster-autoscaler") && deployment.Status.ReadyReplicas > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getNodeASGs describes the ASGs of every region the nodes live in, by name
+func getNodeASGs(cfg aws.Config, nodes []v1.Node) (map[string]astypes.AutoScalingGroup, error) {
+	asgs := make(map[string]astypes.AutoScalingGroup)
+	for region := range nodeInstanceIDsByRegion(nodes, cfg.Region) {
+		client := autoscaling.NewFromConfig(cfg, func(o *autoscaling.Options) { o.Region = region })
+		groups, err := getAutoScalingGroups(context.TODO(), client)
+		if err != nil {
+			return nil, fmt.Errorf("in %s: %w", region, err)
+		}
+		for _, asg := range groups {
+			asgs[aws.ToString(asg.AutoScalingGroupName)] = asg
+		}
+	}
+	return asgs, nil
+}
+
+// autoscalerConflicts finds capacity that both cluster-autoscaler and Karpenter may act on:
+// Karpenter-launched instances carrying cluster-autoscaler tags, and Karpenter nodes in ASGs
+// that cluster-autoscaler auto-discovers. Both controllers then add and remove nodes for the
+// same pending pods, which shows up as churn.
+func autoscalerConflicts(nodes []v1.Node, instanceMap map[string]types.Instance, asgs map[string]astypes.AutoScalingGroup, caRunning bool) []problem {
+	var problems []problem
+	karpenterNodesPerASG := make(map[string]int)
+	for _, node := range nodes {
+		if karpenterNodePool(node) == "" {
+			continue
+		}
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists {
+			continue
+		}
+
+		var caTags []string
+		for _, tag := range instance.Tags {
+			if key := aws.ToString(tag.Key); isClusterAutoscalerTag(key) {
+				caTags = append(caTags, key)
+			}
+		}
+		if len(caTags) > 0 {
+			sort.Strings(caTags)
+			problems = append(problems, problem{"node", node.Name,
+				"Karpenter node's instance has cluster-autoscaler tags: " + strings.Join(caTags, ",")})
+		}
+		if asgName := instanceASG(instance); asgName != "" {
+			karpenterNodesPerASG[asgName]++
+		}
+	}
+
+	var names []string
+	for name := range karpenterNodesPerASG {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		asg, exists := asgs[name]
+		if !exists || !asgDiscoveredByClusterAutoscaler(asg) {
+			continue
+		}
+		message := fmt.Sprintf("cluster-autoscaler auto-discovers this ASG but %d of its nodes are Karpenter-managed", karpenterNodesPerASG[name])
+		if caRunning {
+			message += ", and cluster-autoscaler is running"
+		}
+		problems = append(problems, problem{"group", name, message})
+	}
+	return problems
+}
+
+// asgDiscoveredByClusterAutoscaler reports whether the ASG has cluster-autoscaler's enabled tag
+func asgDiscoveredByClusterAutoscaler(asg astypes.AutoScalingGroup) bool {
+	return hasASGTag(asg, clusterAutoscalerEnabled)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Some AWS APIs are only needed for one or two read-only operations (Health, Pricing, SSM,
+// EKS), so they are called through this small SigV4-signed JSON client instead of
+// pulling in their generated SDK packages.
+
+type awsJSONClient struct {
+	cfg          aws.Config
+	serviceID    string
+	signingName  string
+	region       string
+	endpoint     string
+	targetPrefix string
+}
+
+func (c *awsJSONClient) call(ctx context.Context, operation string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", c.targetPrefix+operation)
+	return c.send(ctx, req, body, operation, output)
+}
+
+// get calls a REST-JSON operation (such as EKS') that reads the resource at path
+func (c *awsJSONClient) get(ctx context.Context, operation, path string, output interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.endpoint, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	return c.send(ctx, req, nil, operation, output)
+}
+
+// send signs and sends the request, decoding the JSON response into output. Like the SDK clients,
+// throttled requests, 5xx responses and connection errors are retried with the config's retryer
+// (the SDK's standard one by default).
+func (c *awsJSONClient) send(ctx context.Context, req *http.Request, body []byte, operation string, output interface{}) error {
+	retryer := c.cfg.Retryer
+	if retryer == nil {
+		retryer = func() aws.Retryer { return retry.NewStandard() }
+	}
+	r := retryer()
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.Clone(ctx)
+		if body != nil {
+			attemptReq.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		err := c.sendOnce(ctx, attemptReq, body, operation, output)
+		if err == nil || attempt >= r.MaxAttempts() || !r.IsErrorRetryable(err) {
+			return err
+		}
+		delay, delayErr := r.RetryDelay(attempt, err)
+		if delayErr != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *awsJSONClient) sendOnce(ctx context.Context, req *http.Request, body []byte, operation string, output interface{}) error {
+	creds, err := c.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return classifyAWSError(fmt.Errorf("failed to retrieve credentials: %w", err))
+	}
+	hash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), c.signingName, c.region, time.Now()); err != nil {
+		return err
+	}
+
+	httpClient := c.cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		// REST-JSON services return the error code in a header
+		if apiErr.Type == "" {
+			apiErr.Type = strings.SplitN(resp.Header.Get("X-Amzn-Errortype"), ":", 2)[0]
+		}
+		code := apiErr.Type[strings.LastIndex(apiErr.Type, "#")+1:]
+		if code == "" {
+			code = resp.Status
+		}
+		return classifyAWSError(&smithy.OperationError{
+			ServiceID:     c.serviceID,
+			OperationName: operation,
+			Err: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: resp},
+				Err:      &smithy.GenericAPIError{Code: code, Message: apiErr.Message},
+			},
+		})
+	}
+	return json.Unmarshal(data, output)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+func TestAWSJSONClientRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int // responses with status and code before a success
+		status    int
+		code      string
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "throttled then success", failures: 2, status: http.StatusBadRequest, code: "ThrottlingException", wantCalls: 3},
+		{name: "unavailable until attempts run out", failures: 5, status: http.StatusServiceUnavailable, code: "ServiceUnavailable", wantCalls: 3, wantErr: true},
+		{name: "access denied is not retried", failures: 5, status: http.StatusBadRequest, code: "AccessDeniedException", wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tt.failures {
+					w.WriteHeader(tt.status)
+					w.Write([]byte(`{"__type":"com.amazonaws#` + tt.code + `","message":"test"}`))
+					return
+				}
+				w.Write([]byte(`{"Value":"ok"}`))
+			}))
+			defer server.Close()
+
+			client := &awsJSONClient{
+				cfg: aws.Config{
+					Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+						return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+					}),
+					Retryer: func() aws.Retryer {
+						return retry.NewStandard(func(o *retry.StandardOptions) {
+							o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+						})
+					},
+				},
+				serviceID:    "Test",
+				signingName:  "test",
+				region:       "us-east-1",
+				endpoint:     server.URL + "/",
+				targetPrefix: "Test.",
+			}
+			var output struct{ Value string }
+			err := client.call(context.Background(), "Get", map[string]string{}, &output)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("call() = %v, want error: %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && output.Value != "ok" {
+				t.Errorf("output = %+v, want the successful response", output)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("server got %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
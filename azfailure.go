@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// zoneFree sums the capacity and requests of a surviving zone's nodes
+type zoneFree struct {
+	Nodes        int
+	CPUCapacity  resource.Quantity
+	CPURequested resource.Quantity
+	MemCapacity  resource.Quantity
+	MemRequested resource.Quantity
+}
+
+func runSimulateAZFailure(args []string) {
+	fs := flag.NewFlagSet("simulate-az-failure", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s simulate-az-failure ZONE\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Simulate the loss of an Availability Zone: place the pods of the zone's nodes on the Ready,\n")
+		fmt.Fprintf(os.Stderr, "schedulable nodes of the other zones by their requests, nodeSelector, node affinity and\n")
+		fmt.Fprintf(os.Stderr, "taints, and list the pods left without a node. Inter-pod affinity, topology spread and\n")
+		fmt.Fprintf(os.Stderr, "zonal volumes are not considered, and no new nodes are assumed. Exits with status 1 if\n")
+		fmt.Fprintf(os.Stderr, "any pod does not fit.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	zone := fs.Arg(0)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	lost := make(map[string]bool)
+	var candidates []v1.Node
+	for _, node := range nodes.Items {
+		if getNodeZone(node) == zone {
+			lost[node.Name] = true
+		} else if !node.Spec.Unschedulable && getNodeStatus(node) == "Ready" {
+			candidates = append(candidates, node)
+		}
+	}
+	if len(lost) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no nodes in zone '%s'\n", zone)
+		os.Exit(1)
+	}
+
+	var displaced []v1.Pod
+	var displacedCPU, displacedMem resource.Quantity
+	for _, pod := range pods.Items {
+		if !lost[pod.Spec.NodeName] || isDaemonSetPod(pod) || isMirrorPod(pod) ||
+			pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		displaced = append(displaced, pod)
+		requests := podRequests(pod)
+		displacedCPU.Add(*requests.Cpu())
+		displacedMem.Add(*requests.Memory())
+	}
+
+	nodeResources := calculateNodeResources(nodes.Items, pods.Items)
+	before := zoneFreeByZone(candidates, nodeResources)
+	issues := placeDisplacedPods(displaced, candidates, nodeResources)
+	after := zoneFreeByZone(candidates, nodeResources)
+
+	fmt.Printf("Zone %s: %d nodes, %d pods to reschedule (cpu %s, memory %s) on %d nodes in other zones\n\n",
+		zone, len(lost), len(displaced), formatResource(&displacedCPU), formatMemory(&displacedMem), len(candidates))
+
+	var zones []string
+	for z := range before {
+		zones = append(zones, z)
+	}
+	sort.Strings(zones)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ZONE\tNODES\tCPU-FREE%\tCPU-FREE%-AFTER\tMEM-FREE%\tMEM-FREE%-AFTER")
+	for _, z := range zones {
+		b, a := before[z], after[z]
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", z, b.Nodes,
+			formatPercent(calculateFreePercentage(&b.CPUCapacity, &b.CPURequested)),
+			formatPercent(calculateFreePercentage(&a.CPUCapacity, &a.CPURequested)),
+			formatPercent(calculateFreePercentage(&b.MemCapacity, &b.MemRequested)),
+			formatPercent(calculateFreePercentage(&a.MemCapacity, &a.MemRequested)))
+	}
+	w.Flush()
+
+	if len(issues) == 0 {
+		fmt.Printf("\nAll %d pods fit on the remaining nodes\n", len(displaced))
+		return
+	}
+
+	fmt.Printf("\n%d of %d pods would stay Pending:\n", len(issues), len(displaced))
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tPOD\tOWNER\tREASON")
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Pod.Namespace, issue.Pod.Name, podOwner(issue.Pod), issue.Reason)
+	}
+	w.Flush()
+	os.Exit(1)
+}
+
+// placeDisplacedPods places the pods, largest CPU request first, each on the fitting candidate
+// with the most free CPU and memory, the way the scheduler's default scoring spreads load.
+// Placed requests are added to nodeResources, so later pods see the reduced free capacity.
+func placeDisplacedPods(pods []v1.Pod, candidates []v1.Node, nodeResources map[string]*NodeInfo) []relocationIssue {
+	sort.SliceStable(pods, func(i, j int) bool {
+		a, b := podRequests(pods[i]), podRequests(pods[j])
+		if c := a.Cpu().Cmp(*b.Cpu()); c != 0 {
+			return c > 0
+		}
+		return a.Memory().Cmp(*b.Memory()) > 0
+	})
+
+	var issues []relocationIssue
+	for _, pod := range pods {
+		requests := podRequests(pod)
+		var best *NodeInfo
+		bestFree := -1.0
+		for _, node := range candidates {
+			info := nodeResources[node.Name]
+			if !matchesNodeSelector(pod, node) || !matchesNodeAffinity(pod, node) || !toleratesNodeTaints(pod, node) ||
+				!fitsFreeResources(requests, info) || int64(info.PodCount+len(info.StaticPods)) >= node.Status.Allocatable.Pods().Value() {
+				continue
+			}
+			free := calculateFreePercentage(info.CPUCapacity, info.CPURequested) + calculateFreePercentage(info.MemCapacity, info.MemRequested)
+			if free > bestFree {
+				best, bestFree = info, free
+			}
+		}
+
+		if best == nil {
+			reason := relocationBlocker(pod, candidates, nodeResources)
+			if reason == "" {
+				reason = "no remaining pod slots on matching nodes"
+			}
+			issues = append(issues, relocationIssue{Pod: pod, Reason: reason})
+			continue
+		}
+		best.CPURequested.Add(*requests.Cpu())
+		best.MemRequested.Add(*requests.Memory())
+		best.PodCount++
+	}
+	return issues
+}
+
+// zoneFreeByZone sums the capacity and requests of the nodes per zone
+func zoneFreeByZone(nodes []v1.Node, nodeResources map[string]*NodeInfo) map[string]zoneFree {
+	byZone := make(map[string]zoneFree)
+	for _, node := range nodes {
+		zone := getNodeZone(node)
+		if zone == "" {
+			zone = "<none>"
+		}
+		info := nodeResources[node.Name]
+		z := byZone[zone]
+		z.Nodes++
+		addQuantity(&z.CPUCapacity, info.CPUCapacity)
+		addQuantity(&z.CPURequested, info.CPURequested)
+		addQuantity(&z.MemCapacity, info.MemCapacity)
+		addQuantity(&z.MemRequested, info.MemRequested)
+		byZone[zone] = z
+	}
+	return byZone
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeBlockers counts, per node, why recently unschedulable pods could not land on it
+type nodeBlockers map[string]map[string]int
+
+func runBlocked(args []string) {
+	fs := flag.NewFlagSet("blocked", flag.ExitOnError)
+	since := fs.Duration("since", time.Hour, "Only consider FailedScheduling events newer than this")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s blocked [--since DURATION]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Correlate recent FailedScheduling events with node-level causes and show, per node,\n")
+		fmt.Fprintf(os.Stderr, "what keeps the still-pending pods off it (cordon, taints, selectors or free requests).\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	pending, err := recentlyUnschedulablePods(clientset, pods.Items, time.Now().Add(-*since))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing events: %v\n", err)
+		os.Exit(1)
+	}
+	if len(pending) == 0 {
+		fmt.Printf("No pending pods with FailedScheduling events in the last %s\n", *since)
+		return
+	}
+
+	blockers := findSchedulingBlockers(pending, nodes.Items, pods.Items)
+	fmt.Printf("%d pending pod(s) with FailedScheduling events in the last %s\n\n", len(pending), *since)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NODE\tBLOCKED-PODS\tBLOCKERS")
+	for _, node := range nodes.Items {
+		reasons := blockers[node.Name]
+		total := 0
+		for _, count := range reasons {
+			total += count
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", node.Name, total, formatBlockers(reasons))
+	}
+	w.Flush()
+}
+
+// recentlyUnschedulablePods returns pods that are still unscheduled and had a FailedScheduling event after cutoff
+func recentlyUnschedulablePods(clientset *kubernetes.Clientset, pods []v1.Pod, cutoff time.Time) ([]v1.Pod, error) {
+	selector := fields.SelectorFromSet(fields.Set{"involvedObject.kind": "Pod", "reason": "FailedScheduling"})
+	events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{FieldSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make(map[string]bool)
+	for _, event := range events.Items {
+		if eventTime(event).After(cutoff) {
+			failed[event.InvolvedObject.Namespace+"/"+event.InvolvedObject.Name] = true
+		}
+	}
+
+	var pending []v1.Pod
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" && pod.Status.Phase == v1.PodPending && failed[pod.Namespace+"/"+pod.Name] {
+			pending = append(pending, pod)
+		}
+	}
+	return pending, nil
+}
+
+// findSchedulingBlockers evaluates every pending pod against every node and counts the first reason each node rejects it
+func findSchedulingBlockers(pending []v1.Pod, nodes []v1.Node, pods []v1.Pod) nodeBlockers {
+	nodeResources := calculateNodeResources(nodes, pods)
+	blockers := make(nodeBlockers)
+	for _, node := range nodes {
+		for _, pod := range pending {
+			reason := schedulingBlocker(pod, node, nodeResources[node.Name])
+			if reason == "" {
+				continue
+			}
+			if blockers[node.Name] == nil {
+				blockers[node.Name] = make(map[string]int)
+			}
+			blockers[node.Name][reason]++
+		}
+	}
+	return blockers
+}
+
+// schedulingBlocker explains why the pod can't be placed on node, or returns "" if it fits
+func schedulingBlocker(pod v1.Pod, node v1.Node, nodeInfo *NodeInfo) string {
+	if node.Spec.Unschedulable {
+		return "cordoned"
+	}
+	if getNodeStatus(node) != "Ready" {
+		return "not ready"
+	}
+	if !matchesNodeSelector(pod, node) || !matchesNodeAffinity(pod, node) {
+		return "nodeSelector/affinity mismatch"
+	}
+	if taint := untoleratedTaint(pod, node); taint != nil {
+		return "taint " + taint.ToString()
+	}
+
+	requests := podRequests(pod)
+	var insufficient []string
+	if nodeInfo == nil || !fits(requests.Cpu(), nodeInfo.CPUCapacity, nodeInfo.CPURequested) {
+		insufficient = append(insufficient, "cpu")
+	}
+	if nodeInfo == nil || !fits(requests.Memory(), nodeInfo.MemCapacity, nodeInfo.MemRequested) {
+		insufficient = append(insufficient, "memory")
+	}
+	if len(insufficient) > 0 {
+		return "insufficient " + strings.Join(insufficient, "/")
+	}
+	return ""
+}
+
+// formatBlockers renders reasons as "reason (count)", most frequent first
+func formatBlockers(reasons map[string]int) string {
+	if len(reasons) == 0 {
+		return "-"
+	}
+	var keys []string
+	for reason := range reasons {
+		keys = append(keys, reason)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if reasons[keys[i]] != reasons[keys[j]] {
+			return reasons[keys[i]] > reasons[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	parts := make([]string, len(keys))
+	for i, reason := range keys {
+		parts[i] = fmt.Sprintf("%s (%d)", reason, reasons[reason])
+	}
+	return strings.Join(parts, ", ")
+}
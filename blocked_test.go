@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func quantity(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func requestsOf(cpu, memory string) v1.ResourceRequirements {
+	requests := v1.ResourceList{}
+	if cpu != "" {
+		requests[v1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		requests[v1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return v1.ResourceRequirements{Requests: requests}
+}
+
+func TestSchedulingBlocker(t *testing.T) {
+	readyNode := func(modify func(*v1.Node)) v1.Node {
+		node := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"pool": "general"}},
+			Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: v1.ConditionTrue},
+			}},
+		}
+		if modify != nil {
+			modify(&node)
+		}
+		return node
+	}
+	podRequesting := func(cpu, memory string) v1.Pod {
+		return v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Resources: requestsOf(cpu, memory)}}}}
+	}
+	// 2 CPUs and 4Gi allocatable, half of both requested
+	halfFull := &NodeInfo{
+		CPUCapacity: quantity("2"), CPURequested: quantity("1"),
+		MemCapacity: quantity("4Gi"), MemRequested: quantity("2Gi"),
+	}
+
+	tests := []struct {
+		name     string
+		pod      v1.Pod
+		node     v1.Node
+		nodeInfo *NodeInfo
+		want     string
+	}{
+		{
+			name:     "fits",
+			pod:      podRequesting("500m", "1Gi"),
+			node:     readyNode(nil),
+			nodeInfo: halfFull,
+			want:     "",
+		},
+		{
+			name:     "cordoned",
+			pod:      podRequesting("500m", "1Gi"),
+			node:     readyNode(func(n *v1.Node) { n.Spec.Unschedulable = true }),
+			nodeInfo: halfFull,
+			want:     "cordoned",
+		},
+		{
+			name:     "not ready",
+			pod:      podRequesting("500m", "1Gi"),
+			node:     readyNode(func(n *v1.Node) { n.Status.Conditions[0].Status = v1.ConditionFalse }),
+			nodeInfo: halfFull,
+			want:     "not ready",
+		},
+		{
+			name: "nodeSelector mismatch",
+			pod: v1.Pod{Spec: v1.PodSpec{
+				NodeSelector: map[string]string{"pool": "gpu"},
+				Containers:   []v1.Container{{Resources: requestsOf("500m", "1Gi")}},
+			}},
+			node:     readyNode(nil),
+			nodeInfo: halfFull,
+			want:     "nodeSelector/affinity mismatch",
+		},
+		{
+			name: "untolerated taint",
+			pod:  podRequesting("500m", "1Gi"),
+			node: readyNode(func(n *v1.Node) {
+				n.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+			}),
+			nodeInfo: halfFull,
+			want:     "taint dedicated=gpu:NoSchedule",
+		},
+		{
+			name: "tolerated taint",
+			pod: v1.Pod{Spec: v1.PodSpec{
+				Tolerations: []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpEqual, Value: "gpu", Effect: v1.TaintEffectNoSchedule}},
+				Containers:  []v1.Container{{Resources: requestsOf("500m", "1Gi")}},
+			}},
+			node: readyNode(func(n *v1.Node) {
+				n.Spec.Taints = []v1.Taint{{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}}
+			}),
+			nodeInfo: halfFull,
+			want:     "",
+		},
+		{
+			name:     "insufficient cpu",
+			pod:      podRequesting("1500m", "1Gi"),
+			node:     readyNode(nil),
+			nodeInfo: halfFull,
+			want:     "insufficient cpu",
+		},
+		{
+			name:     "insufficient cpu and memory",
+			pod:      podRequesting("1500m", "3Gi"),
+			node:     readyNode(nil),
+			nodeInfo: halfFull,
+			want:     "insufficient cpu/memory",
+		},
+		{
+			name:     "unknown resources",
+			pod:      podRequesting("100m", "100Mi"),
+			node:     readyNode(nil),
+			nodeInfo: nil,
+			want:     "insufficient cpu/memory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedulingBlocker(tt.pod, tt.node, tt.nodeInfo); got != tt.want {
+				t.Errorf("schedulingBlocker() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// onDemandPriceTTL is how long on-demand prices are cached. AWS changes them rarely, unlike the
+// instances and groups of a cluster, so --cache-ttl does not apply.
+const onDemandPriceTTL = 24 * time.Hour
+
+// defaultCacheDir is where AWS responses are cached between runs
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "kubectl-aws-nodes")
+}
+
+// cacheKey names a cached response by what was asked for and which credentials and region asked.
+// The credentials are identified by their access key ID, since AWS_PROFILE or environment
+// credentials leave --profile empty. IDs are sorted so the same set of nodes hits the same entry.
+// It returns "" (no caching) when the credentials can't be retrieved.
+func cacheKey(cfg aws.Config, kind, region string, ids []string) string {
+	if cfg.Credentials == nil {
+		return ""
+	}
+	creds, err := cfg.Credentials.Retrieve(context.TODO())
+	if err != nil || creds.AccessKeyID == "" {
+		return ""
+	}
+	sorted := append([]string(nil), ids...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{kind, creds.AccessKeyID, region}, sorted...), "\x00")))
+	return kind + "-" + hex.EncodeToString(sum[:8])
+}
+
+// cachedJSON fills value from the cache entry if it is younger than ttl, otherwise calls
+// fetch (which fills value) and stores the result. Cache read and write failures only mean a
+// fresh lookup, they are never an error.
+func cachedJSON(key string, ttl time.Duration, value interface{}, fetch func() error) error {
+	dir := defaultCacheDir()
+	if opts.noCache || ttl <= 0 || dir == "" || key == "" {
+		return fetch()
+	}
+	path := filepath.Join(dir, key+".json")
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < ttl {
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, value) == nil {
+			return nil
+		}
+	}
+
+	if err := fetch(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil
+	}
+	// Written under a temporary name so concurrent runs never read a partial entry
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0o600); err == nil {
+		os.Rename(tmp, path)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestCacheKey(t *testing.T) {
+	withKey := func(accessKeyID string) aws.Config {
+		return aws.Config{Credentials: credentials.NewStaticCredentialsProvider(accessKeyID, "secret", "")}
+	}
+	base := cacheKey(withKey("AKIAEXAMPLE1"), "instances", "us-east-1", []string{"i-1", "i-2"})
+
+	tests := []struct {
+		name     string
+		key      string
+		wantSame bool
+	}{
+		{"same request", cacheKey(withKey("AKIAEXAMPLE1"), "instances", "us-east-1", []string{"i-1", "i-2"}), true},
+		{"IDs in another order", cacheKey(withKey("AKIAEXAMPLE1"), "instances", "us-east-1", []string{"i-2", "i-1"}), true},
+		{"other credentials", cacheKey(withKey("AKIAEXAMPLE2"), "instances", "us-east-1", []string{"i-1", "i-2"}), false},
+		{"other region", cacheKey(withKey("AKIAEXAMPLE1"), "instances", "eu-west-1", []string{"i-1", "i-2"}), false},
+		{"other kind", cacheKey(withKey("AKIAEXAMPLE1"), "asg-capacities", "us-east-1", []string{"i-1", "i-2"}), false},
+		{"other IDs", cacheKey(withKey("AKIAEXAMPLE1"), "instances", "us-east-1", []string{"i-1"}), false},
+	}
+
+	if base == "" {
+		t.Fatal("cacheKey with static credentials is empty")
+	}
+	for _, tt := range tests {
+		if same := tt.key == base; same != tt.wantSame {
+			t.Errorf("%s: key %q, base %q, want same = %v", tt.name, tt.key, base, tt.wantSame)
+		}
+	}
+
+	// Without credentials nothing is cached
+	if key := cacheKey(aws.Config{}, "instances", "us-east-1", nil); key != "" {
+		t.Errorf("cacheKey without credentials = %q, want empty", key)
+	}
+	if key := cacheKey(withKey(""), "instances", "us-east-1", nil); key != "" {
+		t.Errorf("cacheKey with empty credentials = %q, want empty", key)
+	}
+}
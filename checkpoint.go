@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// checkpointAnnotation opts a Job or StatefulSet into --wait-for-checkpoint. The workload sets it to
+// checkpointSafe once its pods can be evicted without losing work (e.g. after writing a checkpoint),
+// and to any other value while they can't.
+const (
+	checkpointAnnotation = "kubectl-aws-nodes/checkpoint"
+	checkpointSafe       = "safe"
+)
+
+// checkpointPollInterval is how often the annotated workloads are re-read while waiting
+const checkpointPollInterval = 10 * time.Second
+
+// checkpointWorkload is a Job or StatefulSet with pods on the node being drained
+type checkpointWorkload struct {
+	Namespace string
+	Kind      string
+	Name      string
+}
+
+func (w checkpointWorkload) String() string {
+	return w.Namespace + "/" + w.Kind + "/" + w.Name
+}
+
+// checkpointWorkloads returns the Jobs and StatefulSets controlling the pods a drain would evict
+func checkpointWorkloads(pods []v1.Pod) []checkpointWorkload {
+	seen := make(map[checkpointWorkload]bool)
+	var workloads []checkpointWorkload
+	for _, pod := range pods {
+		if !isDrainable(pod) {
+			continue
+		}
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil || (owner.Kind != "Job" && owner.Kind != "StatefulSet") {
+			continue
+		}
+		w := checkpointWorkload{Namespace: pod.Namespace, Kind: owner.Kind, Name: owner.Name}
+		if !seen[w] {
+			seen[w] = true
+			workloads = append(workloads, w)
+		}
+	}
+	sort.Slice(workloads, func(i, j int) bool { return workloads[i].String() < workloads[j].String() })
+	return workloads
+}
+
+// checkpointPending reports whether a workload with these annotations is still to reach its safe
+// point; workloads without the annotation are not waited for
+func checkpointPending(annotations map[string]string) bool {
+	value, exists := annotations[checkpointAnnotation]
+	return exists && value != checkpointSafe
+}
+
+// waitForCheckpoints blocks until every annotated Job and StatefulSet with pods on the node is at
+// its safe point, or the timeout expires
+func waitForCheckpoints(clientset *kubernetes.Clientset, nodeName string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reported := ""
+	for {
+		pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+		if err != nil {
+			return fmt.Errorf("listing pods: %w", err)
+		}
+
+		var pending []string
+		for _, w := range checkpointWorkloads(pods.Items) {
+			var annotations map[string]string
+			switch w.Kind {
+			case "Job":
+				job, err := clientset.BatchV1().Jobs(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+				if err != nil {
+					return fmt.Errorf("getting Job %s/%s: %w", w.Namespace, w.Name, err)
+				}
+				annotations = job.Annotations
+			case "StatefulSet":
+				sts, err := clientset.AppsV1().StatefulSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+				if err != nil {
+					return fmt.Errorf("getting StatefulSet %s/%s: %w", w.Namespace, w.Name, err)
+				}
+				annotations = sts.Annotations
+			}
+			if checkpointPending(annotations) {
+				pending = append(pending, w.String())
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if list := strings.Join(pending, ", "); list != reported {
+			reported = list
+			fmt.Printf("waiting for %s=%s on %s\n", checkpointAnnotation, checkpointSafe, list)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for the checkpoint of %s", strings.Join(pending, ", "))
+		case <-time.After(checkpointPollInterval):
+		}
+	}
+}
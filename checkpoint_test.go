@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckpointWorkloads(t *testing.T) {
+	controller := true
+	pod := func(namespace, ownerKind, ownerName string, phase v1.PodPhase) v1.Pod {
+		p := v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: ownerName + "-pod"}, Status: v1.PodStatus{Phase: phase}}
+		if ownerKind != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &controller}}
+		}
+		return p
+	}
+	pods := []v1.Pod{
+		pod("data", "StatefulSet", "kafka", v1.PodRunning),
+		pod("data", "StatefulSet", "kafka", v1.PodRunning),
+		pod("batch", "Job", "train", v1.PodRunning),
+		pod("batch", "Job", "done", v1.PodSucceeded),
+		pod("web", "ReplicaSet", "frontend", v1.PodRunning),
+		pod("kube-system", "DaemonSet", "agent", v1.PodRunning),
+		pod("default", "", "debug", v1.PodRunning),
+	}
+
+	want := []checkpointWorkload{
+		{Namespace: "batch", Kind: "Job", Name: "train"},
+		{Namespace: "data", Kind: "StatefulSet", Name: "kafka"},
+	}
+	if got := checkpointWorkloads(pods); !reflect.DeepEqual(got, want) {
+		t.Errorf("checkpointWorkloads() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckpointPending(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		want        bool
+	}{
+		{nil, false},
+		{map[string]string{"other": "busy"}, false},
+		{map[string]string{checkpointAnnotation: "busy"}, true},
+		{map[string]string{checkpointAnnotation: ""}, true},
+		{map[string]string{checkpointAnnotation: "safe"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := checkpointPending(tt.annotations); got != tt.want {
+			t.Errorf("checkpointPending(%v) = %v, want %v", tt.annotations, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// globalOptions holds the connection and output settings shared by every command
+type globalOptions struct {
+	caBundle          string
+	kubeconfig        string
+	kubeContext       string
+	kubeCluster       string
+	kubeUser          string
+	namespace         string
+	server            string
+	token             string
+	certificateAuth   string
+	clientCert        string
+	clientKey         string
+	insecureTLS       bool
+	impersonateUser   string
+	impersonateGroups stringSliceFlag
+	awsProfile        string
+	awsRegion         string
+	quiet             bool
+	plainNumbers      bool
+	noCache           bool
+	cacheTTL          time.Duration
+}
+
+var opts globalOptions
+
+// warnf prints a warning to stderr, unless -q is set
+func warnf(format string, args ...interface{}) {
+	if !opts.quiet {
+		fmt.Fprintf(os.Stderr, "Warning: "+format, args...)
+	}
+}
+
+// infof prints a progress message to stderr, unless -q is set. Stdout is kept for data.
+func infof(format string, args ...interface{}) {
+	if !opts.quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// stringSliceFlag collects the values of a flag that may be repeated
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Both the AWS SDK and client-go use http.ProxyFromEnvironment, so
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored without extra wiring.
+
+// addKubeFlags registers kubectl's connection flags on fs. The subcommands get them too, so
+// "kubectl aws-nodes pods NODE --context prod" works like any kubectl command; a subcommand's own
+// flag of the same name (the EKS cluster name of --cluster) takes precedence over it.
+func addKubeFlags(fs *flag.FlagSet) {
+	stringFlags := []struct {
+		name  string
+		value *string
+		usage string
+	}{
+		{"kubeconfig", &opts.kubeconfig, "Path to the kubeconfig file (default: $KUBECONFIG or ~/.kube/config)"},
+		{"context", &opts.kubeContext, "Kubeconfig context to use"},
+		{"cluster", &opts.kubeCluster, "Kubeconfig cluster to use"},
+		{"user", &opts.kubeUser, "Kubeconfig user to use"},
+		{"n", &opts.namespace, "Namespace for namespaced arguments such as --for (default: the context's namespace)"},
+		{"namespace", &opts.namespace, "Same as -n"},
+		{"s", &opts.server, "Address and port of the Kubernetes API server"},
+		{"server", &opts.server, "Same as -s"},
+		{"token", &opts.token, "Bearer token for authentication to the API server"},
+		{"certificate-authority", &opts.certificateAuth, "Path to a cert file for the certificate authority"},
+		{"client-certificate", &opts.clientCert, "Path to a client certificate file for TLS"},
+		{"client-key", &opts.clientKey, "Path to a client key file for TLS"},
+		{"as", &opts.impersonateUser, "Username to impersonate for Kubernetes API calls"},
+	}
+	for _, f := range stringFlags {
+		if fs.Lookup(f.name) == nil {
+			// The current value is the default, so a flag given before the subcommand is kept
+			fs.StringVar(f.value, f.name, *f.value, f.usage)
+		}
+	}
+	if fs.Lookup("insecure-skip-tls-verify") == nil {
+		fs.BoolVar(&opts.insecureTLS, "insecure-skip-tls-verify", opts.insecureTLS, "Don't check the API server's certificate, making the connection insecure")
+	}
+	if fs.Lookup("as-group") == nil {
+		fs.Var(&opts.impersonateGroups, "as-group", "Group to impersonate for Kubernetes API calls, can be repeated")
+	}
+}
+
+// parseCommandFlags parses a subcommand's flags, with the kubectl connection flags added, allowing
+// them before and after the positional arguments like kubectl does. A "--" ends the flags.
+func parseCommandFlags(fs *flag.FlagSet, args []string) {
+	addKubeFlags(fs)
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+	// Parsing the positional arguments alone leaves them as fs.Args()
+	fs.Parse(append([]string{"--"}, positional...))
+}
+
+// kubeClientConfig loads the kubeconfig with the same overrides as kubectl's connection flags
+func kubeClientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = opts.kubeconfig
+	configOverrides := &clientcmd.ConfigOverrides{
+		CurrentContext: opts.kubeContext,
+		Context: clientcmdapi.Context{
+			Cluster:   opts.kubeCluster,
+			AuthInfo:  opts.kubeUser,
+			Namespace: opts.namespace,
+		},
+		ClusterInfo: clientcmdapi.Cluster{
+			Server:                opts.server,
+			CertificateAuthority:  opts.certificateAuth,
+			InsecureSkipTLSVerify: opts.insecureTLS,
+		},
+		AuthInfo: clientcmdapi.AuthInfo{
+			Token:             opts.token,
+			ClientCertificate: opts.clientCert,
+			ClientKey:         opts.clientKey,
+		},
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
+}
+
+func getKubeConfig() (*rest.Config, error) {
+	config, err := kubeClientConfig().ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.caBundle != "" {
+		if err := appendCABundle(config, opts.caBundle); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.impersonateUser != "" || len(opts.impersonateGroups) > 0 {
+		config.Impersonate = rest.ImpersonationConfig{
+			UserName: opts.impersonateUser,
+			Groups:   opts.impersonateGroups,
+		}
+	}
+	return config, nil
+}
+
+// currentNamespace returns -n or the namespace of the selected kubeconfig context, "default" if unset
+func currentNamespace() string {
+	namespace, _, err := kubeClientConfig().Namespace()
+	if err != nil || namespace == "" {
+		return "default"
+	}
+	return namespace
+}
+
+func getClientset() (*kubernetes.Clientset, error) {
+	config, err := getKubeConfig()
+	if err != nil {
+		return nil, fmt.Errorf("getting kubeconfig: %w", err)
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// appendCABundle adds the certificates in path to the CAs trusted for the API server,
+// keeping the cluster CA from the kubeconfig.
+func appendCABundle(config *rest.Config, path string) error {
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading CA bundle: %w", err)
+	}
+
+	caData := config.TLSClientConfig.CAData
+	if len(caData) == 0 && config.TLSClientConfig.CAFile != "" {
+		caData, err = os.ReadFile(config.TLSClientConfig.CAFile)
+		if err != nil {
+			return fmt.Errorf("reading cluster CA: %w", err)
+		}
+	}
+	config.TLSClientConfig.CAData = bytes.Join([][]byte{caData, bundle}, []byte("\n"))
+	config.TLSClientConfig.CAFile = ""
+	return nil
+}
+
+func loadAWSConfig() (aws.Config, error) {
+	var loadOpts []func(*awsconfig.LoadOptions) error
+	if opts.caBundle != "" {
+		bundle, err := os.ReadFile(opts.caBundle)
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		loadOpts = append(loadOpts, awsconfig.WithCustomCABundle(bytes.NewReader(bundle)))
+	}
+	// Without the flags, AWS_PROFILE and AWS_REGION apply as usual
+	if opts.awsProfile != "" {
+		loadOpts = append(loadOpts, awsconfig.WithSharedConfigProfile(opts.awsProfile))
+	}
+	if opts.awsRegion != "" {
+		loadOpts = append(loadOpts, awsconfig.WithRegion(opts.awsRegion))
+	}
+	ctx := context.TODO()
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return aws.Config{}, classifyAWSError(err)
+	}
+
+	// Offer an SSO login up front rather than failing on the first API call
+	if ensureSSOSession(ctx, cfg) {
+		cfg, err = awsconfig.LoadDefaultConfig(ctx, loadOpts...)
+		if err != nil {
+			return aws.Config{}, classifyAWSError(err)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommandFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantArgs    []string
+		wantContext string
+		wantNS      string
+		wantCluster string
+		wantOwn     bool
+	}{
+		{"flags first", []string{"--context", "prod", "node/a"}, []string{"node/a"}, "prod", "", "", false},
+		{"flags after the node", []string{"node/a", "--context", "prod", "-n", "kube-system"}, []string{"node/a"}, "prod", "kube-system", "", false},
+		{"interspersed", []string{"a", "--own", "b", "--namespace=apps"}, []string{"a", "b"}, "", "apps", "", true},
+		{"double dash ends the flags", []string{"a", "--", "--context", "b"}, []string{"a", "--context", "b"}, "", "", "", false},
+		{"own flag wins over the kubeconfig one", []string{"--cluster", "eks-prod"}, []string{}, "", "", "eks-prod", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := opts
+			defer func() { opts = saved }()
+			opts = globalOptions{}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			own := fs.Bool("own", false, "")
+			cluster := fs.String("cluster", "", "EKS cluster name")
+			parseCommandFlags(fs, tt.args)
+
+			if got := fs.Args(); !reflect.DeepEqual(got, tt.wantArgs) {
+				t.Errorf("Args() = %q, want %q", got, tt.wantArgs)
+			}
+			if opts.kubeContext != tt.wantContext || opts.namespace != tt.wantNS {
+				t.Errorf("context, namespace = %q, %q, want %q, %q", opts.kubeContext, opts.namespace, tt.wantContext, tt.wantNS)
+			}
+			if *cluster != tt.wantCluster || opts.kubeCluster != "" {
+				t.Errorf("--cluster = %q (kubeconfig cluster %q), want %q", *cluster, opts.kubeCluster, tt.wantCluster)
+			}
+			if *own != tt.wantOwn {
+				t.Errorf("--own = %v, want %v", *own, tt.wantOwn)
+			}
+		})
+	}
+}
+
+func TestAddKubeFlagsKeepsGlobalValues(t *testing.T) {
+	saved := opts
+	defer func() { opts = saved }()
+	opts = globalOptions{kubeContext: "prod", namespace: "apps"}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	parseCommandFlags(fs, []string{"node/a"})
+	if opts.kubeContext != "prod" || opts.namespace != "apps" {
+		t.Errorf("context, namespace = %q, %q, want the values given before the subcommand", opts.kubeContext, opts.namespace)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// sendCommandMaxInstances is the API limit of instance IDs per SendCommand call
+const sendCommandMaxInstances = 50
+
+// ssmCommandPollInterval is how often SSM Run Command invocations are polled for their result
+const ssmCommandPollInterval = 2 * time.Second
+
+// chronySystemTime matches chronyc tracking's "System time : 0.000012 seconds fast of NTP time"
+var chronySystemTime = regexp.MustCompile(`System time\s*:\s*([\d.]+) seconds (fast|slow)`)
+
+func runClockDrift(args []string) {
+	fs := flag.NewFlagSet("clock-drift", flag.ExitOnError)
+	threshold := fs.Duration("threshold", 5*time.Second, "Estimated skew above which a node is flagged")
+	chrony := fs.Bool("chrony", false, "Also run 'chronyc tracking' on each node through SSM Run Command")
+	chronyTimeout := fs.Duration("chrony-timeout", time.Minute, "Maximum time to wait for the chronyc results")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s clock-drift [--threshold DURATION] [--chrony]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Estimate each node's clock skew from the renew time its kubelet writes to its node lease,\n")
+		fmt.Fprintf(os.Stderr, "compared with the API server's clock. A skewed clock breaks TLS and token validation.\n")
+		fmt.Fprintf(os.Stderr, "Exits with status 1 if a node is flagged.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	kubeConfig, err := getKubeConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting kubeconfig: %v\n", err)
+		os.Exit(1)
+	}
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.TODO()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+	leases, err := clientset.CoordinationV1().Leases("kube-node-lease").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing node leases: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Lease renew times are written with the kubelet's clock, so they are compared with the API server's
+	serverNow, err := getServerTime(kubeConfig)
+	if err != nil {
+		warnf("could not read the API server time, comparing with the local clock: %v\n", err)
+		serverNow = time.Now()
+	}
+
+	var chronyOffsets map[string]string
+	if *chrony {
+		awsConfig, err := loadAWSConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+			os.Exit(1)
+		}
+		chronyOffsets, err = runChronyTracking(ctx, awsConfig, nodes.Items, *chronyTimeout)
+		if err != nil {
+			warnf("could not run chronyc through SSM: %v\n", err)
+		}
+	}
+
+	type leaseInfo struct {
+		renew    time.Time
+		duration time.Duration
+	}
+	leaseByNode := make(map[string]leaseInfo)
+	for _, lease := range leases.Items {
+		if lease.Spec.RenewTime == nil {
+			continue
+		}
+		duration := 40 * time.Second
+		if lease.Spec.LeaseDurationSeconds != nil {
+			duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+		}
+		leaseByNode[lease.Name] = leaseInfo{lease.Spec.RenewTime.Time, duration}
+	}
+
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	flagged := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	header := "NODE\tSTATUS\tLEASE-RENEWED\tEST-SKEW\tDRIFT"
+	if *chrony {
+		header += "\tCHRONY-OFFSET"
+	}
+	fmt.Fprintln(w, header)
+	for _, node := range nodes.Items {
+		renewed, skew, drift := "-", "-", ""
+		if lease, exists := leaseByNode[node.Name]; exists {
+			renewed = formatOffset(lease.renew.Sub(serverNow))
+			if d, ok := estimateClockSkew(lease.renew, lease.duration, serverNow, getNodeStatus(node) == "Ready"); ok {
+				skew = formatOffset(d)
+				if d > *threshold || d < -*threshold {
+					drift = "yes"
+					flagged++
+				}
+			}
+		}
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s", node.Name, getNodeStatus(node), renewed, skew, drift)
+		if *chrony {
+			offset := chronyOffsets[getInstanceID(node)]
+			if offset == "" {
+				offset = "-"
+			}
+			row += "\t" + offset
+		}
+		fmt.Fprintln(w, row)
+	}
+	w.Flush()
+
+	if flagged > 0 {
+		os.Exit(1)
+	}
+}
+
+// estimateClockSkew estimates how far the kubelet's clock is off from the lease renew time. The
+// kubelet renews every quarter of the lease duration, so a renew time up to that far in the past is
+// normal and a renew time in the future means the clock is ahead. An older renew time means the
+// clock is behind by at least the excess, unless the kubelet stopped renewing, which a node that is
+// still Ready rules out: the node controller tracks renewals with its own clock.
+func estimateClockSkew(renew time.Time, leaseDuration time.Duration, now time.Time, ready bool) (time.Duration, bool) {
+	age := now.Sub(renew)
+	renewInterval := leaseDuration / 4
+	switch {
+	case age < 0:
+		return -age, true
+	case age <= renewInterval:
+		return 0, true
+	case age <= leaseDuration || ready:
+		return -(age - renewInterval), true
+	}
+	return 0, false
+}
+
+// getServerTime returns the API server's clock from the Date header of a /version request
+func getServerTime(config *rest.Config) (time.Time, error) {
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := httpClient.Get(strings.TrimSuffix(config.Host, "/") + "/version")
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp.Body.Close()
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing Date header: %w", err)
+	}
+	return date, nil
+}
+
+// runChronyTracking runs 'chronyc tracking' on the instances through SSM Run Command and returns
+// their NTP offsets by instance ID. Instances without SSM or chrony report the failure instead.
+func runChronyTracking(ctx context.Context, cfg aws.Config, nodes []v1.Node, timeout time.Duration) (map[string]string, error) {
+	return runNodeShellCommand(ctx, cfg, nodes, "chronyc tracking", "kubectl-aws-nodes clock-drift", timeout, parseChronyOffset)
+}
+
+// runNodeShellCommand runs runShellCommand on the nodes' instances through SSM in the region of
+// each node
+func runNodeShellCommand(ctx context.Context, cfg aws.Config, nodes []v1.Node, command, comment string, timeout time.Duration, parse func(string) string) (map[string]string, error) {
+	results := make(map[string]string)
+	for region, ids := range nodeInstanceIDsByRegion(nodes, cfg.Region) {
+		regionResults, err := runShellCommand(ctx, newSSMClient(regionalConfig(cfg, region)), ids, command, comment, timeout, parse)
+		if err != nil {
+			return results, fmt.Errorf("in %s: %w", region, err)
+		}
+		for id, result := range regionResults {
+			results[id] = result
+		}
+	}
+	return results, nil
+}
+
+// runShellCommand runs a shell command on the instances through SSM Run Command and returns the
+// parsed output by instance ID, or the lowercase invocation status if the command did not succeed
+func runShellCommand(ctx context.Context, client *awsJSONClient, instanceIDs []string, command, comment string, timeout time.Duration, parse func(string) string) (map[string]string, error) {
+	results := make(map[string]string)
+	if len(instanceIDs) == 0 {
+		return results, nil
+	}
+
+	commandIDs := make(map[string]string) // by instance ID
+	for start := 0; start < len(instanceIDs); start += sendCommandMaxInstances {
+		end := start + sendCommandMaxInstances
+		if end > len(instanceIDs) {
+			end = len(instanceIDs)
+		}
+		var sent struct {
+			Command struct {
+				CommandID string `json:"CommandId"`
+			} `json:"Command"`
+		}
+		err := client.call(ctx, "SendCommand", map[string]interface{}{
+			"DocumentName": "AWS-RunShellScript",
+			"InstanceIds":  instanceIDs[start:end],
+			"Parameters":   map[string][]string{"commands": {command}},
+			"Comment":      comment,
+		}, &sent)
+		if err != nil {
+			return results, err
+		}
+		for _, instanceID := range instanceIDs[start:end] {
+			commandIDs[instanceID] = sent.Command.CommandID
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	pending := append([]string(nil), instanceIDs...)
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		time.Sleep(ssmCommandPollInterval)
+		var still []string
+		for _, instanceID := range pending {
+			var invocation struct {
+				Status                string `json:"Status"`
+				StandardOutputContent string `json:"StandardOutputContent"`
+			}
+			err := client.call(ctx, "GetCommandInvocation", map[string]string{
+				"CommandId":  commandIDs[instanceID],
+				"InstanceId": instanceID,
+			}, &invocation)
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvocationDoesNotExist" {
+				// The invocation may not be registered yet
+				still = append(still, instanceID)
+				continue
+			} else if err != nil {
+				return results, err
+			}
+			switch invocation.Status {
+			case "Pending", "InProgress", "Delayed":
+				still = append(still, instanceID)
+			case "Success":
+				results[instanceID] = parse(invocation.StandardOutputContent)
+			default:
+				results[instanceID] = strings.ToLower(invocation.Status)
+			}
+		}
+		pending = still
+	}
+	for _, instanceID := range pending {
+		results[instanceID] = "timed out"
+	}
+	return results, nil
+}
+
+// parseChronyOffset returns the system clock offset from NTP time, positive when fast
+func parseChronyOffset(output string) string {
+	match := chronySystemTime.FindStringSubmatch(output)
+	if match == nil {
+		return "unknown"
+	}
+	seconds, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return "unknown"
+	}
+	if match[2] == "slow" {
+		seconds = -seconds
+	}
+	return formatOffset(time.Duration(seconds * float64(time.Second)))
+}
+
+// formatOffset prints a signed duration, rounded to milliseconds below a second
+func formatOffset(d time.Duration) string {
+	if math.Abs(d.Seconds()) < 1 {
+		d = d.Round(time.Millisecond)
+	} else {
+		d = d.Round(time.Second)
+	}
+	if d > 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
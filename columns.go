@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// customColumn projects a node label or annotation into the table, from --column NAME=label:KEY
+type customColumn struct {
+	Name   string
+	Source string // label or annotation
+	Key    string
+}
+
+// customColumns is a repeatable --column flag
+type customColumns []customColumn
+
+func (c *customColumns) String() string {
+	var specs []string
+	for _, column := range *c {
+		specs = append(specs, column.Name+"="+column.Source+":"+column.Key)
+	}
+	return strings.Join(specs, ",")
+}
+
+func (c *customColumns) Set(value string) error {
+	name, expression, found := strings.Cut(value, "=")
+	if !found || name == "" {
+		return fmt.Errorf("expected NAME=label:KEY or NAME=annotation:KEY, got '%s'", value)
+	}
+	source, key, found := strings.Cut(expression, ":")
+	if !found || key == "" || (source != "label" && source != "annotation") {
+		return fmt.Errorf("expected label:KEY or annotation:KEY for column '%s', got '%s'", name, expression)
+	}
+	*c = append(*c, customColumn{Name: strings.ToUpper(name), Source: source, Key: key})
+	return nil
+}
+
+// header returns the tab-prefixed column names to append to a table header
+func (c customColumns) header() string {
+	var header string
+	for _, column := range c {
+		header += "\t" + column.Name
+	}
+	return header
+}
+
+// values reads the columns from the node's metadata, by column name
+func (c customColumns) values(node v1.Node) map[string]string {
+	if len(c) == 0 {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, column := range c {
+		metadata := node.Labels
+		if column.Source == "annotation" {
+			metadata = node.Annotations
+		}
+		if value, exists := metadata[column.Key]; exists {
+			values[column.Name] = value
+		}
+	}
+	return values
+}
+
+// cells returns the tab-prefixed column values of a node to append to its row, <none> when unset
+func (c customColumns) cells(n NodeInfo) string {
+	var cells string
+	for _, column := range c {
+		value, exists := n.Columns[column.Name]
+		if !exists {
+			value = "<none>"
+		}
+		cells += "\t" + value
+	}
+	return cells
+}
@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+// commandNames are the subcommands dispatched in main, for suggestions on a mistyped one
+var commandNames = []string{
+	"density", "check-access", "wait", "rollout-status", "join-lag", "why-gone", "drain-check", "label", "taint",
+	"asgs", "health", "problems", "blocked", "daemon", "trend", "disruption", "cost", "kubelet-drift", "recycle",
+	"plan-upgrade", "sg-check", "clock-drift", "consolidation", "cpu-topology", "confidential", "describe",
+	"simulate-az-failure", "audit", "amis", "spot-pools", "pods", "open", "open-asg",
+}
+
+// unknownCommandError rejects positional arguments the node list has no use for, so a mistyped
+// subcommand isn't taken for a request to list every node. Like kubectl, it suggests subcommands
+// within two edits of the argument.
+func unknownCommandError(arg string) error {
+	var suggestions []string
+	for _, name := range commandNames {
+		if editDistance(arg, name) <= 2 {
+			suggestions = append(suggestions, name)
+		}
+	}
+	if len(suggestions) == 0 {
+		return fmt.Errorf("unknown command %q, see --help for the commands", arg)
+	}
+	return fmt.Errorf("unknown command %q, did you mean %s?", arg, joinOr(suggestions))
+}
+
+// joinOr lists the words as "a", "a or b" or "a, b or c"
+func joinOr(words []string) string {
+	if len(words) == 1 {
+		return words[0]
+	}
+	list := words[0]
+	for _, word := range words[1 : len(words)-1] {
+		list += ", " + word
+	}
+	return list + " or " + words[len(words)-1]
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
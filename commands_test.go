@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestUnknownCommandError(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"recyle", `unknown command "recyle", did you mean recycle?`},
+		{"cots", `unknown command "cots", did you mean cost or pods?`},
+		{"opne", `unknown command "opne", did you mean open?`},
+		{"node/ip-10-0-1-100", `unknown command "node/ip-10-0-1-100", see --help for the commands`},
+	}
+
+	for _, tt := range tests {
+		if got := unknownCommandError(tt.arg).Error(); got != tt.want {
+			t.Errorf("unknownCommandError(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"recycle", "recycle", 0},
+		{"recyle", "recycle", 1},
+		{"open-ags", "open-asg", 2},
+		{"", "pods", 4},
+		{"cost", "", 4},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
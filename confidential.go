@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func runConfidential(args []string) {
+	fs := flag.NewFlagSet("confidential", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s confidential\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show whether each node's instance type supports Nitro Enclaves and AMD SEV-SNP, and whether\n")
+		fmt.Fprintf(os.Stderr, "its instance was launched with them enabled, for placing confidential-computing workloads.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+
+	typeInfo, err := getNodeInstanceTypeInfo(awsConfig, nodes.Items, instanceMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error describing instance types: %v\n", err)
+		os.Exit(1)
+	}
+
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NODE\tINSTANCE-TYPE\tENCLAVES-SUPPORTED\tENCLAVES-ENABLED\tSEV-SNP-SUPPORTED\tSEV-SNP-ENABLED")
+	for _, node := range nodes.Items {
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t-\t-\n", node.Name, valueOrDash(getInstanceType(node)))
+			continue
+		}
+
+		enclavesSupported, sevSnpSupported := "-", "-"
+		if info, exists := typeInfo[string(instance.InstanceType)]; exists {
+			enclavesSupported = formatFlag(info.NitroEnclavesSupport == types.NitroEnclavesSupportSupported)
+			sevSnpSupported = formatFlag(supportsSevSnp(info))
+		}
+		enclavesEnabled := instance.EnclaveOptions != nil && aws.ToBool(instance.EnclaveOptions.Enabled)
+		sevSnpEnabled := instance.CpuOptions != nil && instance.CpuOptions.AmdSevSnp == types.AmdSevSnpSpecificationEnabled
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", node.Name, instance.InstanceType,
+			enclavesSupported, formatFlag(enclavesEnabled), sevSnpSupported, formatFlag(sevSnpEnabled))
+	}
+	w.Flush()
+}
+
+func supportsSevSnp(info types.InstanceTypeInfo) bool {
+	if info.ProcessorInfo == nil {
+		return false
+	}
+	for _, feature := range info.ProcessorInfo.SupportedFeatures {
+		if feature == types.SupportedAdditionalProcessorFeatureAmdSevSnp {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const defaultASGTagKey = "aws:autoscaling:groupName"
+
+// Config is the optional on-disk configuration for the plugin
+type Config struct {
+	// GroupTags lists EC2 tag keys used to detect a node's group, in priority order
+	GroupTags []string `json:"groupTags,omitempty"`
+	// Headroom is the capacity policy checked by the problems command
+	Headroom *HeadroomConfig `json:"headroom,omitempty"`
+	// CriticalDaemonSets names the addon DaemonSets a Ready node must run to be serviceable
+	CriticalDaemonSets []string `json:"criticalDaemonSets,omitempty"`
+	// RequiredLabels lists the label keys every node must carry, checked by the audit command
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+}
+
+// HeadroomConfig sets the minimum unrequested capacity per node group
+type HeadroomConfig struct {
+	Default HeadroomPolicy            `json:"default,omitempty"`
+	Groups  map[string]HeadroomPolicy `json:"groups,omitempty"`
+}
+
+// HeadroomPolicy holds minimum free percentages of allocatable CPU and memory. Unset values are not checked.
+type HeadroomPolicy struct {
+	CPU    *float64 `json:"cpu,omitempty"`
+	Memory *float64 `json:"memory,omitempty"`
+}
+
+// headroomFor returns the group's policy, falling back to the default for unset values
+func (c *Config) headroomFor(group string) HeadroomPolicy {
+	if c == nil || c.Headroom == nil {
+		return HeadroomPolicy{}
+	}
+	policy := c.Headroom.Default
+	if override, exists := c.Headroom.Groups[group]; exists {
+		if override.CPU != nil {
+			policy.CPU = override.CPU
+		}
+		if override.Memory != nil {
+			policy.Memory = override.Memory
+		}
+	}
+	return policy
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "kubectl-aws-nodes", "config.yaml")
+}
+
+func configOrDefault(path string) string {
+	if path != "" {
+		return path
+	}
+	return defaultConfigPath()
+}
+
+// loadConfig reads the config file at path. A missing file is not an error
+// unless the path was given explicitly.
+func loadConfig(path string, explicit bool) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return cfg, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// resolveGroupTags picks the group tag keys from the flag, then the config file, then the default
+func resolveGroupTags(flagValue string, cfg *Config) []string {
+	if flagValue != "" {
+		return splitList(flagValue)
+	}
+	if cfg != nil && len(cfg.GroupTags) > 0 {
+		return cfg.GroupTags
+	}
+	return []string{defaultASGTagKey}
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// zoneRegion matches the region prefix of an Availability Zone, Local Zone or Wavelength Zone
+// name, e.g. us-west-2 in us-west-2a, us-west-2-lax-1a and us-east-1-wl1-bos-wlz-1
+var zoneRegion = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+`)
+
+// ConsoleLinks holds pre-built AWS console URLs for a node's instance
+type ConsoleLinks struct {
+	EC2        string `json:"ec2,omitempty"`
+	ASG        string `json:"asg,omitempty"`
+	CloudWatch string `json:"cloudWatch,omitempty"`
+}
+
+// consoleHost returns the console hostname for the partition the region belongs to
+func consoleHost(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return region + ".console.amazonaws.cn"
+	case strings.HasPrefix(region, "us-gov-"):
+		return region + ".console.amazonaws-us-gov.com"
+	default:
+		return region + ".console.aws.amazon.com"
+	}
+}
+
+// nodeRegion returns the region of the node's instance, from its region label or the zone in its
+// providerID, so clusters spanning regions or Local Zones get working links. It falls back to the
+// configured region.
+func nodeRegion(node v1.Node, fallback string) string {
+	if region := node.Labels["topology.kubernetes.io/region"]; region != "" {
+		return region
+	}
+	return regionOfZone(getNodeZone(node), fallback)
+}
+
+// regionOfZone returns the region a zone belongs to, or the fallback for an unknown zone
+func regionOfZone(zone, fallback string) string {
+	if region := zoneRegion.FindString(zone); region != "" {
+		return region
+	}
+	return fallback
+}
+
+func ec2ConsoleURL(region, instanceID string) string {
+	return fmt.Sprintf("https://%s/ec2/home?region=%s#InstanceDetails:instanceId=%s",
+		consoleHost(region), region, instanceID)
+}
+
+func asgConsoleURL(region, asgName string) string {
+	return fmt.Sprintf("https://%s/ec2/home?region=%s#AutoScalingGroupDetails:id=%s",
+		consoleHost(region), region, url.PathEscape(asgName))
+}
+
+func cloudWatchConsoleURL(region, instanceID string) string {
+	return fmt.Sprintf("https://%s/cloudwatch/home?region=%s#metricsV2:graph=~();search=%s",
+		consoleHost(region), region, instanceID)
+}
+
+func buildConsoleLinks(region, instanceID, asgName string) *ConsoleLinks {
+	if region == "" || instanceID == "" {
+		return nil
+	}
+	links := &ConsoleLinks{
+		EC2:        ec2ConsoleURL(region, instanceID),
+		CloudWatch: cloudWatchConsoleURL(region, instanceID),
+	}
+	if asgName != "" {
+		links.ASG = asgConsoleURL(region, asgName)
+	}
+	return links
+}
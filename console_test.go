@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeRegion(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels map[string]string
+		zone   string
+		want   string
+	}{
+		{"availability zone", nil, "us-west-2a", "us-west-2"},
+		{"availability zone with two-digit region", nil, "eu-central-1c", "eu-central-1"},
+		{"multi-part region", nil, "ap-southeast-2b", "ap-southeast-2"},
+		{"local zone", nil, "us-west-2-lax-1a", "us-west-2"},
+		{"wavelength zone", nil, "us-east-1-wl1-bos-wlz-1", "us-east-1"},
+		{"govcloud", nil, "us-gov-west-1a", "us-gov-west-1"},
+		{"china", nil, "cn-north-1a", "cn-north-1"},
+		{"region label wins", map[string]string{"topology.kubernetes.io/region": "eu-west-1"}, "us-west-2a", "eu-west-1"},
+		{"no zone", nil, "", "fallback-1"},
+		{"unknown zone", nil, "not-a-zone", "fallback-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}}
+			if tt.zone != "" {
+				node.Spec.ProviderID = "aws:///" + tt.zone + "/i-0123456789abcdef0"
+			}
+			if got := nodeRegion(node, "fallback-1"); got != tt.want {
+				t.Errorf("nodeRegion(%q) = %q, want %q", tt.zone, got, tt.want)
+			}
+		})
+	}
+}
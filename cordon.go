@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// cordonNode marks the node (un)schedulable and confirms with its instance ID and ASG
+func cordonNode(nodeName string, cordon bool, groupTagKeys []string) {
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting node '%s': %v\n", nodeName, err)
+		os.Exit(1)
+	}
+
+	if err := setUnschedulable(clientset, nodeName, cordon); err != nil {
+		fmt.Fprintf(os.Stderr, "Error patching node '%s': %v\n", nodeName, err)
+		os.Exit(1)
+	}
+
+	action := "uncordoned"
+	if cordon {
+		action = "cordoned"
+	}
+
+	// The AWS details are only for the confirmation, so failing to get them is not an error
+	instanceID := getInstanceID(*node)
+	group := ""
+	if instanceID != "" {
+		if awsConfig, err := loadAWSConfig(); err != nil {
+			warnf("could not load AWS config: %v\n", err)
+		} else if instanceMap, err := getNodeInstances(context.TODO(), awsConfig, []v1.Node{*node}); err != nil {
+			warnf("could not get EC2 instance: %v\n", err)
+		} else if instance, exists := instanceMap[instanceID]; exists {
+			group = getGroupFromTags(instance.Tags, groupTagKeys)
+		}
+	}
+	fmt.Printf("node/%s %s (instance: %s, ASG: %s)\n", nodeName, action, valueOrDash(instanceID), valueOrDash(group))
+}
+
+func setUnschedulable(clientset *kubernetes.Clientset, nodeName string, unschedulable bool) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))
+	_, err := clientset.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// hoursPerMonth is the average number of hours in a month, as used by AWS pricing
+const hoursPerMonth = 730
+
+// priceBook holds the prices used by the cost view. Prices assume Linux with shared tenancy.
+type priceBook struct {
+	Region   string             // of nodes without a known zone
+	OnDemand map[string]float64 // by instance type and region, "m5.large/us-east-1"
+	Spot     map[string]float64 // by instance type and zone, "m5.large/us-east-1a"
+}
+
+func newPricingClient(cfg aws.Config) *awsJSONClient {
+	region := "us-east-1"
+	host := "api.pricing.us-east-1.amazonaws.com"
+	if strings.HasPrefix(cfg.Region, "cn-") {
+		region = "cn-northwest-1"
+		host = "api.pricing.cn-northwest-1.amazonaws.com.cn"
+	}
+	return &awsJSONClient{
+		cfg:          cfg,
+		serviceID:    "Pricing",
+		signingName:  "pricing",
+		region:       region,
+		endpoint:     "https://" + host + "/",
+		targetPrefix: "AWSPriceListService.",
+	}
+}
+
+// getPriceBook looks up on-demand prices for the instance types of each region, and spot prices
+// for the spot ones, both by region
+func getPriceBook(cfg aws.Config, instanceTypes, spotTypes map[string][]string) (*priceBook, error) {
+	book := &priceBook{Region: cfg.Region, OnDemand: make(map[string]float64), Spot: make(map[string]float64)}
+	pricing := newPricingClient(cfg)
+	for region, regionTypes := range instanceTypes {
+		for _, instanceType := range regionTypes {
+			var price float64
+			err := cachedJSON(cacheKey(cfg, "on-demand-price", region, []string{instanceType}), onDemandPriceTTL, &price, func() (err error) {
+				price, err = getOnDemandPrice(context.TODO(), pricing, region, instanceType)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("getting on-demand price of %s in %s: %w", instanceType, region, err)
+			}
+			if price > 0 {
+				book.OnDemand[instanceType+"/"+region] = price
+			}
+		}
+	}
+
+	for region, regionTypes := range spotTypes {
+		client := ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+		var spot map[string]float64
+		err := cachedJSON(cacheKey(cfg, "spot-prices", region, regionTypes), opts.cacheTTL, &spot, func() (err error) {
+			spot, err = getSpotPrices(client, regionTypes)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("getting spot prices in %s: %w", region, err)
+		}
+		for key, price := range spot {
+			book.Spot[key] = price
+		}
+	}
+	return book, nil
+}
+
+// getInstancePrices builds the price book for the instance types in use, in the region of each instance
+func getInstancePrices(cfg aws.Config, instanceMap map[string]types.Instance) (*priceBook, error) {
+	instanceTypes := make(map[string][]string)
+	spotTypes := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, instance := range instanceMap {
+		instanceType := string(instance.InstanceType)
+		zone := ""
+		if instance.Placement != nil {
+			zone = aws.ToString(instance.Placement.AvailabilityZone)
+		}
+		region := regionOfZone(zone, cfg.Region)
+		if instanceLifecycle(instance) == "spot" && !seen["spot/"+instanceType+"/"+region] {
+			seen["spot/"+instanceType+"/"+region] = true
+			spotTypes[region] = append(spotTypes[region], instanceType)
+		}
+		if !seen[instanceType+"/"+region] {
+			seen[instanceType+"/"+region] = true
+			instanceTypes[region] = append(instanceTypes[region], instanceType)
+		}
+	}
+	return getPriceBook(cfg, instanceTypes, spotTypes)
+}
+
+// getOnDemandPrice returns the hourly USD on-demand price of a Linux instance type in region, 0 if unknown
+func getOnDemandPrice(ctx context.Context, client *awsJSONClient, region, instanceType string) (float64, error) {
+	filter := func(field, value string) map[string]string {
+		return map[string]string{"Type": "TERM_MATCH", "Field": field, "Value": value}
+	}
+	input := map[string]interface{}{
+		"ServiceCode": "AmazonEC2",
+		"Filters": []map[string]string{
+			filter("instanceType", instanceType),
+			filter("regionCode", region),
+			filter("operatingSystem", "Linux"),
+			filter("tenancy", "Shared"),
+			filter("preInstalledSw", "NA"),
+			filter("capacitystatus", "Used"),
+			filter("licenseModel", "No License required"),
+		},
+		"FormatVersion": "aws_v1",
+		"MaxResults":    10,
+	}
+	var output struct {
+		PriceList []string `json:"PriceList"`
+	}
+	if err := client.call(ctx, "GetProducts", input, &output); err != nil {
+		return 0, err
+	}
+
+	// Each entry is itself a JSON document; the on-demand term has a single hourly dimension
+	for _, item := range output.PriceList {
+		var product struct {
+			Terms struct {
+				OnDemand map[string]struct {
+					PriceDimensions map[string]struct {
+						Unit         string            `json:"unit"`
+						PricePerUnit map[string]string `json:"pricePerUnit"`
+					} `json:"priceDimensions"`
+				} `json:"OnDemand"`
+			} `json:"terms"`
+		}
+		if err := json.Unmarshal([]byte(item), &product); err != nil {
+			return 0, err
+		}
+		for _, term := range product.Terms.OnDemand {
+			for _, dimension := range term.PriceDimensions {
+				if dimension.Unit != "Hrs" {
+					continue
+				}
+				price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+				if err == nil && price > 0 {
+					return price, nil
+				}
+			}
+		}
+	}
+	return 0, nil
+}
+
+// getSpotPrices returns the current Linux spot price per instance type and zone
+func getSpotPrices(client *ec2.Client, instanceTypes []string) (map[string]float64, error) {
+	var typeFilter []types.InstanceType
+	for _, instanceType := range instanceTypes {
+		typeFilter = append(typeFilter, types.InstanceType(instanceType))
+	}
+
+	prices := make(map[string]float64)
+	// A start time of now returns only the price in effect for each type and zone
+	paginator := ec2.NewDescribeSpotPriceHistoryPaginator(client, &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       typeFilter,
+		ProductDescriptions: []string{"Linux/UNIX"},
+		StartTime:           aws.Time(time.Now()),
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, classifyAWSError(err)
+		}
+		for _, entry := range result.SpotPriceHistory {
+			key := string(entry.InstanceType) + "/" + aws.ToString(entry.AvailabilityZone)
+			if _, seen := prices[key]; seen {
+				continue
+			}
+			if price, err := strconv.ParseFloat(aws.ToString(entry.SpotPrice), 64); err == nil {
+				prices[key] = price
+			}
+		}
+	}
+	return prices, nil
+}
+
+// applyPrices sets the node's prices in its region; spot nodes are charged the spot price of their zone
+func (b *priceBook) applyPrices(nodeInfo *NodeInfo) {
+	if b == nil {
+		return
+	}
+	nodeInfo.OnDemandPrice = b.OnDemand[nodeInfo.InstanceType+"/"+regionOfZone(nodeInfo.Zone, b.Region)]
+	nodeInfo.HourlyCost = nodeInfo.OnDemandPrice
+	if nodeInfo.Lifecycle == "spot" {
+		nodeInfo.SpotPrice = b.Spot[nodeInfo.InstanceType+"/"+nodeInfo.Zone]
+		nodeInfo.HourlyCost = nodeInfo.SpotPrice
+	}
+}
+
+func formatPrice(price float64) string {
+	if price == 0 {
+		return "-"
+	}
+	if opts.plainNumbers {
+		return fmt.Sprintf("%.4f", price)
+	}
+	return fmt.Sprintf("$%.4f", price)
+}
+
+func formatMonthly(hourly float64) string {
+	if hourly == 0 {
+		return "-"
+	}
+	if opts.plainNumbers {
+		return fmt.Sprintf("%.2f", hourly*hoursPerMonth)
+	}
+	return fmt.Sprintf("$%.2f", hourly*hoursPerMonth)
+}
+
+// printCostTotals prints the hourly and monthly cost per ASG and for the whole cluster
+func printCostTotals(out io.Writer, nodes []NodeInfo) {
+	printCostRollup(out, "ASG", nodes, func(n NodeInfo) string { return n.ASG })
+}
+
+// printCostRollup prints the hourly and monthly cost per group, as returned by groupOf, and for the whole cluster
+func printCostRollup(out io.Writer, header string, nodes []NodeInfo, groupOf func(NodeInfo) string) {
+	type total struct {
+		nodes  int
+		hourly float64
+	}
+	byGroup := make(map[string]*total)
+	var cluster total
+	unpriced := 0
+	for _, n := range nodes {
+		group := groupOf(n)
+		if group == "" {
+			group = "<none>"
+		}
+		if byGroup[group] == nil {
+			byGroup[group] = &total{}
+		}
+		byGroup[group].nodes++
+		byGroup[group].hourly += n.HourlyCost
+		cluster.nodes++
+		cluster.hourly += n.HourlyCost
+		if n.HourlyCost == 0 {
+			unpriced++
+		}
+	}
+
+	var groups []string
+	for group := range byGroup {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, header+"\tNODES\tHOURLY\tMONTHLY")
+	for _, group := range groups {
+		t := byGroup[group]
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", group, t.nodes, formatPrice(t.hourly), formatMonthly(t.hourly))
+	}
+	fmt.Fprintf(w, "TOTAL\t%d\t%s\t%s\n", cluster.nodes, formatPrice(cluster.hourly), formatMonthly(cluster.hourly))
+	w.Flush()
+
+	if unpriced > 0 {
+		warnf("%d node(s) without a known price are not included in the totals\n", unpriced)
+	}
+}
+
+func runCost(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("cost", flag.ExitOnError)
+	byTag := fs.String("by-tag", "", "Aggregate cost by this EC2 tag key (e.g. CostCenter) instead of by ASG")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s cost [--by-tag KEY]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Estimate the hourly and monthly cost of the cluster's nodes, per ASG or per value of an\n")
+		fmt.Fprintf(os.Stderr, "EC2 tag for chargeback. Nodes without the tag are grouped under <none>.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+
+	prices, err := getInstancePrices(awsConfig, instanceMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting prices: %v\n", err)
+		os.Exit(1)
+	}
+
+	var nodeInfos []NodeInfo
+	for _, node := range nodes.Items {
+		nodeInfo := newNodeInfo(node, nil, instanceMap, nil, awsConfig.Region, groupTagKeys)
+		prices.applyPrices(&nodeInfo)
+		nodeInfos = append(nodeInfos, nodeInfo)
+	}
+
+	if *byTag == "" {
+		printCostTotals(os.Stdout, nodeInfos)
+		return
+	}
+	printCostRollup(os.Stdout, strings.ToUpper(*byTag), nodeInfos, func(n NodeInfo) string {
+		instance, exists := instanceMap[n.InstanceID]
+		if !exists {
+			return ""
+		}
+		for _, tag := range instance.Tags {
+			if aws.ToString(tag.Key) == *byTag {
+				return aws.ToString(tag.Value)
+			}
+		}
+		return ""
+	})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// numaNodesCommand counts the NUMA nodes the kernel exposes, which EC2 does not report
+const numaNodesCommand = "ls -d /sys/devices/system/node/node[0-9]* | wc -l"
+
+// kubeletCPUConfig holds the kubelet settings that decide how CPUs are assigned to containers
+type kubeletCPUConfig struct {
+	CPUManagerPolicy      string `json:"cpuManagerPolicy"`
+	TopologyManagerPolicy string `json:"topologyManagerPolicy"`
+	TopologyManagerScope  string `json:"topologyManagerScope"`
+	ReservedSystemCPUs    string `json:"reservedSystemCPUs"`
+}
+
+func runCPUTopology(args []string) {
+	fs := flag.NewFlagSet("cpu-topology", flag.ExitOnError)
+	numa := fs.Bool("numa", false, "Also count each node's NUMA nodes through SSM Run Command")
+	numaTimeout := fs.Duration("numa-timeout", time.Minute, "Maximum time to wait for the NUMA node counts")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s cpu-topology [--numa]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show each node's CPU layout (vCPUs, cores, threads per core) from EC2 and how its kubelet\n")
+		fmt.Fprintf(os.Stderr, "assigns CPUs (CPU manager and topology manager policies, reserved CPUs) from configz.\n")
+		fmt.Fprintf(os.Stderr, "EC2 does not report NUMA layout; --numa reads it from the node itself.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.TODO()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	typeInfo, err := getNodeInstanceTypeInfo(awsConfig, nodes.Items, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error describing instance types: %v\n", err)
+		os.Exit(1)
+	}
+
+	var numaNodes map[string]string
+	if *numa {
+		numaNodes, err = runNodeShellCommand(ctx, awsConfig, nodes.Items, numaNodesCommand,
+			"kubectl-aws-nodes cpu-topology", *numaTimeout, strings.TrimSpace)
+		if err != nil {
+			warnf("could not count NUMA nodes through SSM: %v\n", err)
+		}
+	}
+
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	header := "NODE\tINSTANCE-TYPE\tVCPUS\tCORES\tTHREADS/CORE\tCPU-MANAGER\tTOPOLOGY-MANAGER\tRESERVED-CPUS"
+	if *numa {
+		header += "\tNUMA-NODES"
+	}
+	fmt.Fprintln(w, header)
+	for _, node := range nodes.Items {
+		instanceType := getInstanceType(node)
+		vcpus, cores, threads := "-", "-", "-"
+		if info, exists := typeInfo[instanceType]; exists && info.VCpuInfo != nil {
+			vcpus = formatInt32(info.VCpuInfo.DefaultVCpus)
+			cores = formatInt32(info.VCpuInfo.DefaultCores)
+			threads = formatInt32(info.VCpuInfo.DefaultThreadsPerCore)
+		}
+
+		cpuManager, topologyManager, reserved := "?", "?", "?"
+		config, err := getKubeletCPUConfig(clientset, node.Name)
+		if err != nil {
+			warnf("could not read kubelet config of node '%s': %v\n", node.Name, err)
+		} else {
+			cpuManager = config.CPUManagerPolicy
+			topologyManager = config.TopologyManagerPolicy + " (" + config.TopologyManagerScope + ")"
+			reserved = valueOrDash(config.ReservedSystemCPUs)
+		}
+
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s", node.Name, valueOrDash(instanceType), vcpus, cores, threads,
+			cpuManager, topologyManager, reserved)
+		if *numa {
+			row += "\t" + valueOrDash(numaNodes[getInstanceID(node)])
+		}
+		fmt.Fprintln(w, row)
+	}
+	w.Flush()
+}
+
+// getNodeInstanceTypeInfo describes the nodes' instance types, from instanceMap or else the node
+// labels, in the region of each node since not every type is offered everywhere
+func getNodeInstanceTypeInfo(cfg aws.Config, nodes []v1.Node, instanceMap map[string]types.Instance) (map[string]types.InstanceTypeInfo, error) {
+	typesByRegion := make(map[string]map[string]bool)
+	for _, node := range nodes {
+		instanceType := getInstanceType(node)
+		if instance, exists := instanceMap[getInstanceID(node)]; exists {
+			instanceType = string(instance.InstanceType)
+		}
+		if instanceType == "" {
+			continue
+		}
+		region := nodeRegion(node, cfg.Region)
+		if typesByRegion[region] == nil {
+			typesByRegion[region] = make(map[string]bool)
+		}
+		typesByRegion[region][instanceType] = true
+	}
+
+	info := make(map[string]types.InstanceTypeInfo)
+	for region, instanceTypes := range typesByRegion {
+		client := ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+		regionInfo, err := getInstanceTypeInfo(client, instanceTypes)
+		if err != nil {
+			return nil, fmt.Errorf("in %s: %w", region, err)
+		}
+		for name, instanceType := range regionInfo {
+			info[name] = instanceType
+		}
+	}
+	return info, nil
+}
+
+// getInstanceTypeInfo describes the instance types by name
+func getInstanceTypeInfo(client *ec2.Client, instanceTypes map[string]bool) (map[string]types.InstanceTypeInfo, error) {
+	info := make(map[string]types.InstanceTypeInfo)
+	if len(instanceTypes) == 0 {
+		return info, nil
+	}
+
+	var names []types.InstanceType
+	for name := range instanceTypes {
+		names = append(names, types.InstanceType(name))
+	}
+	paginator := ec2.NewDescribeInstanceTypesPaginator(client, &ec2.DescribeInstanceTypesInput{InstanceTypes: names})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, classifyAWSError(err)
+		}
+		for _, instanceType := range result.InstanceTypes {
+			info[string(instanceType.InstanceType)] = instanceType
+		}
+	}
+	return info, nil
+}
+
+// getKubeletCPUConfig reads the node's CPU assignment settings from the kubelet configz,
+// with the kubelet defaults for settings missing from it
+func getKubeletCPUConfig(clientset *kubernetes.Clientset, nodeName string) (kubeletCPUConfig, error) {
+	config := kubeletCPUConfig{
+		CPUManagerPolicy:      "none",
+		TopologyManagerPolicy: "none",
+		TopologyManagerScope:  "container",
+	}
+	data, err := getKubeletConfigz(clientset, nodeName)
+	if err != nil {
+		return config, err
+	}
+
+	var configz struct {
+		KubeletConfig kubeletCPUConfig `json:"kubeletconfig"`
+	}
+	configz.KubeletConfig = config
+	if err := json.Unmarshal(data, &configz); err != nil {
+		return config, err
+	}
+	return configz.KubeletConfig, nil
+}
+
+func formatInt32(v *int32) string {
+	if v == nil {
+		return "-"
+	}
+	return strconv.Itoa(int(aws.ToInt32(v)))
+}
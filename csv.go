@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// printCSV writes the nodes as CSV for spreadsheets: a header row, then one row per node with
+// the columns of -o table (see nodeTableColumns) followed by the --column columns
+func printCSV(out io.Writer, nodeInfos []NodeInfo, columns customColumns) error {
+	w := csv.NewWriter(out)
+	var header []string
+	for _, column := range nodeTableColumns {
+		header = append(header, column.Name)
+	}
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, n := range nodeInfos {
+		var record []string
+		for _, cell := range nodeTableCells(n) {
+			record = append(record, fmt.Sprint(cell))
+		}
+		for _, column := range columns {
+			// Unset labels are empty cells rather than <none>, so spreadsheet formulas see blanks
+			record = append(record, n.Columns[column.Name])
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// outputColumn is one HEADER:.path column of -o custom-columns
+type outputColumn struct {
+	Header string
+	Path   []string // field names of the JSON output, with an optional [N] index each
+}
+
+// outputColumns is the parsed -o custom-columns= spec
+type outputColumns []outputColumn
+
+// parseOutputColumns parses "NAME:.name,ASG:.asg" like kubectl's custom-columns. Paths are the
+// JSON field names of -o json, dot-separated (e.g. .nodegroup.status, .staticPods[0]); kubectl's
+// braces are accepted, other JSONPath syntax (filters, wildcards) is not supported.
+func parseOutputColumns(spec string) (outputColumns, error) {
+	var columns outputColumns
+	for _, part := range strings.Split(spec, ",") {
+		header, path, found := strings.Cut(part, ":")
+		if !found || header == "" {
+			return nil, fmt.Errorf("expected HEADER:.path, got '%s'", part)
+		}
+		path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+		if !strings.HasPrefix(path, ".") || len(path) == 1 {
+			return nil, fmt.Errorf("column '%s': expected a path starting with '.', got '%s'", header, path)
+		}
+		column := outputColumn{Header: header}
+		for _, field := range strings.Split(path[1:], ".") {
+			if field == "" || strings.ContainsAny(field, "*?@()") {
+				return nil, fmt.Errorf("column '%s': unsupported path '%s'", header, path)
+			}
+			column.Path = append(column.Path, field)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// references reports whether any column reads one of the top-level fields, so data only some
+// views fetch (prices, kubelet stats) is looked up when needed
+func (c outputColumns) references(fields ...string) bool {
+	for _, column := range c {
+		name, _, _ := strings.Cut(column.Path[0], "[")
+		if containsString(fields, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// printCustomColumns writes one row per node with the selected fields, <none> when unset
+func printCustomColumns(out io.Writer, nodes []NodeInfo, columns outputColumns) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	var header []string
+	for _, column := range columns {
+		header = append(header, column.Header)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, n := range nodes {
+		// Going through JSON gives the same field names and value formats as -o json
+		data, err := json.Marshal(n)
+		if err != nil {
+			return err
+		}
+		var object interface{}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+
+		var cells []string
+		for _, column := range columns {
+			cells = append(cells, formatColumnValue(lookupPath(object, column.Path)))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// lookupPath walks the decoded JSON along the path, nil if any step is missing
+func lookupPath(value interface{}, path []string) interface{} {
+	for _, field := range path {
+		name, index, hasIndex := strings.Cut(field, "[")
+		if name != "" {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = object[name]
+		}
+		if hasIndex {
+			i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+			list, ok := value.([]interface{})
+			if err != nil || !ok || i < 0 || i >= len(list) {
+				return nil
+			}
+			value = list[i]
+		}
+	}
+	return value
+}
+
+func formatColumnValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "<none>"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
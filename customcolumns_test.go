@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOutputColumns(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    outputColumns
+		wantErr bool
+	}{
+		{
+			spec: "NAME:.name,ASG:.asg",
+			want: outputColumns{{Header: "NAME", Path: []string{"name"}}, {Header: "ASG", Path: []string{"asg"}}},
+		},
+		{
+			spec: "STATUS:.nodegroup.status",
+			want: outputColumns{{Header: "STATUS", Path: []string{"nodegroup", "status"}}},
+		},
+		{
+			spec: "FIRST:{.staticPods[0]}",
+			want: outputColumns{{Header: "FIRST", Path: []string{"staticPods[0]"}}},
+		},
+		{spec: "NAME", wantErr: true},
+		{spec: ":.name", wantErr: true},
+		{spec: "NAME:name", wantErr: true},
+		{spec: "NAME:.", wantErr: true},
+		{spec: "NAME:.nodegroup..status", wantErr: true},
+		{spec: "PODS:.staticPods[*]", wantErr: true},
+		{spec: "NAME:.name,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := parseOutputColumns(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseOutputColumns(%q) = %+v, want an error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOutputColumns(%q) failed: %v", tt.spec, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOutputColumns(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
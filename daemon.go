@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// inventorySnapshot is the document written by each export
+type inventorySnapshot struct {
+	Timestamp time.Time  `json:"timestamp"`
+	Nodes     []NodeInfo `json:"nodes"`
+}
+
+func runDaemon(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	exportS3 := fs.String("export-s3", "", "Write gzipped JSON inventory snapshots to this S3 location (s3://bucket/prefix)")
+	interval := fs.Duration("interval", time.Hour, "Time between snapshots")
+	once := fs.Bool("once", false, "Write a single snapshot and exit")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s daemon --export-s3 s3://bucket/prefix [--interval DURATION]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Run continuously and periodically export the node inventory: the -o json fields that come from\n")
+		fmt.Fprintf(os.Stderr, "the nodes, their pods, EC2 instances and ASGs (no root volume, AMI, node group status, Karpenter,\n")
+		fmt.Fprintf(os.Stderr, "instance refresh, launch template drift, pod IP family, usage or AWS Health events).\n")
+		fmt.Fprintf(os.Stderr, "Snapshots are written as PREFIX/YYYY/MM/DD/nodes-TIMESTAMP.json.gz.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if *exportS3 == "" {
+		fmt.Fprintf(os.Stderr, "Error: daemon requires --export-s3\n")
+		os.Exit(1)
+	}
+	bucket, prefix, err := parseS3URL(*exportS3)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --interval must be positive\n")
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for {
+		// A failed snapshot is reported and retried on the next tick instead of stopping the daemon
+		key, err := exportSnapshot(ctx, clientset, awsConfig, bucket, prefix, groupTagKeys)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Error exporting snapshot: %v\n", time.Now().UTC().Format(time.RFC3339), err)
+			if *once {
+				os.Exit(1)
+			}
+		} else {
+			fmt.Printf("%s Wrote s3://%s/%s\n", time.Now().UTC().Format(time.RFC3339), bucket, key)
+		}
+		if *once {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// exportSnapshot collects the inventory and uploads it, returning the object key
+func exportSnapshot(ctx context.Context, clientset *kubernetes.Clientset, awsConfig aws.Config, bucket, prefix string, groupTagKeys []string) (string, error) {
+	nodes, err := collectInventory(clientset, awsConfig, groupTagKeys)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(inventorySnapshot{Timestamp: now, Nodes: nodes}); err != nil {
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		return "", err
+	}
+
+	key := path.Join(prefix, now.Format("2006/01/02"), "nodes-"+now.Format("20060102T150405Z")+".json.gz")
+	if err := putS3Object(ctx, awsConfig, bucket, key, buf.Bytes(), "application/json", "gzip"); err != nil {
+		return "", fmt.Errorf("uploading to S3: %w", err)
+	}
+	return key, nil
+}
+
+// collectInventory gathers the NodeInfo records of -o json with the node, pod, EC2 instance and ASG
+// data only, without the lookups the list view adds for volumes, AMIs, node groups and Karpenter
+func collectInventory(clientset *kubernetes.Clientset, awsConfig aws.Config, groupTagKeys []string) ([]NodeInfo, error) {
+	inventory, _, err := collectInventoryAndPods(clientset, awsConfig, groupTagKeys)
+	return inventory, err
+}
+
+// collectInventoryAndPods is collectInventory that also returns the pods the requests were computed from
+func collectInventoryAndPods(clientset *kubernetes.Clientset, awsConfig aws.Config, groupTagKeys []string) ([]NodeInfo, []v1.Pod, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing nodes: %w", err)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing pods: %w", err)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting EC2 instances: %w", err)
+	}
+	asgMap, err := getNodeASGCapacities(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting ASG capacities: %w", err)
+	}
+
+	nodeResources := calculateNodeResources(nodes.Items, pods.Items)
+	inventory := []NodeInfo{}
+	for _, node := range nodes.Items {
+		inventory = append(inventory, newNodeInfo(node, nodeResources[node.Name], instanceMap, asgMap, awsConfig.Region, groupTagKeys))
+	}
+	return inventory, pods.Items, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const histogramBarWidth = 40
+
+// histogramBucket counts values in the half-open range [Low, High)
+type histogramBucket struct {
+	Label string
+	Low   float64
+	High  float64
+	Count int
+}
+
+func runDensity(args []string) {
+	fs := flag.NewFlagSet("density", flag.ExitOnError)
+	buckets := fs.Int("buckets", 10, "Number of buckets for the pods-per-node histogram")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s density [OPTIONS]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Print histograms of pods and requests per node across the fleet.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if *buckets < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --buckets must be at least 1\n")
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(nodes.Items) == 0 {
+		fmt.Println("No nodes found")
+		return
+	}
+
+	nodeResources := calculateNodeResources(nodes.Items, pods.Items)
+
+	var podCounts, cpuRequested, memRequested []float64
+	maxPods := 0
+	for _, nodeInfo := range nodeResources {
+		podCounts = append(podCounts, float64(nodeInfo.PodCount))
+		if nodeInfo.PodCount > maxPods {
+			maxPods = nodeInfo.PodCount
+		}
+		cpuRequested = append(cpuRequested, 100-calculateFreePercentage(nodeInfo.CPUCapacity, nodeInfo.CPURequested))
+		memRequested = append(memRequested, 100-calculateFreePercentage(nodeInfo.MemCapacity, nodeInfo.MemRequested))
+	}
+
+	fmt.Printf("Nodes: %d\n\n", len(nodeResources))
+	printHistogram(os.Stdout, "PODS-PER-NODE", podCountBuckets(maxPods, *buckets), podCounts)
+	fmt.Println()
+	printHistogram(os.Stdout, "CPU-REQ%", percentBuckets(), cpuRequested)
+	fmt.Println()
+	printHistogram(os.Stdout, "MEM-REQ%", percentBuckets(), memRequested)
+}
+
+// podCountBuckets splits 0..maxPods into at most n equally sized integer ranges
+func podCountBuckets(maxPods, n int) []histogramBucket {
+	width := (maxPods + n) / n
+	var buckets []histogramBucket
+	for low := 0; low <= maxPods; low += width {
+		high := low + width
+		label := fmt.Sprintf("%d-%d", low, high-1)
+		if width == 1 {
+			label = fmt.Sprintf("%d", low)
+		}
+		buckets = append(buckets, histogramBucket{Label: label, Low: float64(low), High: float64(high)})
+	}
+	return buckets
+}
+
+// percentBuckets returns 10% wide buckets with a final bucket for overcommitted nodes
+func percentBuckets() []histogramBucket {
+	var buckets []histogramBucket
+	for low := 0; low < 100; low += 10 {
+		buckets = append(buckets, histogramBucket{
+			Label: fmt.Sprintf("%d-%d%%", low, low+10),
+			Low:   float64(low),
+			High:  float64(low + 10),
+		})
+	}
+	// Fully requested nodes land in the last regular bucket
+	buckets[len(buckets)-1].High = 100.000001
+	buckets = append(buckets, histogramBucket{Label: ">100%", Low: 100.000001, High: 1e18})
+	return buckets
+}
+
+func printHistogram(out io.Writer, title string, buckets []histogramBucket, values []float64) {
+	maxCount := 0
+	for _, v := range values {
+		for i := range buckets {
+			if v >= buckets[i].Low && v < buckets[i].High {
+				buckets[i].Count++
+				if buckets[i].Count > maxCount {
+					maxCount = buckets[i].Count
+				}
+				break
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "%s\tNODES\t\n", title)
+	for _, b := range buckets {
+		bar := 0
+		if maxCount > 0 {
+			bar = b.Count * histogramBarWidth / maxCount
+		}
+		if b.Count > 0 && bar == 0 {
+			bar = 1
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", b.Label, b.Count, strings.Repeat("#", bar))
+	}
+	w.Flush()
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// describeBlockersWindow is how far back FailedScheduling events count for the node's scheduling blockers
+const describeBlockersWindow = time.Hour
+
+func runDescribe(args []string) {
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s describe NODE_NAME\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Print everything about one node: conditions, taints, labels, allocatable and requested\n")
+		fmt.Fprintf(os.Stderr, "resources, its EC2 instance (AMI, IPs, security groups, IAM profile, tags), EBS volumes and\n")
+		fmt.Fprintf(os.Stderr, "its ASG settings, with what keeps recently unschedulable pods off the node and what removing\n")
+		fmt.Fprintf(os.Stderr, "it would disrupt.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	nodeName, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.TODO()
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting node '%s': %v\n", nodeName, err)
+		os.Exit(1)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + node.Name})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+	resources := calculateNodeResources([]v1.Node{*node}, pods.Items)[node.Name]
+
+	out := os.Stdout
+	printNodeDescription(out, *node, resources)
+
+	pending, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName="})
+	if err != nil {
+		warnf("could not list pending pods, scheduling blockers are omitted: %v\n", err)
+	} else if unschedulable, err := recentlyUnschedulablePods(clientset, pending.Items, time.Now().Add(-describeBlockersWindow)); err != nil {
+		warnf("could not list FailedScheduling events, scheduling blockers are omitted: %v\n", err)
+	} else {
+		printBlockersDescription(out, *node, resources, unschedulable)
+	}
+
+	estimate, err := estimateDisruption(clientset, node.Name)
+	if err != nil {
+		warnf("could not estimate the disruption of removing the node: %v\n", err)
+	} else {
+		fmt.Fprintf(out, "\nDisruption If Removed:\n")
+		printDisruption(out, estimate)
+	}
+
+	instanceID := getInstanceID(*node)
+	if !strings.HasPrefix(instanceID, "i-") {
+		return
+	}
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		warnf("could not load AWS config, EC2 and ASG details are omitted: %v\n", err)
+		return
+	}
+	region := nodeRegion(*node, awsConfig.Region)
+
+	ec2Client := ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = region })
+	instances, err := getEC2Instances(ctx, ec2Client, []string{instanceID})
+	if err != nil {
+		warnf("could not describe instance %s: %v\n", instanceID, err)
+		return
+	}
+	instance, exists := instances[instanceID]
+	if !exists {
+		fmt.Fprintf(out, "\nEC2 Instance:\n  %s not found, terminated instances disappear after about an hour\n", instanceID)
+		return
+	}
+	printInstanceDescription(out, instance)
+
+	volumes, err := getNodeVolumes(awsConfig, []v1.Node{*node}, instances)
+	if err != nil {
+		warnf("could not describe EBS volumes of instance %s: %v\n", instanceID, err)
+	} else {
+		printVolumesDescription(out, volumes[instanceID])
+	}
+
+	asgName := instanceASG(instance)
+	if asgName == "" {
+		return
+	}
+	asClient := autoscaling.NewFromConfig(awsConfig, func(o *autoscaling.Options) { o.Region = region })
+	result, err := asClient.DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{asgName},
+	})
+	if err != nil {
+		warnf("could not describe ASG %s: %v\n", asgName, classifyAWSError(err))
+		return
+	}
+	if len(result.AutoScalingGroups) == 0 {
+		return
+	}
+	printASGDescription(out, result.AutoScalingGroups[0], instanceID)
+}
+
+// printNodeDescription writes the Kubernetes side of a node in kubectl describe's block format
+func printNodeDescription(out io.Writer, node v1.Node, resources *NodeInfo) {
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", node.Name)
+	fmt.Fprintf(w, "Status:\t%s\n", getNodeStatus(node))
+	fmt.Fprintf(w, "Unschedulable:\t%t\n", node.Spec.Unschedulable)
+	fmt.Fprintf(w, "Created:\t%s (%s ago)\n", node.CreationTimestamp.Format(time.RFC3339), getNodeAge(node))
+	fmt.Fprintf(w, "Kubelet version:\t%s\n", node.Status.NodeInfo.KubeletVersion)
+	fmt.Fprintf(w, "OS image:\t%s\n", node.Status.NodeInfo.OSImage)
+	fmt.Fprintf(w, "Kernel version:\t%s\n", node.Status.NodeInfo.KernelVersion)
+	fmt.Fprintf(w, "Container runtime:\t%s\n", node.Status.NodeInfo.ContainerRuntimeVersion)
+	fmt.Fprintf(w, "Provider ID:\t%s\n", valueOrDash(node.Spec.ProviderID))
+	w.Flush()
+
+	fmt.Fprintf(out, "\nConditions:\n")
+	w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  TYPE\tSTATUS\tLAST-TRANSITION\tREASON\tMESSAGE")
+	for _, condition := range node.Status.Conditions {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", condition.Type, condition.Status,
+			condition.LastTransitionTime.Format(time.RFC3339), valueOrDash(condition.Reason), valueOrDash(condition.Message))
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\nTaints:\n")
+	if len(node.Spec.Taints) == 0 {
+		fmt.Fprintf(out, "  <none>\n")
+	}
+	for _, taint := range node.Spec.Taints {
+		fmt.Fprintf(out, "  %s\n", taint.ToString())
+	}
+
+	fmt.Fprintf(out, "\nLabels:\n")
+	printSortedMap(out, node.Labels)
+
+	fmt.Fprintf(out, "\nResources:\n")
+	w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  RESOURCE\tALLOCATABLE\tREQUESTED\tFREE")
+	fmt.Fprintf(w, "  cpu\t%s\t%s\t%s\n", formatResource(resources.CPUCapacity), formatResource(resources.CPURequested),
+		formatPercent(calculateFreePercentage(resources.CPUCapacity, resources.CPURequested)))
+	fmt.Fprintf(w, "  memory\t%s\t%s\t%s\n", formatMemory(resources.MemCapacity), formatMemory(resources.MemRequested),
+		formatPercent(calculateFreePercentage(resources.MemCapacity, resources.MemRequested)))
+	fmt.Fprintf(w, "  ephemeral-storage\t%s\t%s\t%s\n", formatMemory(resources.EphCapacity), formatMemory(resources.EphRequested),
+		formatPercent(calculateFreePercentage(resources.EphCapacity, resources.EphRequested)))
+	fmt.Fprintf(w, "  pods\t%d\t%d\t\n", node.Status.Allocatable.Pods().Value(), resources.PodCount+len(resources.StaticPods))
+	w.Flush()
+}
+
+// printBlockersDescription writes why recently unschedulable pods could not land on the node
+func printBlockersDescription(out io.Writer, node v1.Node, resources *NodeInfo, pending []v1.Pod) {
+	fmt.Fprintf(out, "\nScheduling Blockers (last %s):\n", describeBlockersWindow)
+	reasons := make(map[string]int)
+	blocked := 0
+	for _, pod := range pending {
+		if reason := schedulingBlocker(pod, node, resources); reason != "" {
+			reasons[reason]++
+			blocked++
+		}
+	}
+	if blocked == 0 {
+		fmt.Fprintf(out, "  <none>\n")
+		return
+	}
+	fmt.Fprintf(out, "  %d of %d pending pod(s) blocked: %s\n", blocked, len(pending), formatBlockers(reasons))
+}
+
+// printInstanceDescription writes the EC2 details of a node's instance
+func printInstanceDescription(out io.Writer, instance types.Instance) {
+	fmt.Fprintf(out, "\nEC2 Instance:\n")
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	state := ""
+	if instance.State != nil {
+		state = string(instance.State.Name)
+	}
+	fmt.Fprintf(w, "  ID:\t%s\n", aws.ToString(instance.InstanceId))
+	fmt.Fprintf(w, "  State:\t%s\n", valueOrDash(state))
+	fmt.Fprintf(w, "  Type:\t%s\n", instance.InstanceType)
+	fmt.Fprintf(w, "  Lifecycle:\t%s\n", instanceLifecycle(instance))
+	fmt.Fprintf(w, "  AMI:\t%s\n", aws.ToString(instance.ImageId))
+	if instance.LaunchTime != nil {
+		fmt.Fprintf(w, "  Launch time:\t%s\n", instance.LaunchTime.Format(time.RFC3339))
+	}
+	zone := ""
+	if instance.Placement != nil {
+		zone = aws.ToString(instance.Placement.AvailabilityZone)
+	}
+	fmt.Fprintf(w, "  Zone:\t%s\n", valueOrDash(zone))
+	fmt.Fprintf(w, "  VPC:\t%s\n", valueOrDash(aws.ToString(instance.VpcId)))
+	fmt.Fprintf(w, "  Subnet:\t%s\n", valueOrDash(aws.ToString(instance.SubnetId)))
+	fmt.Fprintf(w, "  Private IP:\t%s\n", valueOrDash(aws.ToString(instance.PrivateIpAddress)))
+	fmt.Fprintf(w, "  IPv6:\t%s\n", valueOrDash(aws.ToString(instance.Ipv6Address)))
+	fmt.Fprintf(w, "  Private DNS:\t%s\n", valueOrDash(aws.ToString(instance.PrivateDnsName)))
+	fmt.Fprintf(w, "  Public IP:\t%s\n", valueOrDash(aws.ToString(instance.PublicIpAddress)))
+	var groups []string
+	for _, group := range instance.SecurityGroups {
+		groups = append(groups, fmt.Sprintf("%s (%s)", aws.ToString(group.GroupId), aws.ToString(group.GroupName)))
+	}
+	fmt.Fprintf(w, "  Security groups:\t%s\n", valueOrDash(strings.Join(groups, ", ")))
+	profile := ""
+	if instance.IamInstanceProfile != nil {
+		profile = aws.ToString(instance.IamInstanceProfile.Arn)
+	}
+	fmt.Fprintf(w, "  IAM profile:\t%s\n", valueOrDash(profile))
+	w.Flush()
+
+	fmt.Fprintf(out, "  Tags:\n")
+	tags := make(map[string]string)
+	for _, tag := range instance.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	printSortedMap(out, tags)
+}
+
+// printVolumesDescription writes the EBS volumes attached to the node's instance, root volume first
+func printVolumesDescription(out io.Writer, volumes []ebsVolume) {
+	fmt.Fprintf(out, "\nEBS Volumes:\n")
+	if len(volumes) == 0 {
+		fmt.Fprintf(out, "  <none>\n")
+		return
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		if volumes[i].Root != volumes[j].Root {
+			return volumes[i].Root
+		}
+		return volumes[i].Device < volumes[j].Device
+	})
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  DEVICE\tVOLUME-ID\tTYPE\tSIZE\tIOPS\tTHROUGHPUT\tROOT")
+	for _, v := range volumes {
+		iops, throughput := "-", "-"
+		if v.IOPS > 0 {
+			iops = fmt.Sprintf("%d", v.IOPS)
+		}
+		if v.Throughput > 0 {
+			throughput = fmt.Sprintf("%dMiB/s", v.Throughput)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%dGi\t%s\t%s\t%s\n", v.Device, v.ID, v.Type, v.SizeGiB, iops, throughput, formatFlag(v.Root))
+	}
+	w.Flush()
+}
+
+// printASGDescription writes the settings of the node's ASG and the instance's state in it
+func printASGDescription(out io.Writer, asg astypes.AutoScalingGroup, instanceID string) {
+	fmt.Fprintf(out, "\nAuto Scaling Group:\n")
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+	capacity := ASGCapacity{
+		Min:     aws.ToInt32(asg.MinSize),
+		Max:     aws.ToInt32(asg.MaxSize),
+		Desired: aws.ToInt32(asg.DesiredCapacity),
+	}
+	fmt.Fprintf(w, "  Name:\t%s\n", aws.ToString(asg.AutoScalingGroupName))
+	fmt.Fprintf(w, "  Capacity (min/max/desired):\t%s\n", capacity)
+	fmt.Fprintf(w, "  Instances:\t%d\n", len(asg.Instances))
+	fmt.Fprintf(w, "  Launch template:\t%s\n", valueOrDash(formatLaunchTemplate(asg)))
+	fmt.Fprintf(w, "  Health check:\t%s (grace %ds)\n", aws.ToString(asg.HealthCheckType), aws.ToInt32(asg.HealthCheckGracePeriod))
+	fmt.Fprintf(w, "  Termination policies:\t%s\n", valueOrDash(strings.Join(asg.TerminationPolicies, ", ")))
+	fmt.Fprintf(w, "  Capacity rebalance:\t%t\n", aws.ToBool(asg.CapacityRebalance))
+	fmt.Fprintf(w, "  Subnets:\t%s\n", valueOrDash(aws.ToString(asg.VPCZoneIdentifier)))
+	for _, member := range asg.Instances {
+		if aws.ToString(member.InstanceId) == instanceID {
+			fmt.Fprintf(w, "  Instance lifecycle state:\t%s\n", member.LifecycleState)
+			fmt.Fprintf(w, "  Instance health:\t%s\n", aws.ToString(member.HealthStatus))
+			fmt.Fprintf(w, "  Protected from scale-in:\t%t\n", aws.ToBool(member.ProtectedFromScaleIn))
+		}
+	}
+	w.Flush()
+}
+
+// formatLaunchTemplate names the ASG's launch template and version, also behind a mixed instances policy
+func formatLaunchTemplate(asg astypes.AutoScalingGroup) string {
+	spec := asg.LaunchTemplate
+	if spec == nil && asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		spec = asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	if spec == nil {
+		return aws.ToString(asg.LaunchConfigurationName)
+	}
+	name := aws.ToString(spec.LaunchTemplateName)
+	if name == "" {
+		name = aws.ToString(spec.LaunchTemplateId)
+	}
+	if version := aws.ToString(spec.Version); version != "" {
+		name += " (" + version + ")"
+	}
+	return name
+}
+
+// printSortedMap writes key=value lines ordered by key, or <none>
+func printSortedMap(out io.Writer, m map[string]string) {
+	if len(m) == 0 {
+		fmt.Fprintf(out, "  <none>\n")
+		return
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(out, "  %s=%s\n", key, m[key])
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// workloadDisruption is the effect of removing a node on one workload
+type workloadDisruption struct {
+	Namespace   string
+	Kind        string
+	Name        string
+	PodsOnNode  int
+	ReadyOnNode int
+	Ready       int32
+	Desired     int32
+}
+
+// Below reports whether the workload would drop below its desired replicas
+func (d workloadDisruption) Below() bool {
+	return d.Ready-int32(d.ReadyOnNode) < d.Desired
+}
+
+// pdbDisruption is a PodDisruptionBudget covering pods on the node
+type pdbDisruption struct {
+	Namespace          string
+	Name               string
+	PodsOnNode         int
+	DisruptionsAllowed int32
+}
+
+// AtLimit reports whether the budget can't absorb evicting all its pods on the node
+func (d pdbDisruption) AtLimit() bool {
+	return int(d.DisruptionsAllowed) < d.PodsOnNode
+}
+
+// disruptionEstimate is the blast radius of removing a node
+type disruptionEstimate struct {
+	Workloads []workloadDisruption
+	PDBs      []pdbDisruption
+	BarePods  []v1.Pod
+}
+
+func runDisruption(args []string) {
+	fs := flag.NewFlagSet("disruption", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s disruption NODE_NAME\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Estimate the blast radius of removing a node: affected workloads, replicas that would\n")
+		fmt.Fprintf(os.Stderr, "drop below desired, PodDisruptionBudgets at their limit and bare pods that would be lost.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	nodeName, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	estimate, err := estimateDisruption(clientset, nodeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	printDisruption(os.Stdout, estimate)
+}
+
+// estimateDisruption looks at the pods a drain of nodeName would evict
+func estimateDisruption(clientset *kubernetes.Clientset, nodeName string) (*disruptionEstimate, error) {
+	ctx := context.TODO()
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+	if err != nil {
+		return nil, fmt.Errorf("listing pods: %w", err)
+	}
+
+	estimate := &disruptionEstimate{}
+	workloads := make(map[string]*workloadDisruption)
+	var evicted []v1.Pod
+	for _, pod := range pods.Items {
+		if !isDrainable(pod) {
+			continue
+		}
+		evicted = append(evicted, pod)
+
+		kind, name, err := podWorkload(clientset, pod)
+		if err != nil {
+			return nil, err
+		}
+		if kind == "" {
+			estimate.BarePods = append(estimate.BarePods, pod)
+			continue
+		}
+
+		key := pod.Namespace + "/" + kind + "/" + name
+		if workloads[key] == nil {
+			workloads[key] = &workloadDisruption{Namespace: pod.Namespace, Kind: kind, Name: name}
+			if err := fillReplicaCounts(clientset, workloads[key]); err != nil {
+				return nil, err
+			}
+		}
+		workloads[key].PodsOnNode++
+		if isPodReady(pod) {
+			workloads[key].ReadyOnNode++
+		}
+	}
+
+	for _, w := range workloads {
+		estimate.Workloads = append(estimate.Workloads, *w)
+	}
+	sort.Slice(estimate.Workloads, func(i, j int) bool {
+		a, b := estimate.Workloads[i], estimate.Workloads[j]
+		return a.Namespace+"/"+a.Kind+"/"+a.Name < b.Namespace+"/"+b.Kind+"/"+b.Name
+	})
+
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing PodDisruptionBudgets: %w", err)
+	}
+	for _, pdb := range pdbs.Items {
+		if covered := pdbCoveredPods(pdb, evicted); covered > 0 {
+			estimate.PDBs = append(estimate.PDBs, pdbDisruption{
+				Namespace:          pdb.Namespace,
+				Name:               pdb.Name,
+				PodsOnNode:         covered,
+				DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+			})
+		}
+	}
+	return estimate, nil
+}
+
+// podWorkload returns the top-level controller of a pod, following ReplicaSets up to their
+// Deployment. Bare pods return an empty kind.
+func podWorkload(clientset *kubernetes.Clientset, pod v1.Pod) (kind, name string, err error) {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return "", "", nil
+	}
+	if owner.Kind == "ReplicaSet" {
+		rs, err := clientset.AppsV1().ReplicaSets(pod.Namespace).Get(context.TODO(), owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", "", fmt.Errorf("getting ReplicaSet %s/%s: %w", pod.Namespace, owner.Name, err)
+		}
+		if deployment := metav1.GetControllerOf(rs); deployment != nil && deployment.Kind == "Deployment" {
+			return deployment.Kind, deployment.Name, nil
+		}
+	}
+	return owner.Kind, owner.Name, nil
+}
+
+// fillReplicaCounts sets ready and desired replicas for the workload kinds that have them
+func fillReplicaCounts(clientset *kubernetes.Clientset, w *workloadDisruption) error {
+	ctx := context.TODO()
+	apps := clientset.AppsV1()
+	switch w.Kind {
+	case "Deployment":
+		obj, err := apps.Deployments(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		w.Ready, w.Desired = obj.Status.ReadyReplicas, replicasOrOne(obj.Spec.Replicas)
+	case "StatefulSet":
+		obj, err := apps.StatefulSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		w.Ready, w.Desired = obj.Status.ReadyReplicas, replicasOrOne(obj.Spec.Replicas)
+	case "ReplicaSet":
+		obj, err := apps.ReplicaSets(w.Namespace).Get(ctx, w.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		w.Ready, w.Desired = obj.Status.ReadyReplicas, replicasOrOne(obj.Spec.Replicas)
+	}
+	return nil
+}
+
+// isDrainable reports whether a drain evicts the pod, i.e. it is not a DaemonSet, static or completed pod
+func isDrainable(pod v1.Pod) bool {
+	return !isDaemonSetPod(pod) && !isMirrorPod(pod) && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed
+}
+
+func replicasOrOne(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+func isPodReady(pod v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// pdbCoveredPods counts the pods selected by the budget
+func pdbCoveredPods(pdb policyv1.PodDisruptionBudget, pods []v1.Pod) int {
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil || selector.Empty() {
+		return 0
+	}
+	count := 0
+	for _, pod := range pods {
+		if pod.Namespace == pdb.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+			count++
+		}
+	}
+	return count
+}
+
+func printDisruption(out io.Writer, estimate *disruptionEstimate) {
+	if len(estimate.Workloads) == 0 && len(estimate.PDBs) == 0 && len(estimate.BarePods) == 0 {
+		fmt.Fprintln(out, "No evictable pods on this node")
+		return
+	}
+
+	below := 0
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tWORKLOAD\tPODS-ON-NODE\tREADY/DESIRED\tREADY-AFTER\tBELOW-DESIRED")
+	for _, d := range estimate.Workloads {
+		readyDesired, after, belowDesired := "-", "-", ""
+		if d.Desired > 0 {
+			readyDesired = fmt.Sprintf("%d/%d", d.Ready, d.Desired)
+			after = fmt.Sprintf("%d", d.Ready-int32(d.ReadyOnNode))
+			if d.Below() {
+				belowDesired = "yes"
+				below++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s/%s\t%d\t%s\t%s\t%s\n", d.Namespace, d.Kind, d.Name, d.PodsOnNode, readyDesired, after, belowDesired)
+	}
+	for _, pod := range estimate.BarePods {
+		fmt.Fprintf(w, "%s\tPod/%s\t1\t-\t-\tlost (no controller)\n", pod.Namespace, pod.Name)
+	}
+	w.Flush()
+
+	atLimit := 0
+	if len(estimate.PDBs) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "NAMESPACE\tPDB\tPODS-ON-NODE\tALLOWED-DISRUPTIONS\tAT-LIMIT")
+		for _, d := range estimate.PDBs {
+			if d.AtLimit() {
+				atLimit++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", d.Namespace, d.Name, d.PodsOnNode, d.DisruptionsAllowed, formatFlag(d.AtLimit()))
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintf(out, "\n%d workload(s) affected, %d would drop below desired replicas, %d PDB(s) at their limit, %d bare pod(s) lost\n",
+		len(estimate.Workloads), below, atLimit, len(estimate.BarePods))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// drainSample is the drainable pod count a cordoned node was first seen with
+type drainSample struct {
+	At   time.Time
+	Pods int
+}
+
+// drainTracker remembers the first sample of each cordoned node, so the eviction rate and
+// time until the node is empty can be estimated across --watch refreshes
+type drainTracker map[string]drainSample
+
+// observe records the node's remaining pods and returns the estimated time until it is empty,
+// false until some pods were evicted since the first sample
+func (t drainTracker) observe(nodeName string, pods int, now time.Time) (time.Duration, bool) {
+	first, seen := t[nodeName]
+	if !seen || pods > first.Pods {
+		// Pods bound despite the cordon (e.g. with spec.nodeName set) restart the estimate
+		t[nodeName] = drainSample{At: now, Pods: pods}
+		return 0, false
+	}
+	evicted := first.Pods - pods
+	elapsed := now.Sub(first.At)
+	if evicted == 0 || elapsed <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(pods) * float64(elapsed) / float64(evicted)), true
+}
+
+// drainablePodsByNode counts the pods a drain would evict from each node
+func drainablePodsByNode(pods []v1.Pod) map[string]int {
+	counts := make(map[string]int)
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" && isDrainable(pod) {
+			counts[pod.Spec.NodeName]++
+		}
+	}
+	return counts
+}
+
+// formatDrain describes a cordoned node's drain progress, e.g. "12 pods, ETA 4m", empty for
+// nodes that are not cordoned
+func formatDrain(n NodeInfo) string {
+	if n.DrainPods == nil {
+		return ""
+	}
+	if *n.DrainPods == 0 {
+		return "empty"
+	}
+	if n.DrainETA == "" {
+		return fmt.Sprintf("%d pods", *n.DrainPods)
+	}
+	return fmt.Sprintf("%d pods, ETA %s", *n.DrainPods, n.DrainETA)
+}
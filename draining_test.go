@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrainTracker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	type observation struct {
+		after   time.Duration
+		pods    int
+		wantETA time.Duration
+		wantOK  bool
+	}
+	tests := []struct {
+		name         string
+		observations []observation
+	}{
+		{
+			name: "first sample has no estimate",
+			observations: []observation{
+				{after: 0, pods: 10},
+			},
+		},
+		{
+			name: "no evictions yet",
+			observations: []observation{
+				{after: 0, pods: 10},
+				{after: time.Minute, pods: 10},
+			},
+		},
+		{
+			name: "rate from the first sample",
+			observations: []observation{
+				{after: 0, pods: 10},
+				// 2 pods per minute, 8 left
+				{after: 2 * time.Minute, pods: 6, wantETA: 3 * time.Minute, wantOK: true},
+				{after: 4 * time.Minute, pods: 2, wantETA: time.Minute, wantOK: true},
+			},
+		},
+		{
+			name: "more pods restart the estimate",
+			observations: []observation{
+				{after: 0, pods: 10},
+				{after: time.Minute, pods: 12},
+				{after: 2 * time.Minute, pods: 6, wantETA: time.Minute, wantOK: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := drainTracker{}
+			for i, o := range tt.observations {
+				eta, ok := tracker.observe("node-a", o.pods, start.Add(o.after))
+				if ok != o.wantOK || eta != o.wantETA {
+					t.Errorf("observation %d: observe() = %v, %v, want %v, %v", i, eta, ok, o.wantETA, o.wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatDrain(t *testing.T) {
+	pods := func(n int) *int { return &n }
+	tests := []struct {
+		node NodeInfo
+		want string
+	}{
+		{NodeInfo{}, ""},
+		{NodeInfo{DrainPods: pods(0)}, "empty"},
+		{NodeInfo{DrainPods: pods(12)}, "12 pods"},
+		{NodeInfo{DrainPods: pods(12), DrainETA: "4m"}, "12 pods, ETA 4m"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDrain(tt.node); got != tt.want {
+			t.Errorf("formatDrain(%+v) = %q, want %q", tt.node, got, tt.want)
+		}
+	}
+}
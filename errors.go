@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+)
+
+// awsError is an AWS failure translated into a readable message and a remediation hint
+type awsError struct {
+	Msg  string
+	Hint string
+	Err  error
+}
+
+func (e *awsError) Error() string {
+	if e.Hint == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s\n  Hint: %s", e.Msg, e.Hint)
+}
+
+func (e *awsError) Unwrap() error {
+	return e.Err
+}
+
+// classifyAWSError maps common AWS SDK failures to an awsError. Errors it does
+// not recognize are returned unchanged.
+func classifyAWSError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *awsError
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	var missingRegion *aws.MissingRegionError
+	if errors.As(err, &missingRegion) {
+		return &awsError{
+			Msg:  "no AWS region configured",
+			Hint: "set AWS_REGION (e.g. export AWS_REGION=us-east-1) or add a region to your profile in ~/.aws/config",
+			Err:  err,
+		}
+	}
+
+	var missingProfile awsconfig.SharedConfigProfileNotExistError
+	if errors.As(err, &missingProfile) {
+		return &awsError{
+			Msg:  fmt.Sprintf("AWS profile '%s' does not exist", missingProfile.Profile),
+			Hint: "check AWS_PROFILE and the profiles listed by 'aws configure list-profiles'",
+			Err:  err,
+		}
+	}
+
+	var ssoToken *ssocreds.InvalidTokenError
+	if errors.As(err, &ssoToken) {
+		return &awsError{
+			Msg:  "the AWS SSO session has expired or is invalid",
+			Hint: "run: " + ssoLoginCommand(),
+			Err:  err,
+		}
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "UnauthorizedOperation", "AccessDenied", "AccessDeniedException":
+			permission := "the required permission"
+			var opErr *smithy.OperationError
+			if errors.As(err, &opErr) {
+				permission = iamAction(opErr.Service(), opErr.Operation())
+			}
+			return &awsError{
+				Msg:  fmt.Sprintf("missing IAM permission %s", permission),
+				Hint: fmt.Sprintf("allow %s in the IAM policy of the identity shown by 'aws sts get-caller-identity'", permission),
+				Err:  err,
+			}
+		case "ExpiredToken", "ExpiredTokenException", "RequestExpired":
+			return &awsError{
+				Msg:  "the AWS session credentials have expired",
+				Hint: "refresh your credentials (e.g. " + ssoLoginCommand() + ", or assume the role again)",
+				Err:  err,
+			}
+		case "InvalidClientTokenId", "UnrecognizedClientException", "AuthFailure", "SignatureDoesNotMatch":
+			return &awsError{
+				Msg:  "the AWS credentials were rejected",
+				Hint: "check AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY or the credentials of the active profile",
+				Err:  err,
+			}
+		case "SubscriptionRequiredException":
+			return &awsError{
+				Msg:  "the AWS Health API is not available for this account",
+				Hint: "the Health API requires a Business, Enterprise On-Ramp or Enterprise support plan",
+				Err:  err,
+			}
+		case "InvalidInstanceID.NotFound", "InvalidInstanceID.Malformed":
+			return &awsError{
+				Msg:  "the node instances were not found in the configured region",
+				Hint: "the cluster probably runs in another region or account; set AWS_REGION/AWS_PROFILE accordingly",
+				Err:  err,
+			}
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return &awsError{
+			Msg:  fmt.Sprintf("could not resolve AWS endpoint %s", dnsErr.Name),
+			Hint: "check that the configured region name is valid and that DNS/proxy settings allow reaching AWS",
+			Err:  err,
+		}
+	}
+
+	msg := err.Error()
+	if strings.Contains(msg, "failed to retrieve credentials") ||
+		strings.Contains(msg, "failed to refresh cached credentials") ||
+		strings.Contains(msg, "no EC2 IMDS role found") {
+		return &awsError{
+			Msg:  "no AWS credentials found",
+			Hint: "run 'aws configure', set AWS_PROFILE, or export AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+			Err:  err,
+		}
+	}
+
+	return err
+}
+
+// iamAction builds the IAM action name (e.g. ec2:DescribeInstances) for an SDK operation
+func iamAction(serviceID, operation string) string {
+	service := strings.ToLower(strings.ReplaceAll(serviceID, " ", ""))
+	return service + ":" + operation
+}
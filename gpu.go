@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// acceleratorResources are the extended resources of accelerator device plugins, checked in order
+var acceleratorResources = []v1.ResourceName{
+	"nvidia.com/gpu",
+	"amd.com/gpu",
+	"aws.amazon.com/neuron",
+	"aws.amazon.com/neuroncore",
+	"habana.ai/gaudi",
+}
+
+// nodeAccelerator returns the accelerator resource the node advertises and its allocatable count
+func nodeAccelerator(node v1.Node) (string, int64) {
+	for _, name := range acceleratorResources {
+		if quantity, exists := node.Status.Allocatable[name]; exists && !quantity.IsZero() {
+			return string(name), quantity.Value()
+		}
+	}
+	return "", 0
+}
+
+func hasAccelerators(nodes []v1.Node) bool {
+	for _, node := range nodes {
+		if name, _ := nodeAccelerator(node); name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatGPUs prints an accelerator count, "-" on nodes without accelerators
+func formatGPUs(count int64, resource string) string {
+	if resource == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%d", count)
+}
+
+func formatGPUFree(n NodeInfo) string {
+	if n.GPUCapacity == 0 {
+		return "-"
+	}
+	return formatPercent(float64(n.GPUCapacity-n.GPURequested) / float64(n.GPUCapacity) * 100)
+}
+
+// formatOptionalPercent prints a percentage that may not have been measured, "-" if not
+func formatOptionalPercent(p *float64) string {
+	if p == nil {
+		return "-"
+	}
+	return formatPercent(*p)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// dcgmExporterPort is dcgm-exporter's default metrics port
+const dcgmExporterPort = 9400
+
+// gpuMetrics is the GPU usage of one node, averaged over its GPUs
+type gpuMetrics struct {
+	Utilization   float64 // percent
+	MemoryUsedPct float64
+}
+
+// dcgmExporters finds the running dcgm-exporter pod of each node, deployed on its own or by the
+// CloudWatch Observability add-on (amazon-cloudwatch namespace)
+func dcgmExporters(pods []v1.Pod) map[string]v1.Pod {
+	exporters := make(map[string]v1.Pod)
+	for _, pod := range pods {
+		if pod.Status.Phase != v1.PodRunning || pod.Spec.NodeName == "" {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if strings.Contains(container.Image, "dcgm-exporter") {
+				exporters[pod.Spec.NodeName] = pod
+				break
+			}
+		}
+	}
+	return exporters
+}
+
+// getGPUMetrics scrapes the node's dcgm-exporter through the API server pod proxy. The
+// CloudWatch add-on serves its metrics over TLS, so https is tried when http fails.
+func getGPUMetrics(clientset *kubernetes.Clientset, exporter v1.Pod) (*gpuMetrics, error) {
+	port := strconv.Itoa(dcgmExporterPort)
+	for _, container := range exporter.Spec.Containers {
+		if strings.Contains(container.Image, "dcgm-exporter") && len(container.Ports) > 0 {
+			port = strconv.Itoa(int(container.Ports[0].ContainerPort))
+		}
+	}
+
+	var data []byte
+	var err error
+	for _, scheme := range []string{"http", "https"} {
+		data, err = clientset.CoreV1().Pods(exporter.Namespace).ProxyGet(scheme, exporter.Name, port, "/metrics", nil).DoRaw(context.TODO())
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseDCGMMetrics(data)
+}
+
+// parseDCGMMetrics reads the per-GPU utilization and framebuffer memory gauges from the
+// Prometheus text format
+func parseDCGMMetrics(data []byte) (*gpuMetrics, error) {
+	var utilization []float64
+	var memUsed, memFree float64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		// The value follows the labels, which may contain spaces (e.g. modelName="NVIDIA A10G")
+		name, rest, hasLabels := strings.Cut(line, "{")
+		if hasLabels {
+			_, rest, _ = strings.Cut(rest, "}")
+		} else {
+			name, rest, _ = strings.Cut(line, " ")
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "DCGM_FI_DEV_GPU_UTIL":
+			utilization = append(utilization, value)
+		case "DCGM_FI_DEV_FB_USED":
+			memUsed += value
+		case "DCGM_FI_DEV_FB_FREE":
+			memFree += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(utilization) == 0 {
+		return nil, fmt.Errorf("no DCGM_FI_DEV_GPU_UTIL metric")
+	}
+
+	metrics := &gpuMetrics{}
+	for _, u := range utilization {
+		metrics.Utilization += u / float64(len(utilization))
+	}
+	if memUsed+memFree > 0 {
+		metrics.MemoryUsedPct = memUsed / (memUsed + memFree) * 100
+	}
+	return metrics, nil
+}
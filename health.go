@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const healthTargetPrefix = "AWSHealth_20160804."
+
+// describeAffectedEntitiesMaxARNs is the API limit of event ARNs per DescribeAffectedEntities call
+const describeAffectedEntitiesMaxARNs = 10
+
+type healthClient struct {
+	awsJSONClient
+}
+
+type healthEvent struct {
+	Arn               string  `json:"arn"`
+	Service           string  `json:"service"`
+	EventTypeCode     string  `json:"eventTypeCode"`
+	EventTypeCategory string  `json:"eventTypeCategory"`
+	Region            string  `json:"region"`
+	AvailabilityZone  string  `json:"availabilityZone"`
+	StartTime         float64 `json:"startTime"`
+	StatusCode        string  `json:"statusCode"`
+}
+
+type healthEntity struct {
+	EventArn    string `json:"eventArn"`
+	EntityValue string `json:"entityValue"`
+	StatusCode  string `json:"statusCode"`
+}
+
+// newHealthClient returns a client for the global Health endpoint of the config's partition
+func newHealthClient(cfg aws.Config) *healthClient {
+	region := "us-east-1"
+	host := "health.us-east-1.amazonaws.com"
+	switch {
+	case strings.HasPrefix(cfg.Region, "cn-"):
+		region = "cn-northwest-1"
+		host = "health.cn-northwest-1.amazonaws.com.cn"
+	case strings.HasPrefix(cfg.Region, "us-gov-"):
+		region = "us-gov-west-1"
+		host = "health.us-gov-west-1.amazonaws.com"
+	}
+	return &healthClient{awsJSONClient{
+		cfg:          cfg,
+		serviceID:    "Health",
+		signingName:  "health",
+		region:       region,
+		endpoint:     "https://" + host + "/",
+		targetPrefix: healthTargetPrefix,
+	}}
+}
+
+// describeOpenEC2Events returns open and upcoming EC2 events in the regions
+func (c *healthClient) describeOpenEC2Events(ctx context.Context, regions []string) ([]healthEvent, error) {
+	var events []healthEvent
+	nextToken := ""
+	for {
+		input := map[string]interface{}{
+			"filter": map[string]interface{}{
+				"services":         []string{"EC2"},
+				"regions":          regions,
+				"eventStatusCodes": []string{"open", "upcoming"},
+			},
+			"maxResults": 100,
+		}
+		if nextToken != "" {
+			input["nextToken"] = nextToken
+		}
+
+		var output struct {
+			Events    []healthEvent `json:"events"`
+			NextToken string        `json:"nextToken"`
+		}
+		if err := c.call(ctx, "DescribeEvents", input, &output); err != nil {
+			return nil, err
+		}
+		events = append(events, output.Events...)
+		if output.NextToken == "" {
+			return events, nil
+		}
+		nextToken = output.NextToken
+	}
+}
+
+func (c *healthClient) describeAffectedEntities(ctx context.Context, eventArns []string) ([]healthEntity, error) {
+	var entities []healthEntity
+	nextToken := ""
+	for {
+		input := map[string]interface{}{
+			"filter": map[string]interface{}{"eventArns": eventArns},
+		}
+		if nextToken != "" {
+			input["nextToken"] = nextToken
+		}
+
+		var output struct {
+			Entities  []healthEntity `json:"entities"`
+			NextToken string         `json:"nextToken"`
+		}
+		if err := c.call(ctx, "DescribeAffectedEntities", input, &output); err != nil {
+			return nil, err
+		}
+		entities = append(entities, output.Entities...)
+		if output.NextToken == "" {
+			return entities, nil
+		}
+		nextToken = output.NextToken
+	}
+}
+
+// nodeHealthEvent is an open AWS Health event affecting a node's instance or its zone
+type nodeHealthEvent struct {
+	Event    string    `json:"event"` // e.g. AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED
+	Category string    `json:"category"`
+	Scope    string    `json:"scope"` // instance or zone
+	Status   string    `json:"status"`
+	Start    time.Time `json:"start"`
+}
+
+func (e nodeHealthEvent) String() string {
+	if e.Scope == "zone" {
+		return e.Event + " (zone)"
+	}
+	return e.Event
+}
+
+// getNodeHealthEvents returns the open EC2 Health events of the nodes' regions that affect their
+// instances or zones, by node name, and how many events are open in those regions
+func getNodeHealthEvents(ctx context.Context, cfg aws.Config, nodes []v1.Node) (map[string][]nodeHealthEvent, int, error) {
+	// Health has a single endpoint per partition, which filters events by region
+	client := newHealthClient(cfg)
+	events, err := client.describeOpenEC2Events(ctx, nodeRegions(nodes, cfg.Region))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	eventsByArn := make(map[string]healthEvent)
+	var arns []string
+	for _, event := range events {
+		eventsByArn[event.Arn] = event
+		arns = append(arns, event.Arn)
+	}
+
+	// Instance-scoped events list the instance IDs as affected entities
+	affectedInstances := make(map[string][]healthEvent)
+	for start := 0; start < len(arns); start += describeAffectedEntitiesMaxARNs {
+		end := start + describeAffectedEntitiesMaxARNs
+		if end > len(arns) {
+			end = len(arns)
+		}
+		entities, err := client.describeAffectedEntities(ctx, arns[start:end])
+		if err != nil {
+			return nil, 0, fmt.Errorf("getting affected entities: %w", err)
+		}
+		for _, entity := range entities {
+			if strings.HasPrefix(entity.EntityValue, "i-") {
+				affectedInstances[entity.EntityValue] = append(affectedInstances[entity.EntityValue], eventsByArn[entity.EventArn])
+			}
+		}
+	}
+
+	return matchHealthEvents(nodes, events, affectedInstances), len(events), nil
+}
+
+// matchHealthEvents assigns the events to the nodes whose instance they list as affected, and the
+// zone-scoped events to the nodes in that zone
+func matchHealthEvents(nodes []v1.Node, events []healthEvent, affectedInstances map[string][]healthEvent) map[string][]nodeHealthEvent {
+	affected := make(map[string][]nodeHealthEvent)
+	for _, node := range nodes {
+		for _, event := range affectedInstances[getInstanceID(node)] {
+			affected[node.Name] = append(affected[node.Name], newNodeHealthEvent(event, "instance"))
+		}
+		// Zone names include the region, so events of other regions don't match
+		zone := getNodeZone(node)
+		for _, event := range events {
+			if event.AvailabilityZone != "" && event.AvailabilityZone == zone {
+				affected[node.Name] = append(affected[node.Name], newNodeHealthEvent(event, "zone"))
+			}
+		}
+	}
+	return affected
+}
+
+func newNodeHealthEvent(event healthEvent, scope string) nodeHealthEvent {
+	return nodeHealthEvent{
+		Event:    event.EventTypeCode,
+		Category: event.EventTypeCategory,
+		Scope:    scope,
+		Status:   event.StatusCode,
+		Start:    time.Unix(int64(event.StartTime), 0).UTC(),
+	}
+}
+
+// formatHealthEvents lists the event codes for the HEALTH column
+func formatHealthEvents(events []nodeHealthEvent) string {
+	if len(events) == 0 {
+		return "-"
+	}
+	codes := make([]string, len(events))
+	for i, event := range events {
+		codes[i] = event.String()
+	}
+	return strings.Join(codes, ",")
+}
+
+func runHealth(args []string) {
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s health\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show open AWS Health events (EC2 issues, degraded hardware, scheduled maintenance)\n")
+		fmt.Fprintf(os.Stderr, "that affect node instances or their Availability Zones, in every region of the\n")
+		fmt.Fprintf(os.Stderr, "cluster's nodes. Requires a Business or Enterprise support plan.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	affected, openEvents, err := getNodeHealthEvents(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting AWS Health events: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(affected) == 0 {
+		fmt.Printf("No open AWS Health events affect the cluster's nodes (%d open EC2 events in %s)\n",
+			openEvents, strings.Join(nodeRegions(nodes.Items, awsConfig.Region), ", "))
+		return
+	}
+
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NODE\tINSTANCE-ID\tZONE\tSCOPE\tEVENT\tCATEGORY\tSTATUS\tSTART")
+	for _, node := range nodes.Items {
+		for _, event := range affected[node.Name] {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", node.Name, getInstanceID(node), getNodeZone(node), event.Scope,
+				event.Event, event.Category, event.Status, event.Start.Format(time.RFC3339))
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchHealthEvents(t *testing.T) {
+	node := func(name, providerID string) v1.Node {
+		return v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: v1.NodeSpec{ProviderID: providerID}}
+	}
+	nodes := []v1.Node{
+		node("west-a", "aws:///us-west-2a/i-0aaa"),
+		node("west-b", "aws:///us-west-2b/i-0bbb"),
+		node("east-a", "aws:///us-east-1a/i-0ccc"),
+	}
+	retirement := healthEvent{Arn: "arn:retirement", EventTypeCode: "AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED", EventTypeCategory: "scheduledChange", Region: "us-east-1", StatusCode: "upcoming", StartTime: 1700000000}
+	zoneIssue := healthEvent{Arn: "arn:zone", EventTypeCode: "AWS_EC2_OPERATIONAL_ISSUE", EventTypeCategory: "issue", Region: "us-west-2", AvailabilityZone: "us-west-2b", StatusCode: "open", StartTime: 1700000000}
+	events := []healthEvent{retirement, zoneIssue}
+	affectedInstances := map[string][]healthEvent{"i-0ccc": {retirement}}
+
+	got := matchHealthEvents(nodes, events, affectedInstances)
+	want := map[string][]string{
+		"west-b": {"AWS_EC2_OPERATIONAL_ISSUE (zone)"},
+		"east-a": {"AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED"},
+	}
+	gotCodes := make(map[string][]string)
+	for name, nodeEvents := range got {
+		for _, event := range nodeEvents {
+			gotCodes[name] = append(gotCodes[name], event.String())
+		}
+	}
+	if !reflect.DeepEqual(gotCodes, want) {
+		t.Errorf("matchHealthEvents() = %v, want %v", gotCodes, want)
+	}
+	if event := got["east-a"][0]; event.Scope != "instance" || event.Status != "upcoming" || event.Start.Unix() != 1700000000 {
+		t.Errorf("east-a event = %+v, want an upcoming instance event starting at 1700000000", event)
+	}
+
+	if got := formatHealthEvents(append(got["east-a"], got["west-b"]...)); got != "AWS_EC2_INSTANCE_RETIREMENT_SCHEDULED,AWS_EC2_OPERATIONAL_ISSUE (zone)" {
+		t.Errorf("formatHealthEvents() = %q", got)
+	}
+	if got := formatHealthEvents(nil); got != "-" {
+		t.Errorf("formatHealthEvents(nil) = %q, want -", got)
+	}
+}
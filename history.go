@@ -0,0 +1,312 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	_ "github.com/ncruces/go-sqlite3/driver"
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+// History is a SQLite database with one row per node per run, indexed by node name so trend
+// only reads the samples of the node it charts. The driver is a pure-Go (WebAssembly) build of
+// SQLite, so the plugin still cross-compiles without cgo.
+
+const sparklineWidth = 60
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS node_samples (
+	timestamp              INTEGER NOT NULL,
+	name                   TEXT    NOT NULL,
+	instance_type          TEXT    NOT NULL DEFAULT '',
+	asg                    TEXT    NOT NULL DEFAULT '',
+	pod_count              INTEGER NOT NULL,
+	cpu_requested_millis   INTEGER NOT NULL,
+	cpu_capacity_millis    INTEGER NOT NULL,
+	memory_requested_bytes INTEGER NOT NULL,
+	memory_capacity_bytes  INTEGER NOT NULL,
+	hourly_cost            REAL    NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS node_samples_name_timestamp ON node_samples (name, timestamp);
+`
+
+// historySample is one node as recorded by one run
+type historySample struct {
+	Timestamp    time.Time
+	Name         string
+	InstanceType string
+	ASG          string
+	PodCount     int
+	CPURequested int64 // millicores
+	CPUCapacity  int64 // millicores
+	MemRequested int64 // bytes
+	MemCapacity  int64 // bytes
+	// HourlyCost is the node's price in USD per hour, 0 when it could not be priced
+	HourlyCost float64
+}
+
+func defaultHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "kubectl-aws-nodes", "history.db")
+}
+
+// openHistory opens the history database, creating it and its schema when create is set
+func openHistory(path string, create bool) (*sql.DB, error) {
+	if path == "" {
+		return nil, errors.New("no history path, the user config directory is unknown")
+	}
+	if create {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// appendHistory records the nodes of one run
+func appendHistory(path string, nodes []NodeInfo) error {
+	db, err := openHistory(path, true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO node_samples (timestamp, name, instance_type, asg, pod_count,
+		cpu_requested_millis, cpu_capacity_millis, memory_requested_bytes, memory_capacity_bytes, hourly_cost)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().Unix()
+	for _, n := range nodes {
+		var cpuRequested, cpuCapacity, memRequested, memCapacity int64
+		if n.CPURequested != nil {
+			cpuRequested = n.CPURequested.MilliValue()
+		}
+		if n.CPUCapacity != nil {
+			cpuCapacity = n.CPUCapacity.MilliValue()
+		}
+		if n.MemRequested != nil {
+			memRequested = n.MemRequested.Value()
+		}
+		if n.MemCapacity != nil {
+			memCapacity = n.MemCapacity.Value()
+		}
+		if _, err := stmt.Exec(now, n.Name, n.InstanceType, n.ASG, n.PodCount,
+			cpuRequested, cpuCapacity, memRequested, memCapacity, n.HourlyCost); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// readHistory returns the node's samples newer than since, oldest first
+func readHistory(path, nodeName string, since time.Time) ([]historySample, error) {
+	db, err := openHistory(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT timestamp, name, instance_type, asg, pod_count, cpu_requested_millis,
+		cpu_capacity_millis, memory_requested_bytes, memory_capacity_bytes, hourly_cost
+		FROM node_samples WHERE name = ? AND timestamp > ? ORDER BY timestamp`, nodeName, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []historySample
+	for rows.Next() {
+		var sample historySample
+		var timestamp int64
+		if err := rows.Scan(&timestamp, &sample.Name, &sample.InstanceType, &sample.ASG, &sample.PodCount,
+			&sample.CPURequested, &sample.CPUCapacity, &sample.MemRequested, &sample.MemCapacity,
+			&sample.HourlyCost); err != nil {
+			return nil, err
+		}
+		sample.Timestamp = time.Unix(timestamp, 0)
+		samples = append(samples, sample)
+	}
+	return samples, rows.Err()
+}
+
+// peakRequests is the most CPU and memory a node had requested over the recorded runs
+type peakRequests struct {
+	CPURequested int64 // millicores
+	MemRequested int64 // bytes
+}
+
+// readPeakRequests returns the highest requests of every node recorded since then, so nodes that
+// are only quiet between batch runs aren't taken for oversized
+func readPeakRequests(path string, since time.Time) (map[string]peakRequests, error) {
+	db, err := openHistory(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT name, MAX(cpu_requested_millis), MAX(memory_requested_bytes)
+		FROM node_samples WHERE timestamp > ? GROUP BY name`, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	peaks := make(map[string]peakRequests)
+	for rows.Next() {
+		var name string
+		var peak peakRequests
+		if err := rows.Scan(&name, &peak.CPURequested, &peak.MemRequested); err != nil {
+			return nil, err
+		}
+		peaks[name] = peak
+	}
+	return peaks, rows.Err()
+}
+
+func runTrend(args []string) {
+	fs := flag.NewFlagSet("trend", flag.ExitOnError)
+	nodeName := fs.String("node", "", "Node to chart (required)")
+	days := fs.Int("days", 7, "Number of days of history to chart")
+	historyPath := fs.String("file", defaultHistoryPath(), "History database written by --history")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s trend --node NODE_NAME [--days N]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Chart a node's pod count, requests and cost over time from the runs recorded with --history.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if *nodeName == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	name, err := parseNodeArg(*nodeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	*nodeName = name
+
+	samples, err := readHistory(*historyPath, *nodeName, time.Now().AddDate(0, 0, -*days))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Error: no history at %s, record runs with --history first\n", *historyPath)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	var times []time.Time
+	var pods, cpu, cpuPct, mem, memPct, cost []float64
+	priced := false
+	for _, n := range samples {
+		times = append(times, n.Timestamp)
+		pods = append(pods, float64(n.PodCount))
+		cpu = append(cpu, float64(n.CPURequested)/1000)
+		cpuPct = append(cpuPct, ratioPercent(n.CPURequested, n.CPUCapacity))
+		mem = append(mem, float64(n.MemRequested))
+		memPct = append(memPct, ratioPercent(n.MemRequested, n.MemCapacity))
+		cost = append(cost, n.HourlyCost)
+		priced = priced || n.HourlyCost > 0
+	}
+
+	if len(times) == 0 {
+		fmt.Printf("No history for node '%s' in the last %d days\n", *nodeName, *days)
+		return
+	}
+
+	fmt.Printf("Node %s: %d samples from %s to %s\n\n", *nodeName, len(times),
+		times[0].Local().Format("2006-01-02 15:04"), times[len(times)-1].Local().Format("2006-01-02 15:04"))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tMIN\tMAX\tLAST\tTREND")
+	printTrendRow(w, "PODS", pods, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+	printTrendRow(w, "CPU-REQ", cpu, func(v float64) string { return fmt.Sprintf("%.2f", v) })
+	printTrendRow(w, "CPU-REQ%", cpuPct, formatPercent)
+	printTrendRow(w, "MEM-REQ", mem, func(v float64) string { return formatBytes(int64(v)) })
+	printTrendRow(w, "MEM-REQ%", memPct, formatPercent)
+	// Runs that could not be priced have no cost
+	if priced {
+		printTrendRow(w, "HOURLY", cost, formatPrice)
+	}
+	w.Flush()
+}
+
+func ratioPercent(part, whole int64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
+}
+
+func printTrendRow(w *tabwriter.Writer, metric string, values []float64, format func(float64) string) {
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", metric, format(low), format(high), format(values[len(values)-1]),
+		sparkline(values, sparklineWidth))
+}
+
+// sparkline renders values as block characters, averaging them down to at most width points
+func sparkline(values []float64, width int) string {
+	if len(values) > width {
+		reduced := make([]float64, width)
+		for i := range reduced {
+			start, end := i*len(values)/width, (i+1)*len(values)/width
+			sum := 0.0
+			for _, v := range values[start:end] {
+				sum += v
+			}
+			reduced[i] = sum / float64(end-start)
+		}
+		values = reduced
+	}
+
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low = math.Min(low, v)
+		high = math.Max(high, v)
+	}
+	line := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if high > low {
+			level = int((v - low) / (high - low) * float64(len(sparkBlocks)-1))
+		}
+		line[i] = sparkBlocks[level]
+	}
+	return string(line)
+}
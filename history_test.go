@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHistoryRecordsCost(t *testing.T) {
+	node := func(name, instanceID string) v1.Node {
+		return v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"node.kubernetes.io/instance-type": "m5.large"}},
+			Spec:       v1.NodeSpec{ProviderID: "aws:///us-west-2a/" + instanceID},
+		}
+	}
+	instanceMap := map[string]types.Instance{
+		"i-priced": {
+			InstanceId:   aws.String("i-priced"),
+			InstanceType: types.InstanceTypeM5Large,
+			Placement:    &types.Placement{AvailabilityZone: aws.String("us-west-2a")},
+		},
+	}
+	prices := &priceBook{Region: "us-west-2", OnDemand: map[string]float64{"m5.large/us-west-2": 0.096}}
+	resources := &NodeInfo{
+		CPUCapacity: quantity("2"), CPURequested: quantity("500m"),
+		MemCapacity: quantity("8Gi"), MemRequested: quantity("1Gi"),
+		PodCount: 7,
+	}
+
+	var nodes []NodeInfo
+	for _, n := range []v1.Node{node("priced", "i-priced"), node("unpriced", "i-unknown")} {
+		nodeInfo := newNodeInfo(n, resources, instanceMap, nil, "us-west-2", nil)
+		if n.Name == "priced" {
+			prices.applyPrices(&nodeInfo)
+		}
+		nodes = append(nodes, nodeInfo)
+	}
+
+	path := filepath.Join(t.TempDir(), "history.db")
+	if err := appendHistory(path, nodes); err != nil {
+		t.Fatalf("appendHistory: %v", err)
+	}
+
+	tests := []struct {
+		node     string
+		wantCost float64
+	}{
+		{"priced", 0.096},
+		{"unpriced", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.node, func(t *testing.T) {
+			samples, err := readHistory(path, tt.node, time.Now().Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("readHistory: %v", err)
+			}
+			if len(samples) != 1 {
+				t.Fatalf("got %d samples, want 1", len(samples))
+			}
+			sample := samples[0]
+			if sample.HourlyCost != tt.wantCost {
+				t.Errorf("HourlyCost = %v, want %v", sample.HourlyCost, tt.wantCost)
+			}
+			if sample.PodCount != 7 || sample.CPURequested != 500 || sample.MemCapacity != 8<<30 {
+				t.Errorf("sample = %+v, want 7 pods, 500m CPU requested and 8Gi memory", sample)
+			}
+		})
+	}
+
+	if samples, err := readHistory(path, "priced", time.Now().Add(time.Hour)); err != nil || len(samples) != 0 {
+		t.Errorf("readHistory after the last run = %v, %v, want no samples", samples, err)
+	}
+	if _, err := readHistory(filepath.Join(t.TempDir(), "missing.db"), "priced", time.Time{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("readHistory of a missing database = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestReadPeakRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	for _, requested := range []string{"1", "6", "2"} {
+		nodes := []NodeInfo{
+			{Name: "batch", CPUCapacity: quantity("16"), CPURequested: quantity(requested), MemCapacity: quantity("64Gi"), MemRequested: quantity(requested + "Gi")},
+			{Name: "steady", CPUCapacity: quantity("16"), CPURequested: quantity("500m"), MemCapacity: quantity("64Gi"), MemRequested: quantity("1Gi")},
+		}
+		if err := appendHistory(path, nodes); err != nil {
+			t.Fatalf("appendHistory: %v", err)
+		}
+	}
+
+	peaks, err := readPeakRequests(path, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("readPeakRequests: %v", err)
+	}
+	want := map[string]peakRequests{
+		"batch":  {CPURequested: 6000, MemRequested: 6 << 30},
+		"steady": {CPURequested: 500, MemRequested: 1 << 30},
+	}
+	for name, peak := range want {
+		if peaks[name] != peak {
+			t.Errorf("peak of %s = %+v, want %+v", name, peaks[name], peak)
+		}
+	}
+
+	if peaks, err := readPeakRequests(path, time.Now().Add(time.Hour)); err != nil || len(peaks) != 0 {
+		t.Errorf("readPeakRequests after the last run = %v, %v, want no peaks", peaks, err)
+	}
+}
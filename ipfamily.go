@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net"
+	"regexp"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// instanceIDPattern matches a bare EC2 instance ID, as opposed to a resource-based hostname
+// like i-0123456789abcdef0.us-west-2.compute.internal, which IPv6 EKS clusters use as node names
+var instanceIDPattern = regexp.MustCompile(`^i-[0-9a-f]{8,17}$`)
+
+// nodeIPv6 returns the node's IPv6 addresses, internal ones first
+func nodeIPv6(node v1.Node) string {
+	var addresses []string
+	for _, addressType := range []v1.NodeAddressType{v1.NodeInternalIP, v1.NodeExternalIP} {
+		for _, address := range node.Status.Addresses {
+			if address.Type == addressType && isIPv6(address.Address) {
+				addresses = append(addresses, address.Address)
+			}
+		}
+	}
+	return strings.Join(addresses, ",")
+}
+
+func hasIPv6Nodes(nodes []v1.Node) bool {
+	for _, node := range nodes {
+		if nodeIPv6(node) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// podIPFamilies returns the IP family of pod networking per node: from the node's pod CIDRs when
+// it has them, otherwise from the IPs of its pods, since the VPC CNI assigns pod IPs from the
+// VPC and leaves podCIDRs unset
+func podIPFamilies(nodes []v1.Node, pods []v1.Pod) map[string]string {
+	ips := make(map[string][]string)
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork || pod.Spec.NodeName == "" {
+			continue
+		}
+		for _, podIP := range pod.Status.PodIPs {
+			ips[pod.Spec.NodeName] = append(ips[pod.Spec.NodeName], podIP.IP)
+		}
+	}
+
+	families := make(map[string]string)
+	for _, node := range nodes {
+		if len(node.Spec.PodCIDRs) > 0 {
+			families[node.Name] = ipFamily(node.Spec.PodCIDRs)
+		} else if family := ipFamily(ips[node.Name]); family != "" {
+			families[node.Name] = family
+		}
+	}
+	return families
+}
+
+// ipFamily returns IPv4, IPv6 or dual-stack for a set of addresses or CIDRs, empty for none
+func ipFamily(addresses []string) string {
+	var v4, v6 bool
+	for _, address := range addresses {
+		if isIPv6(address) {
+			v6 = true
+		} else if address != "" {
+			v4 = true
+		}
+	}
+	switch {
+	case v4 && v6:
+		return "dual-stack"
+	case v6:
+		return "IPv6"
+	case v4:
+		return "IPv4"
+	}
+	return ""
+}
+
+// isIPv6 reports whether an address or CIDR is IPv6
+func isIPv6(address string) bool {
+	address, _, _ = strings.Cut(address, "/")
+	ip := net.ParseIP(address)
+	return ip != nil && ip.To4() == nil
+}
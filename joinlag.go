@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func runJoinLag(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("join-lag", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s join-lag\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Report per node group how long instances take from EC2 launch to node registration and Ready.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+
+	joinLags := make(map[string][]time.Duration)
+	readyLags := make(map[string][]time.Duration)
+	for _, node := range nodes.Items {
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists || instance.LaunchTime == nil {
+			continue
+		}
+		group := getGroupFromTags(instance.Tags, groupTagKeys)
+		if group == "" {
+			group = "<none>"
+		}
+
+		launched := *instance.LaunchTime
+		joinLags[group] = append(joinLags[group], node.CreationTimestamp.Sub(launched))
+		if readyAt, ok := nodeReadySince(node); ok {
+			readyLags[group] = append(readyLags[group], readyAt.Sub(launched))
+		}
+	}
+
+	if len(joinLags) == 0 {
+		fmt.Println("No nodes with matching EC2 instances found")
+		return
+	}
+
+	var groups []string
+	for group := range joinLags {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tNODES\tMEDIAN-JOIN\tMAX-JOIN\tMEDIAN-READY\tMAX-READY")
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", group, len(joinLags[group]),
+			formatLag(medianDuration(joinLags[group])), formatLag(maxDuration(joinLags[group])),
+			formatLag(medianDuration(readyLags[group])), formatLag(maxDuration(readyLags[group])))
+	}
+	w.Flush()
+}
+
+// nodeReadySince returns when the node last became Ready. Nodes that flapped
+// report the latest transition, so their lag is an upper bound.
+func nodeReadySince(node v1.Node) (time.Time, bool) {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func medianDuration(values []time.Duration) (time.Duration, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2, true
+	}
+	return sorted[mid], true
+}
+
+func maxDuration(values []time.Duration) (time.Duration, bool) {
+	if len(values) == 0 {
+		return 0, false
+	}
+	longest := values[0]
+	for _, v := range values[1:] {
+		if v > longest {
+			longest = v
+		}
+	}
+	return longest, true
+}
+
+func formatLag(d time.Duration, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// k9sPlugin is one entry of k9s' plugins.yaml
+type k9sPlugin struct {
+	ShortCut    string   `json:"shortCut"`
+	Description string   `json:"description"`
+	Scopes      []string `json:"scopes"`
+	Command     string   `json:"command"`
+	Background  bool     `json:"background"`
+	Args        []string `json:"args"`
+}
+
+// k9sPlugins wires the node commands to hotkeys in k9s' node view. $NAME is the selected node.
+func k9sPlugins() map[string]k9sPlugin {
+	nodeScope := []string{"nodes"}
+	return map[string]k9sPlugin{
+		"aws-nodes-open": {
+			ShortCut:    "Shift-O",
+			Description: "EC2 console",
+			Scopes:      nodeScope,
+			Command:     "kubectl",
+			Background:  true,
+			Args:        []string{"aws-nodes", "--open", "$NAME"},
+		},
+		"aws-nodes-open-asg": {
+			ShortCut:    "Shift-G",
+			Description: "ASG console",
+			Scopes:      nodeScope,
+			Command:     "kubectl",
+			Background:  true,
+			Args:        []string{"aws-nodes", "--open-asg", "$NAME"},
+		},
+		"aws-nodes-ssm": {
+			ShortCut:    "Shift-S",
+			Description: "SSM shell",
+			Scopes:      nodeScope,
+			Command:     "kubectl",
+			Background:  false,
+			Args:        []string{"aws-nodes", "--ssm", "$NAME"},
+		},
+		"aws-nodes-describe": {
+			ShortCut:    "Shift-D",
+			Description: "AWS describe",
+			Scopes:      nodeScope,
+			Command:     "sh",
+			Background:  false,
+			Args:        []string{"-c", "kubectl aws-nodes describe $NAME | less -K"},
+		},
+		"aws-nodes-drain-check": {
+			ShortCut:    "Shift-K",
+			Description: "Drain check",
+			Scopes:      nodeScope,
+			Command:     "sh",
+			Background:  false,
+			Args:        []string{"-c", "kubectl aws-nodes drain-check $NAME | less -K"},
+		},
+	}
+}
+
+// printK9sPlugins writes a plugins.yaml snippet for k9s
+func printK9sPlugins() error {
+	data, err := yaml.Marshal(map[string]interface{}{"plugins": k9sPlugins()})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
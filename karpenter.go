@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	karpenterNodePoolLabel     = "karpenter.sh/nodepool"
+	karpenterProvisionerLabel  = "karpenter.sh/provisioner-name" // before the v1beta1 API
+	karpenterCapacityTypeLabel = "karpenter.sh/capacity-type"
+	karpenterDoNotDisrupt      = "karpenter.sh/do-not-disrupt"
+	karpenterDoNotEvict        = "karpenter.sh/do-not-evict" // pod annotation before the v1beta1 API
+)
+
+// consolidationCandidate is a Karpenter node and whether removing it is expected to be possible
+type consolidationCandidate struct {
+	Node     NodeInfo
+	NodePool string
+	Pods     int
+	Blocker  string
+}
+
+// nodeClaimAPIVersions are the karpenter.sh versions serving NodeClaims, newest first
+var nodeClaimAPIVersions = []string{"v1", "v1beta1"}
+
+// nodeClaim is the part of a Karpenter NodeClaim used here
+type nodeClaim struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Status struct {
+		NodeName string `json:"nodeName"`
+	} `json:"status"`
+}
+
+type nodeClaimList struct {
+	Items []nodeClaim `json:"items"`
+}
+
+// karpenterNodePool returns the NodePool (or Provisioner) of a Karpenter-managed node, "" otherwise
+func karpenterNodePool(node v1.Node) string {
+	if pool, exists := node.Labels[karpenterNodePoolLabel]; exists {
+		return pool
+	}
+	return node.Labels[karpenterProvisionerLabel]
+}
+
+// hasKarpenterNodes reports whether any node was provisioned by Karpenter
+func hasKarpenterNodes(nodes []v1.Node) bool {
+	for _, node := range nodes {
+		if karpenterNodePool(node) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// getNodeClaims lists Karpenter NodeClaims by the name of their node. Clusters without the
+// NodeClaim CRD (or with Provisioner-era Karpenter) get an empty map.
+func getNodeClaims(clientset *kubernetes.Clientset) (map[string]nodeClaim, error) {
+	claims := make(map[string]nodeClaim)
+	for _, version := range nodeClaimAPIVersions {
+		data, err := clientset.Discovery().RESTClient().Get().
+			AbsPath("/apis/karpenter.sh/" + version + "/nodeclaims").
+			DoRaw(context.TODO())
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return claims, err
+		}
+
+		var list nodeClaimList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return claims, err
+		}
+		for _, claim := range list.Items {
+			if claim.Status.NodeName != "" {
+				claims[claim.Status.NodeName] = claim
+			}
+		}
+		break
+	}
+	return claims, nil
+}
+
+// applyKarpenter sets the NodePool, capacity type and NodeClaim of a Karpenter node, preferring
+// the NodeClaim's labels over the node's
+func applyKarpenter(nodeInfo *NodeInfo, node v1.Node, claims map[string]nodeClaim) {
+	nodeInfo.NodePool = karpenterNodePool(node)
+	nodeInfo.CapacityType = node.Labels[karpenterCapacityTypeLabel]
+	if claim, exists := claims[node.Name]; exists {
+		nodeInfo.NodeClaim = claim.Metadata.Name
+		if pool := claim.Metadata.Labels[karpenterNodePoolLabel]; pool != "" {
+			nodeInfo.NodePool = pool
+		}
+		if capacityType := claim.Metadata.Labels[karpenterCapacityTypeLabel]; capacityType != "" {
+			nodeInfo.CapacityType = capacityType
+		}
+	}
+}
+
+func runConsolidation(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("consolidation", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s consolidation\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Preview Karpenter consolidation: Karpenter-managed nodes whose pods all fit on the other nodes,\n")
+		fmt.Fprintf(os.Stderr, "and the cost saved by deleting them. Nodes are evaluated one at a time, so the savings of several\n")
+		fmt.Fprintf(os.Stderr, "candidates don't necessarily add up; replacing a node with a cheaper one is not modeled.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.TODO()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	var karpenterNodes []v1.Node
+	for _, node := range nodes.Items {
+		if karpenterNodePool(node) != "" {
+			karpenterNodes = append(karpenterNodes, node)
+		}
+	}
+	if len(karpenterNodes) == 0 {
+		fmt.Println("No Karpenter-managed nodes found")
+		return
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing PodDisruptionBudgets: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	instanceMap, err := getNodeInstances(ctx, awsConfig, karpenterNodes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+	prices, err := getInstancePrices(awsConfig, instanceMap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting prices: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodeResources := calculateNodeResources(nodes.Items, pods.Items)
+	var candidates []consolidationCandidate
+	for _, node := range karpenterNodes {
+		nodeInfo := newNodeInfo(node, nodeResources[node.Name], instanceMap, nil, awsConfig.Region, groupTagKeys)
+		prices.applyPrices(&nodeInfo)
+
+		var nodePods []v1.Pod
+		for _, pod := range pods.Items {
+			if pod.Spec.NodeName == node.Name && isDrainable(pod) {
+				nodePods = append(nodePods, pod)
+			}
+		}
+		candidates = append(candidates, consolidationCandidate{
+			Node:     nodeInfo,
+			NodePool: karpenterNodePool(node),
+			Pods:     len(nodePods),
+			Blocker:  consolidationBlocker(node, nodePods, nodes.Items, pods.Items, pdbs.Items),
+		})
+	}
+
+	// Candidates with the largest savings first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.Blocker == "") != (b.Blocker == "") {
+			return a.Blocker == ""
+		}
+		return a.Node.HourlyCost > b.Node.HourlyCost
+	})
+
+	var savings float64
+	count := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tNODEPOOL\tINSTANCE-TYPE\tLIFECYCLE\tPODS\tCANDIDATE\tREASON\tHOURLY\tMONTHLY")
+	for _, c := range candidates {
+		reason := c.Blocker
+		if reason == "" {
+			reason = "pods fit on other nodes"
+			if c.Pods == 0 {
+				reason = "empty"
+			}
+			savings += c.Node.HourlyCost
+			count++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", c.Node.Name, c.NodePool, c.Node.InstanceType, c.Node.Lifecycle,
+			c.Pods, formatFlag(c.Blocker == ""), reason, formatPrice(c.Node.HourlyCost), formatMonthly(c.Node.HourlyCost))
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d of %d Karpenter node(s) are consolidation candidates, up to %s/hour (%s/month) if all were removed\n",
+		count, len(candidates), formatPrice(savings), formatMonthly(savings))
+}
+
+// consolidationBlocker explains why Karpenter would not delete the node, or returns "" if its pods
+// can all be packed onto the other Ready, schedulable nodes
+func consolidationBlocker(node v1.Node, nodePods []v1.Pod, nodes []v1.Node, pods []v1.Pod, pdbs []policyv1.PodDisruptionBudget) string {
+	if node.Annotations[karpenterDoNotDisrupt] == "true" {
+		return "node has " + karpenterDoNotDisrupt
+	}
+	if node.Spec.Unschedulable {
+		return "cordoned"
+	}
+
+	for _, pod := range nodePods {
+		if pod.Annotations[karpenterDoNotDisrupt] == "true" || pod.Annotations[karpenterDoNotEvict] == "true" {
+			return fmt.Sprintf("pod %s/%s has do-not-disrupt", pod.Namespace, pod.Name)
+		}
+		if metav1.GetControllerOf(&pod) == nil {
+			return fmt.Sprintf("pod %s/%s has no controller", pod.Namespace, pod.Name)
+		}
+	}
+	for _, pdb := range pdbs {
+		if covered := pdbCoveredPods(pdb, nodePods); covered > int(pdb.Status.DisruptionsAllowed) {
+			return fmt.Sprintf("PDB %s/%s allows %d disruption(s)", pdb.Namespace, pdb.Name, pdb.Status.DisruptionsAllowed)
+		}
+	}
+
+	var others []v1.Node
+	for _, other := range nodes {
+		if other.Name != node.Name && !other.Spec.Unschedulable && getNodeStatus(other) == "Ready" {
+			others = append(others, other)
+		}
+	}
+
+	// Pack the pods one after another, largest first, so they compete for the same free capacity
+	free := calculateNodeResources(others, pods)
+	sorted := append([]v1.Pod(nil), nodePods...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := podRequests(sorted[i]), podRequests(sorted[j])
+		return a.Cpu().Cmp(*b.Cpu()) > 0
+	})
+	for _, pod := range sorted {
+		requests := podRequests(pod)
+		placed := false
+		for _, other := range others {
+			if !matchesNodeSelector(pod, other) || !matchesNodeAffinity(pod, other) || !toleratesNodeTaints(pod, other) {
+				continue
+			}
+			target := free[other.Name]
+			if !fitsFreeResources(requests, target) {
+				continue
+			}
+			target.CPURequested.Add(*requests.Cpu())
+			target.MemRequested.Add(*requests.Memory())
+			placed = true
+			break
+		}
+		if !placed {
+			return fmt.Sprintf("pod %s/%s doesn't fit elsewhere", pod.Namespace, pod.Name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultDriftKeys are the kubelet settings compared by kubelet-drift
+var defaultDriftKeys = []string{
+	"maxPods",
+	"podsPerCore",
+	"evictionHard",
+	"evictionSoft",
+	"cpuManagerPolicy",
+	"memoryManagerPolicy",
+	"topologyManagerPolicy",
+	"kubeReserved",
+	"systemReserved",
+	"imageGCHighThresholdPercent",
+	"imageGCLowThresholdPercent",
+	"containerLogMaxSize",
+	"serializeImagePulls",
+}
+
+// unsetValue is shown for settings missing from a node's configz
+const unsetValue = "<unset>"
+
+func runKubeletDrift(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("kubelet-drift", flag.ExitOnError)
+	keys := fs.String("keys", strings.Join(defaultDriftKeys, ","), "Comma-separated kubelet configuration fields to compare")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s kubelet-drift [--keys maxPods,cpuManagerPolicy,...]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compare the running kubelet configuration (configz) of the nodes in each ASG/node group and\n")
+		fmt.Fprintf(os.Stderr, "report settings that differ within a group, e.g. after manual fixes on single nodes.\n")
+		fmt.Fprintf(os.Stderr, "Exits with status 1 if drift is found. Requires nodes/proxy access.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	var driftKeys []string
+	for _, key := range strings.Split(*keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			driftKeys = append(driftKeys, key)
+		}
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+
+	// values[group][key][value] lists the nodes with that value
+	values := make(map[string]map[string]map[string][]string)
+	for _, node := range nodes.Items {
+		group := "<none>"
+		if instance, exists := instanceMap[getInstanceID(node)]; exists {
+			if g := getGroupFromTags(instance.Tags, groupTagKeys); g != "" {
+				group = g
+			}
+		}
+
+		data, err := getKubeletConfigz(clientset, node.Name)
+		if err != nil {
+			warnf("could not read kubelet config of node '%s': %v\n", node.Name, err)
+			continue
+		}
+		var configz struct {
+			KubeletConfig map[string]json.RawMessage `json:"kubeletconfig"`
+		}
+		if err := json.Unmarshal(data, &configz); err != nil {
+			warnf("could not parse kubelet config of node '%s': %v\n", node.Name, err)
+			continue
+		}
+
+		if values[group] == nil {
+			values[group] = make(map[string]map[string][]string)
+		}
+		for _, key := range driftKeys {
+			value := unsetValue
+			if raw, exists := configz.KubeletConfig[key]; exists {
+				value = compactJSON(raw)
+			}
+			if values[group][key] == nil {
+				values[group][key] = make(map[string][]string)
+			}
+			values[group][key][value] = append(values[group][key][value], node.Name)
+		}
+	}
+
+	var groups []string
+	for group := range values {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	drift := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tSETTING\tVALUE\tNODES")
+	for _, group := range groups {
+		for _, key := range driftKeys {
+			byValue := values[group][key]
+			if len(byValue) < 2 {
+				continue
+			}
+			drift++
+			var settingValues []string
+			for value := range byValue {
+				settingValues = append(settingValues, value)
+			}
+			// The most common value first, the outliers after it
+			sort.Slice(settingValues, func(i, j int) bool {
+				a, b := byValue[settingValues[i]], byValue[settingValues[j]]
+				if len(a) != len(b) {
+					return len(a) > len(b)
+				}
+				return settingValues[i] < settingValues[j]
+			})
+			for _, value := range settingValues {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", group, key, value, strings.Join(byValue[value], ","))
+			}
+		}
+	}
+
+	if drift == 0 {
+		fmt.Printf("No kubelet configuration drift within %d group(s)\n", len(groups))
+		return
+	}
+	w.Flush()
+	os.Exit(1)
+}
+
+// compactJSON renders a configz value; objects have their keys sorted so equal maps compare equal
+func compactJSON(raw json.RawMessage) string {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw)
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return string(raw)
+	}
+	return string(data)
+}
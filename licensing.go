@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// linuxPlatform is the PlatformDetails of instances without license-included software
+const linuxPlatform = "Linux/UNIX"
+
+// instanceLicense describes the software license of an instance: "included: <platform>" for
+// license-included AMIs (Windows, RHEL, SQL Server, ...), "BYOL: <platform>" when a License
+// Manager configuration is attached, empty for plain Linux
+func instanceLicense(instance types.Instance) string {
+	platform := aws.ToString(instance.PlatformDetails)
+	if platform == "" && instance.Platform == types.PlatformValuesWindows {
+		platform = "Windows"
+	}
+	if len(instance.Licenses) > 0 {
+		return "BYOL: " + valueOrDash(platform)
+	}
+	if platform == "" || platform == linuxPlatform {
+		return ""
+	}
+	return "included: " + platform
+}
+
+// instanceTenancy returns dedicated or host for instances not on shared hardware, empty otherwise
+func instanceTenancy(instance types.Instance) string {
+	if instance.Placement == nil || instance.Placement.Tenancy == types.TenancyDefault {
+		return ""
+	}
+	return string(instance.Placement.Tenancy)
+}
+
+// hasSpecialLicensing reports whether any instance is license-included, BYOL or not on shared
+// hardware, whose cost model and placement rules differ from the Linux shared-tenancy default
+func hasSpecialLicensing(instanceMap map[string]types.Instance) bool {
+	for _, instance := range instanceMap {
+		if instanceLicense(instance) != "" || instanceTenancy(instance) != "" {
+			return true
+		}
+	}
+	return false
+}
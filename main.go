@@ -2,16 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -19,8 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
 )
 
 var (
@@ -29,21 +32,91 @@ var (
 	date    = "unknown"
 )
 
+// noAWSAccess fills the AWS columns of -o wide when AWS could not be reached
+const noAWSAccess = "<no-aws-access>"
+
 type NodeInfo struct {
-	Name         string
-	Status       string
-	Age          string
-	Version      string
-	InstanceID   string
-	InstanceType string
-	ASG          string
-	ASGCapacity  string
-	Taints       string
-	CPUCapacity  *resource.Quantity
-	CPURequested *resource.Quantity
-	MemCapacity  *resource.Quantity
-	MemRequested *resource.Quantity
-	PodCount     int
+	Name         string             `json:"name"`
+	Status       string             `json:"status"`
+	Age          string             `json:"age"`
+	Created      time.Time          `json:"-"`
+	Version      string             `json:"version"`
+	InstanceID   string             `json:"instanceID,omitempty"`
+	InstanceType string             `json:"instanceType,omitempty"`
+	EC2State     string             `json:"instanceState,omitempty"` // only set when not running
+	Lifecycle    string             `json:"lifecycle,omitempty"`
+	Zone         string             `json:"zone,omitempty"`
+	Subnet       string             `json:"subnet,omitempty"`
+	License      string             `json:"license,omitempty"` // e.g. "included: Windows"
+	Tenancy      string             `json:"tenancy,omitempty"` // dedicated or host, empty if shared
+	IPv6         string             `json:"ipv6,omitempty"`
+	PodIPFamily  string             `json:"podIPFamily,omitempty"` // IPv4, IPv6 or dual-stack
+	RootVolume   *ebsVolume         `json:"rootVolume,omitempty"`
+	AMI          string             `json:"ami,omitempty"`
+	AMIAge       string             `json:"amiAge,omitempty"`
+	StaleAMI     bool               `json:"staleAMI,omitempty"` // older than --stale-ami
+	DiskPressure bool               `json:"diskPressure"`
+	ASG          string             `json:"asg,omitempty"`     // group from --group-tag
+	ASGName      string             `json:"asgName,omitempty"` // real Auto Scaling Group, from aws:autoscaling:groupName
+	ASGCapacity  string             `json:"asgCapacity,omitempty"`
+	ASGAtMax     bool               `json:"asgAtMax"`
+	ASGRefresh   string             `json:"instanceRefresh,omitempty"`     // e.g. InProgress 45%
+	LTDrift      string             `json:"launchTemplateDrift,omitempty"` // e.g. v4→v7
+	HealthEvents []nodeHealthEvent  `json:"healthEvents,omitempty"`        // with --health
+	EKSNodegroup *eksNodegroup      `json:"nodegroup,omitempty"`
+	NodePool     string             `json:"nodePool,omitempty"`
+	CapacityType string             `json:"capacityType,omitempty"`
+	NodeClaim    string             `json:"nodeClaim,omitempty"`
+	GPUResource  string             `json:"gpuResource,omitempty"` // e.g. nvidia.com/gpu
+	GPUCapacity  int64              `json:"gpuCapacity,omitempty"`
+	GPURequested int64              `json:"gpuRequested,omitempty"`
+	GPUUtil      *float64           `json:"gpuUtilization,omitempty"` // percent, from dcgm-exporter
+	GPUMemUsed   *float64           `json:"gpuMemoryUsedPercent,omitempty"`
+	DrainPods    *int               `json:"drainPodsRemaining,omitempty"` // only set on cordoned nodes
+	DrainETA     string             `json:"drainETA,omitempty"`
+	Oversized    bool               `json:"oversized"`
+	Taints       string             `json:"taints,omitempty"`
+	CPUCapacity  *resource.Quantity `json:"cpuCapacity,omitempty"`
+	CPURequested *resource.Quantity `json:"cpuRequested,omitempty"`
+	CPULimits    *resource.Quantity `json:"cpuLimits,omitempty"`
+	CPUUsed      *resource.Quantity `json:"cpuUsed,omitempty"`
+	MemCapacity  *resource.Quantity `json:"memoryCapacity,omitempty"`
+	MemRequested *resource.Quantity `json:"memoryRequested,omitempty"`
+	MemLimits    *resource.Quantity `json:"memoryLimits,omitempty"`
+	MemUsed      *resource.Quantity `json:"memoryUsed,omitempty"`
+	PodCount     int                `json:"podCount"`
+	StaticPods   []string           `json:"staticPods,omitempty"`
+	EphCapacity  *resource.Quantity `json:"ephemeralStorageCapacity,omitempty"`
+	EphRequested *resource.Quantity `json:"ephemeralStorageRequested,omitempty"`
+	Stats        *statsSummary      `json:"stats,omitempty"`
+	DiskWarning  string             `json:"diskWarning,omitempty"`
+	Links        *ConsoleLinks      `json:"links,omitempty"`
+	Columns      map[string]string  `json:"columns,omitempty"`
+	// Prices in USD per hour, only set by the cost view
+	OnDemandPrice float64 `json:"onDemandPrice,omitempty"`
+	SpotPrice     float64 `json:"spotPrice,omitempty"`
+	HourlyCost    float64 `json:"hourlyCost,omitempty"`
+}
+
+// ASGCapacity holds the sizing of an Auto Scaling Group
+type ASGCapacity struct {
+	Min     int32
+	Max     int32
+	Desired int32
+}
+
+// AtMax reports whether the group cannot scale up any further
+func (c ASGCapacity) AtMax() bool {
+	return c.Desired >= c.Max
+}
+
+// String formats the capacity as min/max/desired, marking groups at max size with "!"
+func (c ASGCapacity) String() string {
+	s := fmt.Sprintf("%d/%d/%d", c.Min, c.Max, c.Desired)
+	if c.AtMax() {
+		s += "!"
+	}
+	return s
 }
 
 func main() {
@@ -51,24 +124,144 @@ func main() {
 	var showVersion bool
 	var openBrowser bool
 	var openASG bool
-	
+	var scaleASGName string
+	var scaleDesired int
+	var scaleBump int
+	var scaleToMin bool
+	var refreshASGName string
+	var dryRun bool
+	var ssmSession bool
+	var cordon bool
+	var uncordon bool
+	var showStatic bool
+	var showHealth bool
+	var atMax bool
+	var configPath string
+	var groupTags string
+	var wasteful bool
+	var wastefulThreshold float64
+	var wastefulMinCPU int64
+	var wastefulWindow time.Duration
+	var staleAMIDays int
+	var selector string
+	var recordHistory bool
+	var k9sPlugin bool
+	var spotOnly bool
+	var gpuOnly bool
+	var forWorkload string
+	var watchMode bool
+	var watchInterval time.Duration
+	var interactive bool
+	var sortBy string
+	var groupBy string
+	var columns customColumns
+	var customOutput outputColumns
+	var requestTimeout time.Duration
+	var strictAWS bool
+
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [NODE_NAME]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [NODE_NAME|node/NODE_NAME]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s COMMAND [OPTIONS]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "A kubectl plugin that extends 'kubectl get nodes' with AWS EC2 instance information.\n\n")
 		fmt.Fprintf(os.Stderr, "Examples:\n")
 		fmt.Fprintf(os.Stderr, "  %s                           # List all nodes with basic info\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -o wide                   # List all nodes with ASG info\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s -o top                    # List nodes with resource usage\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -o storage                # List nodes with local storage usage\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -o asg                    # Summarize nodes per Auto Scaling Group\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --group-by zone           # Summarize nodes per Availability Zone\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -l role=worker -o top     # Only nodes matching a label selector\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -o top --sort-by cpu-free # Fullest nodes first\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -o wide -w --interval 30s # Redraw the list as nodes change\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --interactive             # Browse nodes in a full-screen terminal UI\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s density                   # Histogram of pods and requests per node\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  %s --open ip-10-0-1-100      # Open AWS console for specific node\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "  %s --open-asg ip-10-0-1-100  # Open ASG console for specific node\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --open-asg ip-10-0-1-100  # Open ASG console for specific node\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --ssm ip-10-0-1-100       # Shell on a node through SSM Session Manager\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s --cordon ip-10-0-1-100    # Mark a node unschedulable (--uncordon reverts)\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Commands:\n")
+		fmt.Fprintf(os.Stderr, "  density          Histogram of pods and requests per node\n")
+		fmt.Fprintf(os.Stderr, "  check-access     Verify IAM permissions for every AWS API the plugin uses\n")
+		fmt.Fprintf(os.Stderr, "  wait             Block until N nodes of an ASG are Ready and schedulable\n")
+		fmt.Fprintf(os.Stderr, "  rollout-status   Show nodes on the current vs old launch template version\n")
+		fmt.Fprintf(os.Stderr, "  join-lag         Median time from EC2 launch to node registration/Ready per group\n")
+		fmt.Fprintf(os.Stderr, "  why-gone         Explain why a recently removed node or instance is gone\n")
+		fmt.Fprintf(os.Stderr, "  drain-check      List pods on a node that would go Pending if it were drained\n")
+		fmt.Fprintf(os.Stderr, "  label, taint     Label or taint all nodes of an ASG or instance type\n")
+		fmt.Fprintf(os.Stderr, "  asgs             List the cluster's ASGs, including empty ones\n")
+		fmt.Fprintf(os.Stderr, "  health           Show open AWS Health events affecting node instances\n")
+		fmt.Fprintf(os.Stderr, "  problems         List NotReady and NotServiceable nodes and node groups violating the headroom policy\n")
+		fmt.Fprintf(os.Stderr, "  blocked          Show per node what keeps recently unschedulable pods off it\n")
+		fmt.Fprintf(os.Stderr, "  daemon           Periodically export node inventory snapshots to S3\n")
+		fmt.Fprintf(os.Stderr, "  trend            Chart a node's pods and requests from runs recorded with --history\n")
+		fmt.Fprintf(os.Stderr, "  disruption       Estimate affected workloads and PDBs if a node were removed\n")
+		fmt.Fprintf(os.Stderr, "  cost             Estimated node cost per ASG or per EC2 tag (--by-tag) for chargeback\n")
+		fmt.Fprintf(os.Stderr, "  kubelet-drift    Report kubelet settings that differ between nodes of a group\n")
+		fmt.Fprintf(os.Stderr, "  recycle          Cordon and drain a node, then terminate it through its ASG\n")
+		fmt.Fprintf(os.Stderr, "  plan-upgrade     Plan a node group rotation to a new Kubernetes version\n")
+		fmt.Fprintf(os.Stderr, "  sg-check         Flag nodes whose security groups can't reach the EKS control plane\n")
+		fmt.Fprintf(os.Stderr, "  clock-drift      Estimate node clock skew from kubelet lease renewals (and chrony via SSM)\n")
+		fmt.Fprintf(os.Stderr, "  consolidation    Preview which Karpenter nodes can be consolidated and the savings\n")
+		fmt.Fprintf(os.Stderr, "  cpu-topology     Show each node's core/thread layout and kubelet CPU and topology manager policies\n")
+		fmt.Fprintf(os.Stderr, "  confidential     Show Nitro Enclaves and AMD SEV-SNP support and enablement per node\n")
+		fmt.Fprintf(os.Stderr, "  describe         Print a node's conditions, labels, resources, EC2 instance and ASG settings\n")
+		fmt.Fprintf(os.Stderr, "  simulate-az-failure  Check whether the other zones could host the pods of a lost zone\n")
+		fmt.Fprintf(os.Stderr, "  audit            Find ASGs lacking the kubernetes.io/cluster/<name> tag and nodes missing required labels\n")
+		fmt.Fprintf(os.Stderr, "  amis             Count nodes per AMI with the AMI name, age and EKS release\n")
+		fmt.Fprintf(os.Stderr, "  spot-pools       Compare the spot capacity pools in use per group with those configured\n")
+		fmt.Fprintf(os.Stderr, "  pods             List a node's pods by requests, largest first, with its EC2 instance\n")
+		fmt.Fprintf(os.Stderr, "  open, open-asg   Open the AWS console page of a node's instance or ASG, like --open and --open-asg\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 	}
-	
-	flag.StringVar(&outputFormat, "o", "", "Output format. Supported: wide, top")
+
+	flag.StringVar(&outputFormat, "o", "", "Output format. Supported: wide, top, storage, cost, asg, json, yaml, table, csv, custom-columns=HEADER:.field,...")
 	flag.BoolVar(&showVersion, "version", false, "Show version information")
 	flag.BoolVar(&openBrowser, "open", false, "Open AWS console for the specified node")
 	flag.BoolVar(&openASG, "open-asg", false, "Open Auto Scaling Group console for the specified node")
+	flag.StringVar(&scaleASGName, "scale-asg", "", "Set the desired capacity of this Auto Scaling Group to --desired, --bump or --to-min, within its min/max")
+	flag.IntVar(&scaleDesired, "desired", -1, "Desired capacity for --scale-asg")
+	flag.IntVar(&scaleBump, "bump", 0, "With --scale-asg, change the desired capacity by N (e.g. +2 or -1)")
+	flag.BoolVar(&scaleToMin, "to-min", false, "With --scale-asg, scale down to the ASG's min size")
+	flag.StringVar(&refreshASGName, "start-instance-refresh", "", "Start an instance refresh of this Auto Scaling Group, replacing its instances (e.g. with a new AMI)")
+	flag.BoolVar(&dryRun, "dry-run", false, "With --scale-asg or --start-instance-refresh, only show the change")
+	flag.BoolVar(&cordon, "cordon", false, "Mark the specified node unschedulable")
+	flag.BoolVar(&uncordon, "uncordon", false, "Mark the specified node schedulable")
+	flag.BoolVar(&ssmSession, "ssm", false, "Start an SSM Session Manager shell on the specified node (needs the AWS CLI and session-manager-plugin)")
+	flag.BoolVar(&atMax, "at-max", false, "Only show nodes whose ASG desired capacity is at its max size")
+	flag.StringVar(&forWorkload, "for", "", "Only show nodes running pods of a workload, [NAMESPACE/]KIND/NAME (e.g. deployment/my-app)")
+	flag.BoolVar(&spotOnly, "spot-only", false, "Only show nodes running on Spot instances")
+	flag.BoolVar(&gpuOnly, "gpu", false, "Only show accelerator nodes (GPU, Neuron or Gaudi extended resources)")
+	flag.StringVar(&configPath, "config", "", "Path to config file (default: "+defaultConfigPath()+")")
+	flag.StringVar(&groupTags, "group-tag", "", "Comma-separated EC2 tag keys used to detect node groups, in priority order (default: "+defaultASGTagKey+")")
+	flag.StringVar(&opts.caBundle, "ca-bundle", "", "Path to a PEM CA bundle trusted for AWS and Kubernetes API connections (e.g. a TLS-intercepting proxy)")
+	flag.StringVar(&opts.awsProfile, "profile", "", "AWS shared config profile to use (default: $AWS_PROFILE or the default profile)")
+	flag.StringVar(&opts.awsRegion, "region", "", "AWS region to use (default: $AWS_REGION or the profile's region)")
+	flag.BoolVar(&opts.quiet, "q", false, "Quiet: suppress warnings and progress messages on stderr (errors are still printed)")
+	flag.BoolVar(&opts.noCache, "no-cache", false, "Always query AWS instead of reusing cached EC2, ASG and pricing responses")
+	flag.DurationVar(&opts.cacheTTL, "cache-ttl", time.Minute, "How long cached EC2, ASG and spot price responses are reused, 0 to disable (on-demand prices are cached for a day)")
+	flag.BoolVar(&opts.plainNumbers, "plain-numbers", false, "Print CPU as integer millicores, memory and disk as integer bytes, and prices and percentages without $ or %")
+	addKubeFlags(flag.CommandLine)
+	flag.BoolVar(&wasteful, "wasteful", false, "Only show oversized nodes (large instances with low requests)")
+	flag.Float64Var(&wastefulThreshold, "wasteful-threshold", 20, "Requested percentage of both CPU and memory below which a node is oversized")
+	flag.IntVar(&staleAMIDays, "stale-ami", 0, "Mark nodes whose AMI is older than this many days with ! in AMI-AGE (0 to disable)")
+	flag.Int64Var(&wastefulMinCPU, "wasteful-min-cpu", 8, "Minimum allocatable CPU cores for a node to be considered oversized")
+	flag.DurationVar(&wastefulWindow, "wasteful-window", 7*24*time.Hour, "Judge oversized nodes by their peak requests over this long in the --history database, 0 for current requests only")
+	flag.StringVar(&selector, "l", "", "Label selector to filter nodes (e.g. node.kubernetes.io/instance-type=m5.xlarge)")
+	flag.StringVar(&selector, "selector", "", "Same as -l")
+	flag.BoolVar(&recordHistory, "history", false, "Append this run's nodes to the local history used by the trend command ("+defaultHistoryPath()+")")
+	flag.BoolVar(&k9sPlugin, "k9s-plugin", false, "Print a k9s plugins.yaml snippet binding node hotkeys to this tool's commands")
+	flag.BoolVar(&watchMode, "w", false, "Watch: redraw the output every --interval and whenever a node changes")
+	flag.BoolVar(&watchMode, "watch", false, "Same as -w")
+	flag.DurationVar(&watchInterval, "interval", 10*time.Second, "Refresh interval of --watch, including AWS data")
+	flag.DurationVar(&requestTimeout, "request-timeout", 2*time.Minute, "Time limit for the node, pod, EC2 and ASG lookups of one listing (0 for none)")
+	flag.BoolVar(&strictAWS, "strict-aws", false, "With -o wide, exit on AWS config or API errors instead of showing "+noAWSAccess+" in the AWS columns")
+	flag.StringVar(&sortBy, "sort-by", "", "Sort nodes by one of: "+nodeSortKeyNames+" (ascending, age oldest first)")
+	flag.Var(&columns, "column", "Add a column from node metadata, NAME=label:KEY or NAME=annotation:KEY (repeatable)")
+	flag.StringVar(&groupBy, "group-by", "", "Summarize nodes per group instead of listing them. Supported: zone")
+	flag.BoolVar(&interactive, "interactive", false, "Full-screen terminal UI to browse, sort and filter nodes and see their pods and ASG")
+	flag.BoolVar(&showStatic, "show-static", false, "List static (mirror) pods per node in top output")
+	flag.BoolVar(&showHealth, "health", false, "With -o wide or structured output, add the open AWS Health events affecting each node (needs a Business or Enterprise support plan)")
 	flag.Parse()
 
 	if showVersion {
@@ -76,25 +269,205 @@ func main() {
 		return
 	}
 
+	if sortBy != "" {
+		if err := validateSortKey(sortBy); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if k9sPlugin {
+		if err := printK9sPlugins(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(configOrDefault(configPath), configPath != "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	groupTagKeys := resolveGroupTags(groupTags, cfg)
+
+	if interactive {
+		runInteractive(groupTagKeys)
+		return
+	}
+
 	// Check if node name is specified with --open or --open-asg
 	args := flag.Args()
-	if openBrowser || openASG {
+	if len(args) > 0 {
+		switch args[0] {
+		case "density":
+			runDensity(args[1:])
+			return
+		case "check-access":
+			runCheckAccess(args[1:])
+			return
+		case "wait":
+			runWait(args[1:], groupTagKeys)
+			return
+		case "rollout-status":
+			runRolloutStatus(args[1:])
+			return
+		case "join-lag":
+			runJoinLag(args[1:], groupTagKeys)
+			return
+		case "why-gone":
+			runWhyGone(args[1:])
+			return
+		case "drain-check":
+			runDrainCheck(args[1:])
+			return
+		case "label", "taint":
+			runNodeEdit(args[0], args[1:], groupTagKeys)
+			return
+		case "asgs":
+			runASGs(args[1:])
+			return
+		case "health":
+			runHealth(args[1:])
+			return
+		case "problems":
+			runProblems(args[1:], cfg, groupTagKeys)
+			return
+		case "blocked":
+			runBlocked(args[1:])
+			return
+		case "daemon":
+			runDaemon(args[1:], groupTagKeys)
+			return
+		case "trend":
+			runTrend(args[1:])
+			return
+		case "disruption":
+			runDisruption(args[1:])
+			return
+		case "cost":
+			runCost(args[1:], groupTagKeys)
+			return
+		case "kubelet-drift":
+			runKubeletDrift(args[1:], groupTagKeys)
+			return
+		case "recycle":
+			runRecycle(args[1:])
+			return
+		case "plan-upgrade":
+			runPlanUpgrade(args[1:], groupTagKeys)
+			return
+		case "sg-check":
+			runSGCheck(args[1:])
+			return
+		case "clock-drift":
+			runClockDrift(args[1:])
+			return
+		case "consolidation":
+			runConsolidation(args[1:], groupTagKeys)
+			return
+		case "cpu-topology":
+			runCPUTopology(args[1:])
+			return
+		case "confidential":
+			runConfidential(args[1:])
+			return
+		case "describe":
+			runDescribe(args[1:])
+			return
+		case "simulate-az-failure":
+			runSimulateAZFailure(args[1:])
+			return
+		case "audit":
+			runAudit(args[1:], cfg)
+			return
+		case "amis":
+			runAMIs(args[1:])
+			return
+		case "spot-pools":
+			runSpotPools(args[1:], groupTagKeys)
+			return
+		case "open", "open-asg":
+			runOpen(args[0], args[1:])
+			return
+		case "pods":
+			runPods(args[1:], groupTagKeys)
+			return
+		}
+	}
+
+	if scaleASGName != "" {
+		target := scaleTarget{desired: int32(scaleDesired), bump: int32(scaleBump), toMin: scaleToMin}
+		if target.count() != 1 {
+			fmt.Fprintf(os.Stderr, "Error: --scale-asg requires one of --desired N, --bump N or --to-min\n")
+			os.Exit(1)
+		}
+		scaleASG(scaleASGName, target, dryRun)
+		return
+	}
+	if refreshASGName != "" {
+		startInstanceRefresh(refreshASGName, dryRun)
+		return
+	}
+
+	if openBrowser || openASG || ssmSession || cordon || uncordon {
 		if len(args) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: --open and --open-asg require a node name\n")
+			fmt.Fprintf(os.Stderr, "Error: --open, --open-asg, --ssm, --cordon and --uncordon require a node name\n")
+			os.Exit(1)
+		}
+		nodeName, err := parseNodeArg(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 		if openBrowser {
-			openNodeInBrowser(args[0])
+			openNodeInBrowser(nodeName)
+		} else if openASG {
+			openNodeASGInBrowser(nodeName)
+		} else if ssmSession {
+			startSSMSession(nodeName)
 		} else {
-			openNodeASGInBrowser(args[0])
+			cordonNode(nodeName, cordon, groupTagKeys)
 		}
 		return
 	}
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", unknownCommandError(args[0]))
+		os.Exit(1)
+	}
 
-	if outputFormat != "" && outputFormat != "wide" && outputFormat != "top" {
-		fmt.Fprintf(os.Stderr, "Error: unsupported output format '%s'. Supported: wide, top\n", outputFormat)
+	if spec, found := strings.CutPrefix(outputFormat, "custom-columns="); found {
+		customOutput, err = parseOutputColumns(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid custom-columns: %v\n", err)
+			os.Exit(1)
+		}
+		outputFormat = "custom-columns"
+	}
+	if outputFormat != "" && outputFormat != "wide" && outputFormat != "top" && outputFormat != "storage" && outputFormat != "cost" && outputFormat != "asg" && outputFormat != "json" && outputFormat != "yaml" && outputFormat != "table" && outputFormat != "csv" && outputFormat != "custom-columns" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported output format '%s'. Supported: wide, top, storage, cost, asg, json, yaml, table, csv, custom-columns=SPEC\n", outputFormat)
+		os.Exit(1)
+	}
+	if groupBy != "" && groupBy != "zone" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported group-by '%s'. Supported: zone\n", groupBy)
 		os.Exit(1)
 	}
+	if groupBy != "" && outputFormat != "" && outputFormat != "wide" && outputFormat != "top" {
+		fmt.Fprintf(os.Stderr, "Error: --group-by is not supported with -o %s\n", outputFormat)
+		os.Exit(1)
+	}
+	if watchMode && (outputFormat == "json" || outputFormat == "yaml" || outputFormat == "table" || outputFormat == "csv") {
+		fmt.Fprintf(os.Stderr, "Error: --watch is not supported with -o %s\n", outputFormat)
+		os.Exit(1)
+	}
+	if watchMode && watchInterval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --interval must be positive\n")
+		os.Exit(1)
+	}
+	if watchMode {
+		// Each refresh should show what AWS reports now
+		opts.noCache = true
+	}
 	// Initialize Kubernetes client
 	kubeConfig, err := getKubeConfig()
 	if err != nil {
@@ -109,163 +482,839 @@ func main() {
 	}
 
 	// Initialize AWS clients only if needed
-	var ec2Client *ec2.Client
-	var asgClient *autoscaling.Client
-	if outputFormat == "wide" {
-		awsConfig, err := awsconfig.LoadDefaultConfig(context.TODO())
-		if err != nil {
+	structured := outputFormat == "json" || outputFormat == "yaml" || outputFormat == "table" || outputFormat == "csv" || outputFormat == "custom-columns"
+	needAWS := outputFormat == "wide" || outputFormat == "cost" || outputFormat == "asg" || groupBy != "" || structured || atMax || spotOnly || recordHistory || showHealth
+	var awsConfig aws.Config
+	var awsRegion string
+	// Without AWS access, -o wide still lists the Kubernetes side of the nodes. The filters
+	// below need AWS data to mean anything, so they keep failing hard.
+	degradeAWS := outputFormat == "wide" && !strictAWS && !atMax && !spotOnly
+	awsUnavailable := false
+	awsWarned := false
+	warnNoAWS := func(err error) {
+		if !awsWarned {
+			awsWarned = true
+			warnf("AWS data unavailable, showing %s in the AWS columns (use --strict-aws to fail instead): %v\n", noAWSAccess, err)
+		}
+	}
+	if needAWS {
+		var err error
+		awsConfig, err = loadAWSConfig()
+		if err != nil && degradeAWS {
+			warnNoAWS(err)
+			awsUnavailable = true
+		} else if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
 			os.Exit(1)
 		}
-		ec2Client = ec2.NewFromConfig(awsConfig)
-		asgClient = autoscaling.NewFromConfig(awsConfig)
+		awsRegion = awsConfig.Region
 	}
 
-	// Get nodes
-	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
-		os.Exit(1)
+	// AWS data is kept between watch refreshes and only looked up again on the interval,
+	// or when nodes with unknown instances joined
+	var instanceMap map[string]types.Instance
+	var asgMap map[string]ASGCapacity
+	var prices *priceBook
+	var peaks map[string]peakRequests
+	var nodegroups map[string]eksNodegroup
+	var histories map[string]desiredHistory
+	var refreshes map[string]string
+	var drift map[string]string
+	var volumes map[string][]ebsVolume
+	var images map[string]types.Image
+	var health map[string][]nodeHealthEvent
+	drains := drainTracker{}
+	render := func(out io.Writer, refreshAWS bool) error {
+		ctx := context.Background()
+		if requestTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+			defer cancel()
+		}
+
+		// Get nodes
+		nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return fmt.Errorf("listing nodes: %w", err)
+		}
+
+		// Restrict to the nodes running the workload's pods
+		if forWorkload != "" {
+			workloadNodeNames, err := workloadNodes(clientset, forWorkload)
+			if err != nil {
+				return fmt.Errorf("resolving workload '%s': %w", forWorkload, err)
+			}
+			var matched []v1.Node
+			for _, node := range nodes.Items {
+				if workloadNodeNames[node.Name] {
+					matched = append(matched, node)
+				}
+			}
+			nodes.Items = matched
+		}
+
+		// Pods for resource calculations, and EC2 instances and ASG info when AWS data is needed,
+		// only depend on the node list and are fetched concurrently
+		var pods *v1.PodList
+		lookups := []func(ctx context.Context) error{
+			func(ctx context.Context) error {
+				var err error
+				pods, err = clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return fmt.Errorf("listing pods: %w", err)
+				}
+				return nil
+			},
+		}
+		instanceIDs := nodeInstanceIDs(nodes.Items)
+		fetchAWS := needAWS && !awsUnavailable && (refreshAWS || !hasInstances(instanceMap, instanceIDs))
+		// With degradeAWS the AWS errors are kept aside instead of failing the listing
+		var instancesErr, asgErr error
+		if fetchAWS {
+			lookups = append(lookups,
+				func(ctx context.Context) error {
+					var err error
+					instanceMap, err = getNodeInstances(ctx, awsConfig, nodes.Items)
+					if err != nil && degradeAWS {
+						instancesErr = err
+					} else if err != nil {
+						return fmt.Errorf("getting EC2 instances: %w", err)
+					}
+					return nil
+				},
+				func(ctx context.Context) error {
+					var err error
+					asgMap, err = getNodeASGCapacities(ctx, awsConfig, nodes.Items)
+					if err != nil && degradeAWS {
+						asgErr = err
+					} else if err != nil {
+						return fmt.Errorf("getting ASG capacities: %w", err)
+					}
+					return nil
+				})
+		}
+		if err := runConcurrently(ctx, lookups...); err != nil {
+			return err
+		}
+		noInstances := awsUnavailable || instancesErr != nil
+		noASGs := noInstances || asgErr != nil
+		if instancesErr != nil {
+			warnNoAWS(instancesErr)
+		} else if asgErr != nil {
+			warnNoAWS(asgErr)
+		}
+
+		// Calculate resource usage per node
+		nodeResources := calculateNodeResources(nodes.Items, pods.Items)
+
+		// EBS volumes are only shown by the wide and structured views, and need the instances'
+		// root device names
+		if fetchAWS && !noInstances && (outputFormat == "wide" || structured) {
+			volumes, err = getNodeVolumes(awsConfig, nodes.Items, instanceMap)
+			if err != nil {
+				warnf("could not describe EBS volumes: %v\n", err)
+			}
+			images, err = getNodeImages(awsConfig, nodes.Items, instanceMap)
+			if err != nil {
+				warnf("could not describe AMIs, their ages are not shown: %v\n", err)
+			}
+		}
+
+		// Managed node groups are described for the views that show them, with the cluster name
+		// taken from the instance tags
+		if (outputFormat == "wide" || structured) && hasEKSNodegroups(nodes.Items) && (refreshAWS || nodegroups == nil) {
+			registered := make(map[string]bool)
+			for _, id := range instanceIDs {
+				registered[id] = true
+			}
+			if clusterName := detectClusterName(instanceMap, registered); clusterName != "" {
+				nodegroups, err = getEKSNodegroups(awsConfig, clusterName, nodes.Items)
+				if err != nil {
+					warnf("could not describe EKS node groups: %v\n", err)
+				}
+			}
+		}
+
+		// Running instance refreshes and instances launched from an outdated launch template
+		// version are shown for the nodes' ASGs, so a rolling AMI update can be followed with --watch
+		if (outputFormat == "wide" || structured) && !noASGs && (refreshAWS || refreshes == nil) {
+			asgRegions := make(map[string]string)
+			for _, node := range nodes.Items {
+				if instance, exists := instanceMap[getInstanceID(node)]; exists {
+					asgName := instanceASG(instance)
+					if _, isASG := asgMap[asgName]; isASG {
+						asgRegions[asgName] = nodeRegion(node, awsConfig.Region)
+					}
+				}
+			}
+			refreshes = getInstanceRefreshes(awsConfig, asgRegions)
+			drift = getLaunchTemplateDrift(awsConfig, asgRegions)
+		}
+		// AWS Health is opt-in, as accounts without a Business or Enterprise support plan can't call it
+		if showHealth && (outputFormat == "wide" || structured) && !awsUnavailable && (refreshAWS || health == nil) {
+			health, _, err = getNodeHealthEvents(ctx, awsConfig, nodes.Items)
+			if err != nil {
+				warnf("could not get AWS Health events: %v\n", classifyAWSError(err))
+				health = map[string][]nodeHealthEvent{}
+			}
+		}
+
+		showRefresh := outputFormat == "wide" && len(refreshes) > 0
+		showDrift := outputFormat == "wide" && len(drift) > 0
+
+		// Licensed software and dedicated hardware change what a node costs and where it can be
+		// placed, and the cost view's prices assume neither
+		showLicensing := (outputFormat == "wide" || outputFormat == "cost") && hasSpecialLicensing(instanceMap)
+		if showLicensing && outputFormat == "cost" {
+			warnf("prices assume Linux with shared tenancy, nodes with a LICENSE or TENANCY cost more\n")
+		}
+
+		// Cordoned nodes show the pods a drain has left to evict, with an ETA once --watch has seen
+		// some evicted
+		showDrain := false
+		for _, node := range nodes.Items {
+			showDrain = showDrain || node.Spec.Unschedulable
+		}
+		var drainable map[string]int
+		if showDrain {
+			drainable = drainablePodsByNode(pods.Items)
+		}
+
+		// The pod IP family matters on IPv6 and dual-stack clusters, whose nodes have IPv6 addresses
+		showIPv6 := outputFormat == "wide" && hasIPv6Nodes(nodes.Items)
+		var ipFamilies map[string]string
+		if showIPv6 || structured {
+			ipFamilies = podIPFamilies(nodes.Items, pods.Items)
+		}
+
+		// Karpenter nodes have no ASG, their NodePool and capacity type are shown instead
+		showKarpenter := (outputFormat == "wide" || structured) && hasKarpenterNodes(nodes.Items)
+		var claims map[string]nodeClaim
+		if showKarpenter {
+			claims, err = getNodeClaims(clientset)
+			if err != nil {
+				warnf("could not list Karpenter NodeClaims: %v\n", err)
+			}
+		}
+
+		// Prices are only looked up for the cost view (or custom columns showing them, or the history
+		// charted by trend), one Pricing API call per instance type, and like the other AWS data only
+		// refreshed on the watch interval
+		showPrices := outputFormat == "cost" || customOutput.references("onDemandPrice", "spotPrice", "hourlyCost")
+		if (showPrices || recordHistory) && (refreshAWS || prices == nil) {
+			prices, err = getInstancePrices(awsConfig, instanceMap)
+			if err != nil && showPrices {
+				return fmt.Errorf("getting prices: %w", err)
+			} else if err != nil {
+				warnf("could not get prices, recording history without cost: %v\n", err)
+			}
+		}
+
+		// Oversized nodes are judged by their peak requests recorded with --history, when there is any,
+		// so a node that is only idle between batch runs isn't flagged
+		showOversized := wasteful || outputFormat == "top" || structured || customOutput.references("oversized")
+		if showOversized && wastefulWindow > 0 && (refreshAWS || peaks == nil) {
+			peaks, err = readPeakRequests(defaultHistoryPath(), time.Now().Add(-wastefulWindow))
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					warnf("could not read peak requests from the history, judging oversized nodes by current requests: %v\n", err)
+				}
+				peaks = map[string]peakRequests{}
+			}
+		}
+
+		// Accelerator columns are only added to the top view of clusters that have such nodes
+		showGPU := outputFormat == "top" && hasAccelerators(nodes.Items)
+
+		// GPU usage is optional like metrics-server's: without dcgm-exporter the columns stay empty
+		var gpuUsage map[string]*gpuMetrics
+		if showGPU || customOutput.references("gpuUtilization", "gpuMemoryUsedPercent") {
+			gpuUsage = make(map[string]*gpuMetrics)
+			for nodeName, exporter := range dcgmExporters(pods.Items) {
+				metrics, err := getGPUMetrics(clientset, exporter)
+				if err != nil {
+					warnf("could not get GPU metrics of node '%s' from %s/%s: %v\n", nodeName, exporter.Namespace, exporter.Name, err)
+					continue
+				}
+				gpuUsage[nodeName] = metrics
+			}
+		}
+
+		// Print results
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		if groupBy != "" {
+			// The summary is written after all nodes are collected
+		} else if outputFormat == "wide" {
+			header := "NAME\tSTATUS\tAGE\tVERSION\tINSTANCE-ID\tINSTANCE-TYPE\tLIFECYCLE\tZONE\tSUBNET\tAMI\tAMI-AGE\tROOT-VOLUME\tDISK-PRESSURE\tTAINTS\tASG\tASG-CAPACITY\tNODEGROUP"
+			if showKarpenter {
+				header += "\tNODEPOOL\tCAPACITY-TYPE"
+			}
+			if showIPv6 {
+				header += "\tIPV6\tPOD-IP-FAMILY"
+			}
+			if showRefresh {
+				header += "\tINSTANCE-REFRESH"
+			}
+			if showDrift {
+				header += "\tDRIFT"
+			}
+			if showLicensing {
+				header += "\tLICENSE\tTENANCY"
+			}
+			if showHealth {
+				header += "\tHEALTH"
+			}
+			if showDrain {
+				header += "\tDRAIN"
+			}
+			fmt.Fprintln(w, header+columns.header())
+		} else if outputFormat == "top" {
+			header := []string{"NAME", "PODS", "STATIC", "CPU-CAP", "CPU-REQ", "CPU-LIM", "CPU-USED", "CPU-FREE%", "MEM-CAP", "MEM-REQ", "MEM-LIM", "MEM-USED", "MEM-FREE%", "EPH-CAP", "EPH-REQ", "EPH-FREE%", "OVERCOMMIT", "OVERSIZED"}
+			if showGPU {
+				header = append(header, "GPU-CAP", "GPU-REQ", "GPU-FREE%", "GPU-UTIL%", "GPU-MEM%")
+			}
+			if showStatic {
+				header = append(header, "STATIC-PODS")
+			}
+			fmt.Fprintln(w, strings.Join(header, "\t")+columns.header())
+		} else if outputFormat == "cost" {
+			header := "NAME\tINSTANCE-TYPE\tLIFECYCLE\tZONE\tASG\tON-DEMAND/H\tSPOT/H\tHOURLY\tMONTHLY"
+			if showLicensing {
+				header += "\tLICENSE\tTENANCY"
+			}
+			fmt.Fprintln(w, header+columns.header())
+		} else if structured || outputFormat == "asg" {
+			// Structured output and the ASG summary are written after all nodes are collected
+		} else if outputFormat == "storage" {
+			fmt.Fprintln(w, "NAME\tEPH-CAP\tEPH-REQ\tEPH-FREE%\tNODEFS-USED\tNODEFS-CAP\tNODEFS-USED%\tIMAGEFS-USED\tIMAGEFS-CAP\tIMAGEFS-USED%\tDISK-USED%\tDISK-WARNING"+columns.header())
+		} else {
+			header := "NAME\tSTATUS\tAGE\tVERSION\tINSTANCE-ID\tINSTANCE-TYPE\tTAINTS"
+			if showDrain {
+				header += "\tDRAIN"
+			}
+			fmt.Fprintln(w, header+columns.header())
+		}
+
+		// Actual usage is optional: without metrics-server the USED columns stay empty
+		var nodeUsage map[string]v1.ResourceList
+		if outputFormat == "top" || customOutput.references("cpuUsed", "memoryUsed") {
+			nodeUsage, err = getNodeUsage(clientset)
+			if err != nil {
+				warnf("could not get node metrics (is metrics-server installed?): %v\n", err)
+			}
+		}
+
+		collected := []NodeInfo{}
+		for _, node := range nodes.Items {
+			nodeInfo := newNodeInfo(node, nodeResources[node.Name], instanceMap, asgMap, awsRegion, groupTagKeys)
+			nodeInfo.Oversized = isOversized(nodeInfo, peaks[node.Name], wastefulThreshold, wastefulMinCPU)
+			prices.applyPrices(&nodeInfo)
+			nodeInfo.Columns = columns.values(node)
+			nodeInfo.RootVolume = rootVolume(volumes[nodeInfo.InstanceID])
+			if instance, exists := instanceMap[nodeInfo.InstanceID]; exists {
+				applyAMI(&nodeInfo, instance, images, staleAMIDays)
+			}
+			if showKarpenter {
+				applyKarpenter(&nodeInfo, node, claims)
+			}
+			nodeInfo.PodIPFamily = ipFamilies[node.Name]
+			nodeInfo.ASGRefresh = refreshes[nodeInfo.ASGName]
+			nodeInfo.LTDrift = drift[nodeInfo.InstanceID]
+			nodeInfo.HealthEvents = health[node.Name]
+			if metrics, exists := gpuUsage[node.Name]; exists {
+				nodeInfo.GPUUtil = &metrics.Utilization
+				nodeInfo.GPUMemUsed = &metrics.MemoryUsedPct
+			}
+			if node.Spec.Unschedulable {
+				remaining := drainable[node.Name]
+				nodeInfo.DrainPods = &remaining
+				if eta, ok := drains.observe(node.Name, remaining, time.Now()); ok {
+					nodeInfo.DrainETA = formatAge(eta)
+				}
+			} else {
+				delete(drains, node.Name)
+			}
+			if nodeInfo.EKSNodegroup != nil {
+				if nodegroup, exists := nodegroups[nodeInfo.EKSNodegroup.Name]; exists {
+					nodeInfo.EKSNodegroup = &nodegroup
+				}
+			}
+			if usage, exists := nodeUsage[node.Name]; exists {
+				// metrics-server reports nanocores, round to millicores like requests
+				nodeInfo.CPUUsed = resource.NewMilliQuantity(usage.Cpu().MilliValue(), resource.DecimalSI)
+				nodeInfo.MemUsed = usage.Memory()
+			}
+
+			// Kubelet filesystem stats are only needed for the storage view
+			if outputFormat == "storage" || customOutput.references("stats", "diskWarning") {
+				stats, err := getNodeStatsSummary(clientset, node.Name)
+				if err != nil {
+					warnf("could not get stats summary for node '%s': %v\n", node.Name, err)
+				}
+				nodeInfo.Stats = stats
+
+				thresholds, err := getKubeletDiskThresholds(clientset, node.Name)
+				if err != nil {
+					warnf("could not read kubelet config of node '%s', assuming default GC/eviction thresholds: %v\n", node.Name, err)
+				}
+				if stats != nil {
+					nodeInfo.DiskWarning = diskWarning(stats, thresholds)
+				}
+			}
+
+			if noInstances {
+				nodeInfo.Lifecycle, nodeInfo.Subnet, nodeInfo.ASG = noAWSAccess, noAWSAccess, noAWSAccess
+			}
+			if noASGs && nodeInfo.ASG != "" {
+				nodeInfo.ASGCapacity = noAWSAccess
+			}
+
+			if gpuOnly && nodeInfo.GPUCapacity == 0 {
+				continue
+			}
+			if spotOnly && nodeInfo.Lifecycle != "spot" {
+				continue
+			}
+			if atMax && !nodeInfo.ASGAtMax {
+				continue
+			}
+			if wasteful && !nodeInfo.Oversized {
+				continue
+			}
+
+			collected = append(collected, nodeInfo)
+		}
+
+		if sortBy != "" {
+			sortNodeInfos(collected, sortBy)
+		}
+
+		for _, nodeInfo := range collected {
+			if structured || outputFormat == "asg" || groupBy != "" {
+				// Printed after the loop
+			} else if outputFormat == "wide" {
+				rootVolume := valueOrDash(nodeInfo.RootVolume.String())
+				ami, amiAge := valueOrDash(nodeInfo.AMI), valueOrDash(nodeInfo.AMIAge)
+				if nodeInfo.StaleAMI {
+					amiAge += "!"
+				}
+				if noInstances {
+					rootVolume, ami, amiAge = noAWSAccess, noAWSAccess, noAWSAccess
+				}
+				row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+					nodeInfo.Name, nodeInfo.Status, nodeInfo.Age,
+					nodeInfo.Version, formatInstanceID(nodeInfo), nodeInfo.InstanceType, nodeInfo.Lifecycle, nodeInfo.Zone, nodeInfo.Subnet,
+					ami, amiAge, rootVolume, formatFlag(nodeInfo.DiskPressure),
+					nodeInfo.Taints, nodeInfo.ASG, nodeInfo.ASGCapacity,
+					valueOrDash(nodeInfo.EKSNodegroup.String()))
+				if showKarpenter {
+					row += "\t" + valueOrDash(nodeInfo.NodePool) + "\t" + valueOrDash(nodeInfo.CapacityType)
+				}
+				if showIPv6 {
+					row += "\t" + valueOrDash(nodeInfo.IPv6) + "\t" + valueOrDash(nodeInfo.PodIPFamily)
+				}
+				if showRefresh {
+					row += "\t" + valueOrDash(nodeInfo.ASGRefresh)
+				}
+				if showDrift {
+					row += "\t" + valueOrDash(nodeInfo.LTDrift)
+				}
+				if showLicensing {
+					row += "\t" + valueOrDash(nodeInfo.License) + "\t" + valueOrDash(nodeInfo.Tenancy)
+				}
+				if showHealth {
+					row += "\t" + formatHealthEvents(nodeInfo.HealthEvents)
+				}
+				if showDrain {
+					row += "\t" + formatDrain(nodeInfo)
+				}
+				fmt.Fprintln(w, row+columns.cells(nodeInfo))
+			} else if outputFormat == "top" {
+				cpuFree := calculateFreePercentage(nodeInfo.CPUCapacity, nodeInfo.CPURequested)
+				memFree := calculateFreePercentage(nodeInfo.MemCapacity, nodeInfo.MemRequested)
+				ephFree := calculateFreePercentage(nodeInfo.EphCapacity, nodeInfo.EphRequested)
+				row := []string{
+					nodeInfo.Name, fmt.Sprintf("%d", nodeInfo.PodCount), fmt.Sprintf("%d", len(nodeInfo.StaticPods)),
+					formatResource(nodeInfo.CPUCapacity), formatResource(nodeInfo.CPURequested), formatResource(nodeInfo.CPULimits), formatUsage(nodeInfo.CPUUsed, formatResource), formatPercent(cpuFree),
+					formatMemory(nodeInfo.MemCapacity), formatMemory(nodeInfo.MemRequested), formatMemory(nodeInfo.MemLimits), formatUsage(nodeInfo.MemUsed, formatMemory), formatPercent(memFree),
+					formatMemory(nodeInfo.EphCapacity), formatMemory(nodeInfo.EphRequested), formatPercent(ephFree),
+					formatRatio(overcommitRatio(nodeInfo)), formatFlag(nodeInfo.Oversized),
+				}
+				if showGPU {
+					row = append(row, formatGPUs(nodeInfo.GPUCapacity, nodeInfo.GPUResource), formatGPUs(nodeInfo.GPURequested, nodeInfo.GPUResource), formatGPUFree(nodeInfo),
+						formatOptionalPercent(nodeInfo.GPUUtil), formatOptionalPercent(nodeInfo.GPUMemUsed))
+				}
+				if showStatic {
+					row = append(row, strings.Join(nodeInfo.StaticPods, ","))
+				}
+				fmt.Fprintln(w, strings.Join(row, "\t")+columns.cells(nodeInfo))
+			} else if outputFormat == "cost" {
+				row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+					nodeInfo.Name, nodeInfo.InstanceType, nodeInfo.Lifecycle, nodeInfo.Zone, nodeInfo.ASG,
+					formatPrice(nodeInfo.OnDemandPrice), formatPrice(nodeInfo.SpotPrice),
+					formatPrice(nodeInfo.HourlyCost), formatMonthly(nodeInfo.HourlyCost))
+				if showLicensing {
+					row += "\t" + valueOrDash(nodeInfo.License) + "\t" + valueOrDash(nodeInfo.Tenancy)
+				}
+				fmt.Fprintln(w, row+columns.cells(nodeInfo))
+			} else if outputFormat == "storage" {
+				ephFree := calculateFreePercentage(nodeInfo.EphCapacity, nodeInfo.EphRequested)
+				nodeFsUsed, nodeFsCap, nodeFsPct := formatFsUsage(nodeInfo.Stats.nodeFs())
+				imageFsUsed, imageFsCap, imageFsPct := formatFsUsage(nodeInfo.Stats.imageFs())
+				diskPct := "-"
+				if used, ok := nodeInfo.Stats.diskUsedPercent(); ok {
+					diskPct = formatPercent(used)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s\n",
+					nodeInfo.Name,
+					formatMemory(nodeInfo.EphCapacity), formatMemory(nodeInfo.EphRequested), formatPercent(ephFree),
+					nodeFsUsed, nodeFsCap, nodeFsPct,
+					imageFsUsed, imageFsCap, imageFsPct,
+					diskPct, nodeInfo.DiskWarning, columns.cells(nodeInfo))
+			} else {
+				row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s",
+					nodeInfo.Name, nodeInfo.Status, nodeInfo.Age,
+					nodeInfo.Version, nodeInfo.InstanceID, nodeInfo.InstanceType, nodeInfo.Taints)
+				if showDrain {
+					row += "\t" + formatDrain(nodeInfo)
+				}
+				fmt.Fprintln(w, row+columns.cells(nodeInfo))
+			}
+		}
+
+		if groupBy == "zone" {
+			printZoneSummary(out, collected)
+		} else if outputFormat == "table" {
+			if err := printTable(out, collected, nodes.Items); err != nil {
+				return fmt.Errorf("encoding table: %w", err)
+			}
+		} else if outputFormat == "csv" {
+			if err := printCSV(out, collected, columns); err != nil {
+				return fmt.Errorf("writing CSV: %w", err)
+			}
+		} else if outputFormat == "custom-columns" {
+			if err := printCustomColumns(out, collected, customOutput); err != nil {
+				return fmt.Errorf("writing custom columns: %w", err)
+			}
+		} else if structured {
+			if err := printStructured(out, outputFormat, collected); err != nil {
+				return fmt.Errorf("encoding %s: %w", outputFormat, err)
+			}
+		} else if outputFormat == "asg" {
+			if refreshAWS || histories == nil {
+				asgRegions := make(map[string]string)
+				for _, n := range collected {
+					if n.ASGName != "" {
+						asgRegions[n.ASGName] = regionOfZone(n.Zone, awsConfig.Region)
+					}
+				}
+				histories = getDesiredHistories(awsConfig, asgRegions, asgMap)
+			}
+			printASGSummary(out, collected, asgMap, histories, cfg)
+		} else {
+			w.Flush()
+		}
+		if outputFormat == "cost" {
+			fmt.Fprintln(out)
+			printCostTotals(out, collected)
+		}
+
+		if recordHistory && refreshAWS {
+			if err := appendHistory(defaultHistoryPath(), collected); err != nil {
+				warnf("could not record history: %v\n", err)
+			}
+		}
+		return nil
 	}
 
-	// Get pods for resource calculations
-	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+	if watchMode {
+		watchNodes(clientset, selector, watchInterval, render)
+		return
+	}
+	if err := render(os.Stdout, true); err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
 		os.Exit(1)
 	}
+}
+
+// printStructured writes the nodes as a JSON array or YAML list. Both use the json field names.
+func printStructured(out io.Writer, format string, nodes []NodeInfo) error {
+	if format == "yaml" {
+		data, err := yaml.Marshal(nodes)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(nodes)
+}
+
+// newNodeInfo combines a node with its resource usage and, when instanceMap is set, its EC2 and ASG details
+func newNodeInfo(node v1.Node, resInfo *NodeInfo, instanceMap map[string]types.Instance, asgMap map[string]ASGCapacity, awsRegion string, groupTagKeys []string) NodeInfo {
+	nodeInfo := NodeInfo{
+		Name:    node.Name,
+		Status:  getNodeStatus(node),
+		Age:     getNodeAge(node),
+		Created: node.CreationTimestamp.Time,
+		Version: node.Status.NodeInfo.KubeletVersion,
+		Taints:  getNodeTaints(node),
+	}
+	nodeInfo.DiskPressure = hasDiskPressure(node)
+	nodeInfo.IPv6 = nodeIPv6(node)
+	if name := node.Labels[eksNodegroupLabel]; name != "" {
+		nodeInfo.EKSNodegroup = &eksNodegroup{Name: name}
+	}
 
-	// Calculate resource usage per node
+	// Copy resource info
+	if resInfo != nil {
+		nodeInfo.CPUCapacity = resInfo.CPUCapacity
+		nodeInfo.CPURequested = resInfo.CPURequested
+		nodeInfo.CPULimits = resInfo.CPULimits
+		nodeInfo.MemCapacity = resInfo.MemCapacity
+		nodeInfo.MemRequested = resInfo.MemRequested
+		nodeInfo.MemLimits = resInfo.MemLimits
+		nodeInfo.PodCount = resInfo.PodCount
+		nodeInfo.StaticPods = resInfo.StaticPods
+		nodeInfo.EphCapacity = resInfo.EphCapacity
+		nodeInfo.EphRequested = resInfo.EphRequested
+		nodeInfo.GPUResource = resInfo.GPUResource
+		nodeInfo.GPUCapacity = resInfo.GPUCapacity
+		nodeInfo.GPURequested = resInfo.GPURequested
+	}
+
+	// Get instance info from Kubernetes
+	nodeInfo.InstanceID = getInstanceID(node)
+	nodeInfo.InstanceType = getInstanceType(node)
+	nodeInfo.Zone = getNodeZone(node)
+
+	// Get ASG info from AWS (only if we have AWS access and instance ID)
+	if nodeInfo.InstanceID != "" {
+		if instance, exists := instanceMap[nodeInfo.InstanceID]; exists {
+			// Stopped and recently terminated instances are still described, with their type and tags
+			if instance.State != nil && instance.State.Name != types.InstanceStateNameRunning {
+				nodeInfo.EC2State = string(instance.State.Name)
+			}
+			if nodeInfo.InstanceType == "" {
+				nodeInfo.InstanceType = string(instance.InstanceType)
+			}
+			nodeInfo.Lifecycle = instanceLifecycle(instance)
+			nodeInfo.Subnet = aws.ToString(instance.SubnetId)
+			nodeInfo.License = instanceLicense(instance)
+			nodeInfo.Tenancy = instanceTenancy(instance)
+			nodeInfo.ASG = getGroupFromTags(instance.Tags, groupTagKeys)
+			nodeInfo.ASGName = instanceASG(instance)
+			if capacity, exists := asgMap[nodeInfo.ASGName]; exists {
+				nodeInfo.ASGCapacity = capacity.String()
+				nodeInfo.ASGAtMax = capacity.AtMax()
+			}
+			nodeInfo.Links = buildConsoleLinks(nodeRegion(node, awsRegion), nodeInfo.InstanceID, nodeInfo.ASGName)
+		} else if instanceMap != nil && strings.HasPrefix(nodeInfo.InstanceID, "i-") {
+			// Terminated instances disappear from DescribeInstances after about an hour
+			nodeInfo.EC2State = "not found"
+		}
+	}
+	return nodeInfo
+}
+
+func calculateNodeResources(nodes []v1.Node, pods []v1.Pod) map[string]*NodeInfo {
 	nodeResources := make(map[string]*NodeInfo)
-	for _, node := range nodes.Items {
+	for _, node := range nodes {
 		nodeResources[node.Name] = &NodeInfo{
 			CPUCapacity:  node.Status.Allocatable.Cpu(),
 			CPURequested: resource.NewQuantity(0, resource.DecimalSI),
+			CPULimits:    resource.NewQuantity(0, resource.DecimalSI),
 			MemCapacity:  node.Status.Allocatable.Memory(),
 			MemRequested: resource.NewQuantity(0, resource.BinarySI),
+			MemLimits:    resource.NewQuantity(0, resource.BinarySI),
+			EphCapacity:  node.Status.Allocatable.StorageEphemeral(),
+			EphRequested: resource.NewQuantity(0, resource.BinarySI),
 			PodCount:     0,
 		}
+		if name, capacity := nodeAccelerator(node); name != "" {
+			nodeResources[node.Name].GPUResource = name
+			nodeResources[node.Name].GPUCapacity = capacity
+		}
 	}
 
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
+		// Completed pods no longer hold their requests on the node
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
 		if nodeInfo, exists := nodeResources[pod.Spec.NodeName]; exists {
-			nodeInfo.PodCount++
-			for _, container := range pod.Spec.Containers {
-				if cpu := container.Resources.Requests.Cpu(); cpu != nil {
-					nodeInfo.CPURequested.Add(*cpu)
-				}
-				if mem := container.Resources.Requests.Memory(); mem != nil {
-					nodeInfo.MemRequested.Add(*mem)
-				}
+			// Static pods are host-level, count them apart from workload pods
+			if isMirrorPod(pod) {
+				nodeInfo.StaticPods = append(nodeInfo.StaticPods, pod.Namespace+"/"+pod.Name)
+			} else {
+				nodeInfo.PodCount++
 			}
+			requests := podRequests(pod)
+			nodeInfo.CPURequested.Add(*requests.Cpu())
+			nodeInfo.MemRequested.Add(*requests.Memory())
+			nodeInfo.EphRequested.Add(*requests.StorageEphemeral())
+			if nodeInfo.GPUResource != "" {
+				gpus := requests[v1.ResourceName(nodeInfo.GPUResource)]
+				nodeInfo.GPURequested += gpus.Value()
+			}
+			limits := podLimits(pod)
+			nodeInfo.CPULimits.Add(*limits.Cpu())
+			nodeInfo.MemLimits.Add(*limits.Memory())
 		}
 	}
 
-	// Get EC2 instances and ASG info only for wide format
-	var instanceMap map[string]types.Instance
-	var asgMap map[string]string
-	if outputFormat == "wide" {
-		var err error
-		instanceMap, err = getEC2Instances(ec2Client)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
-			os.Exit(1)
+	return nodeResources
+}
+
+// describeInstancesChunkSize is the maximum number of values in a DescribeInstances filter
+const describeInstancesChunkSize = 200
+
+// getEC2Instances describes only the given instances. The instance-id filter is used
+// rather than InstanceIds so that already terminated and purged instances don't fail the call.
+func getEC2Instances(ctx context.Context, client *ec2.Client, instanceIDs []string) (map[string]types.Instance, error) {
+	instanceMap := make(map[string]types.Instance)
+	for start := 0; start < len(instanceIDs); start += describeInstancesChunkSize {
+		end := start + describeInstancesChunkSize
+		if end > len(instanceIDs) {
+			end = len(instanceIDs)
 		}
-		
-		asgMap, err = getASGCapacities(asgClient)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting ASG capacities: %v\n", err)
-			os.Exit(1)
+		paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+			Filters: []types.Filter{{Name: aws.String("instance-id"), Values: instanceIDs[start:end]}},
+		})
+		for paginator.HasMorePages() {
+			result, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, classifyAWSError(err)
+			}
+			for _, reservation := range result.Reservations {
+				for _, instance := range reservation.Instances {
+					if instance.InstanceId != nil {
+						instanceMap[*instance.InstanceId] = instance
+					}
+				}
+			}
 		}
 	}
+	return instanceMap, nil
+}
 
-	// Print results
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	if outputFormat == "wide" {
-		fmt.Fprintln(w, "NAME\tSTATUS\tAGE\tVERSION\tINSTANCE-ID\tINSTANCE-TYPE\tTAINTS\tASG\tASG-CAPACITY")
-	} else if outputFormat == "top" {
-		fmt.Fprintln(w, "NAME\tPODS\tCPU-CAP\tCPU-REQ\tCPU-FREE%\tMEM-CAP\tMEM-REQ\tMEM-FREE%")
-	} else {
-		fmt.Fprintln(w, "NAME\tSTATUS\tAGE\tVERSION\tINSTANCE-ID\tINSTANCE-TYPE\tTAINTS")
+// nodeInstanceIDs returns the EC2 instance IDs of the nodes, from their providerIDs
+func nodeInstanceIDs(nodes []v1.Node) []string {
+	var ids []string
+	for _, node := range nodes {
+		if id := getInstanceID(node); strings.HasPrefix(id, "i-") {
+			ids = append(ids, id)
+		}
 	}
+	return ids
+}
 
-	for _, node := range nodes.Items {
-		nodeInfo := NodeInfo{
-			Name:    node.Name,
-			Status:  getNodeStatus(node),
-			Age:     getNodeAge(node),
-			Version: node.Status.NodeInfo.KubeletVersion,
-			Taints:  getNodeTaints(node),
+// nodeInstanceIDsByRegion groups the nodes' instance IDs by the region of each node, see nodeRegion
+func nodeInstanceIDsByRegion(nodes []v1.Node, fallback string) map[string][]string {
+	byRegion := make(map[string][]string)
+	for _, node := range nodes {
+		if id := getInstanceID(node); strings.HasPrefix(id, "i-") {
+			region := nodeRegion(node, fallback)
+			byRegion[region] = append(byRegion[region], id)
 		}
+	}
+	return byRegion
+}
 
-		// Copy resource info
-		if resInfo, exists := nodeResources[node.Name]; exists {
-			nodeInfo.CPUCapacity = resInfo.CPUCapacity
-			nodeInfo.CPURequested = resInfo.CPURequested
-			nodeInfo.MemCapacity = resInfo.MemCapacity
-			nodeInfo.MemRequested = resInfo.MemRequested
-			nodeInfo.PodCount = resInfo.PodCount
+// nodeRegions lists the fallback region and the other regions the nodes' instances live in
+func nodeRegions(nodes []v1.Node, fallback string) []string {
+	regions := []string{fallback}
+	for region := range nodeInstanceIDsByRegion(nodes, fallback) {
+		if region != fallback {
+			regions = append(regions, region)
 		}
+	}
+	sort.Strings(regions[1:])
+	return regions
+}
 
-		// Get instance info from Kubernetes
-		nodeInfo.InstanceID = getInstanceID(node)
-		nodeInfo.InstanceType = getInstanceType(node)
-		
-		// Get ASG info from AWS (only if we have AWS access and instance ID)
-		if nodeInfo.InstanceID != "" {
-			if instance, exists := instanceMap[nodeInfo.InstanceID]; exists {
-				nodeInfo.ASG = getASGFromTags(instance.Tags)
-				if nodeInfo.ASG != "" {
-					if capacity, exists := asgMap[nodeInfo.ASG]; exists {
-						nodeInfo.ASGCapacity = capacity
-					}
-				}
-			}
-		}
+// regionalConfig returns a copy of the AWS config for another region, for the clients that
+// take their region from the config rather than from client options
+func regionalConfig(cfg aws.Config, region string) aws.Config {
+	regional := cfg.Copy()
+	regional.Region = region
+	return regional
+}
 
-		if outputFormat == "wide" {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-				nodeInfo.Name, nodeInfo.Status, nodeInfo.Age,
-				nodeInfo.Version, nodeInfo.InstanceID, nodeInfo.InstanceType, nodeInfo.Taints, nodeInfo.ASG, nodeInfo.ASGCapacity)
-		} else if outputFormat == "top" {
-			cpuFree := calculateFreePercentage(nodeInfo.CPUCapacity, nodeInfo.CPURequested)
-			memFree := calculateFreePercentage(nodeInfo.MemCapacity, nodeInfo.MemRequested)
-			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%.1f%%\t%s\t%s\t%.1f%%\n",
-				nodeInfo.Name, nodeInfo.PodCount,
-				formatResource(nodeInfo.CPUCapacity), formatResource(nodeInfo.CPURequested), cpuFree,
-				formatMemory(nodeInfo.MemCapacity), formatMemory(nodeInfo.MemRequested), memFree)
-		} else {
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-				nodeInfo.Name, nodeInfo.Status, nodeInfo.Age,
-				nodeInfo.Version, nodeInfo.InstanceID, nodeInfo.InstanceType, nodeInfo.Taints)
+// getNodeInstances describes the nodes' instances in the region each one lives in, so nodes of
+// clusters spanning regions resolve even though the AWS config has a single region
+func getNodeInstances(ctx context.Context, cfg aws.Config, nodes []v1.Node) (map[string]types.Instance, error) {
+	instanceMap := make(map[string]types.Instance)
+	for region, ids := range nodeInstanceIDsByRegion(nodes, cfg.Region) {
+		client := ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+		var instances map[string]types.Instance
+		err := cachedJSON(cacheKey(cfg, "instances", region, ids), opts.cacheTTL, &instances, func() (err error) {
+			instances, err = getEC2Instances(ctx, client, ids)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("in %s: %w", region, err)
+		}
+		for id, instance := range instances {
+			instanceMap[id] = instance
 		}
 	}
+	return instanceMap, nil
+}
 
-	w.Flush()
+// getNodeASGCapacities reads the Auto Scaling Groups of every region the nodes live in
+func getNodeASGCapacities(ctx context.Context, cfg aws.Config, nodes []v1.Node) (map[string]ASGCapacity, error) {
+	asgMap := make(map[string]ASGCapacity)
+	for region := range nodeInstanceIDsByRegion(nodes, cfg.Region) {
+		client := autoscaling.NewFromConfig(cfg, func(o *autoscaling.Options) { o.Region = region })
+		var capacities map[string]ASGCapacity
+		err := cachedJSON(cacheKey(cfg, "asg-capacities", region, nil), opts.cacheTTL, &capacities, func() (err error) {
+			capacities, err = getASGCapacities(ctx, client)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("in %s: %w", region, err)
+		}
+		for name, capacity := range capacities {
+			asgMap[name] = capacity
+		}
+	}
+	return asgMap, nil
 }
 
-func getKubeConfig() (*rest.Config, error) {
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
-	return kubeConfig.ClientConfig()
+// instanceLifecycle returns spot, scheduled or capacity-block, or on-demand when EC2 reports none
+func instanceLifecycle(instance types.Instance) string {
+	if instance.InstanceLifecycle == "" {
+		return "on-demand"
+	}
+	return string(instance.InstanceLifecycle)
 }
 
-func getEC2Instances(client *ec2.Client) (map[string]types.Instance, error) {
-	result, err := client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{})
-	if err != nil {
-		return nil, err
+// formatInstanceID appends the instance state when the instance is not running, so its blank
+// or stale AWS columns aren't mistaken for missing permissions
+func formatInstanceID(n NodeInfo) string {
+	if n.EC2State != "" {
+		return fmt.Sprintf("%s (%s)", n.InstanceID, n.EC2State)
 	}
+	return n.InstanceID
+}
 
-	instanceMap := make(map[string]types.Instance)
-	for _, reservation := range result.Reservations {
-		for _, instance := range reservation.Instances {
-			if instance.InstanceId != nil {
-				instanceMap[*instance.InstanceId] = instance
-			}
+// parseNodeArg accepts a node as NAME or, like kubectl, as node/NAME (also nodes/ and no/)
+func parseNodeArg(arg string) (string, error) {
+	resource, name, found := strings.Cut(arg, "/")
+	if !found {
+		return arg, nil
+	}
+	switch strings.ToLower(resource) {
+	case "node", "nodes", "no":
+		if name == "" {
+			return "", fmt.Errorf("missing node name in '%s'", arg)
 		}
+		return name, nil
 	}
-	return instanceMap, nil
+	return "", fmt.Errorf("unsupported resource type '%s' in '%s', only nodes are supported", resource, arg)
 }
 
 func getInstanceType(node v1.Node) string {
@@ -286,6 +1335,21 @@ func getInstanceID(node v1.Node) string {
 	return ""
 }
 
+// getNodeZone returns the node's Availability Zone from its topology label or providerID
+func getNodeZone(node v1.Node) string {
+	if zone := node.Labels["topology.kubernetes.io/zone"]; zone != "" {
+		return zone
+	}
+	if zone := node.Labels["failure-domain.beta.kubernetes.io/zone"]; zone != "" {
+		return zone
+	}
+	parts := strings.Split(node.Spec.ProviderID, "/")
+	if len(parts) >= 2 {
+		return parts[len(parts)-2]
+	}
+	return ""
+}
+
 func getNodeStatus(node v1.Node) string {
 	for _, condition := range node.Status.Conditions {
 		if condition.Type == v1.NodeReady {
@@ -299,7 +1363,11 @@ func getNodeStatus(node v1.Node) string {
 }
 
 func getNodeAge(node v1.Node) string {
-	age := time.Since(node.CreationTimestamp.Time)
+	return formatAge(time.Since(node.CreationTimestamp.Time))
+}
+
+// formatAge shortens a duration to whole days, hours or minutes, e.g. "12d"
+func formatAge(age time.Duration) string {
 	days := int(age.Hours() / 24)
 	if days > 0 {
 		return fmt.Sprintf("%dd", days)
@@ -319,15 +1387,119 @@ func getNodeTaints(node v1.Node) string {
 	return strings.Join(taintKeys, ",")
 }
 
-func getASGFromTags(tags []types.Tag) string {
-	for _, tag := range tags {
-		if tag.Key != nil && *tag.Key == "aws:autoscaling:groupName" && tag.Value != nil {
-			return *tag.Value
+// podRequests returns the effective requests of a pod the way the scheduler computes them:
+// app containers plus sidecars (restartable init containers), or the largest regular init
+// container if that is higher. Pod-level requests, when set, take precedence. The RuntimeClass
+// overhead (e.g. of Kata or gVisor sandboxes) is added on top.
+func podRequests(pod v1.Pod) v1.ResourceList {
+	requests := podResources(pod, func(r v1.ResourceRequirements) v1.ResourceList { return r.Requests })
+	addResources(requests, pod.Spec.Overhead)
+	return requests
+}
+
+// podLimits returns the effective limits of a pod the way kubectl describe node sums them:
+// like podRequests, with the overhead only added to resources that have a limit. Containers
+// without a limit add nothing, so the total understates what the pod may use.
+func podLimits(pod v1.Pod) v1.ResourceList {
+	limits := podResources(pod, func(r v1.ResourceRequirements) v1.ResourceList { return r.Limits })
+	for name, quantity := range pod.Spec.Overhead {
+		if limit, exists := limits[name]; exists {
+			limit.Add(quantity)
+			limits[name] = limit
+		}
+	}
+	return limits
+}
+
+// podResources combines the requests or limits picked from the pod's containers
+func podResources(pod v1.Pod, pick func(v1.ResourceRequirements) v1.ResourceList) v1.ResourceList {
+	total := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(total, pick(container.Resources))
+	}
+
+	sidecars := v1.ResourceList{}
+	for _, container := range pod.Spec.InitContainers {
+		if container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways {
+			addResources(total, pick(container.Resources))
+			addResources(sidecars, pick(container.Resources))
+			continue
+		}
+		// A regular init container runs alongside the sidecars started before it
+		initResources := v1.ResourceList{}
+		addResources(initResources, sidecars)
+		addResources(initResources, pick(container.Resources))
+		for name, quantity := range initResources {
+			if current, exists := total[name]; !exists || quantity.Cmp(current) > 0 {
+				total[name] = quantity
+			}
+		}
+	}
+
+	if pod.Spec.Resources != nil {
+		for name, quantity := range pick(*pod.Spec.Resources) {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+	return total
+}
+
+func addResources(total, add v1.ResourceList) {
+	for name, quantity := range add {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+func isMirrorPod(pod v1.Pod) bool {
+	_, exists := pod.Annotations[v1.MirrorPodAnnotationKey]
+	return exists
+}
+
+// getGroupFromTags returns the value of the first tag key found, honoring the order of keys
+func getGroupFromTags(tags []types.Tag, keys []string) string {
+	for _, key := range keys {
+		for _, tag := range tags {
+			if tag.Key != nil && *tag.Key == key && tag.Value != nil {
+				return *tag.Value
+			}
 		}
 	}
 	return ""
 }
 
+// instanceASG returns the Auto Scaling Group that launched the instance, which --group-tag may
+// label differently
+func instanceASG(instance types.Instance) string {
+	return getGroupFromTags(instance.Tags, []string{asgNameTag})
+}
+
+// isOversized flags large nodes where both CPU and memory requests are far below capacity, taking
+// the higher of the current requests and the node's recorded peak
+func isOversized(nodeInfo NodeInfo, peak peakRequests, thresholdPercent float64, minCPU int64) bool {
+	if nodeInfo.CPUCapacity == nil || nodeInfo.CPUCapacity.Value() < minCPU {
+		return false
+	}
+	cpuRequested, memRequested := nodeInfo.CPURequested, nodeInfo.MemRequested
+	if cpuRequested == nil || peak.CPURequested > cpuRequested.MilliValue() {
+		cpuRequested = resource.NewMilliQuantity(peak.CPURequested, resource.DecimalSI)
+	}
+	if memRequested == nil || peak.MemRequested > memRequested.Value() {
+		memRequested = resource.NewQuantity(peak.MemRequested, resource.BinarySI)
+	}
+	cpuPercent := 100 - calculateFreePercentage(nodeInfo.CPUCapacity, cpuRequested)
+	memPercent := 100 - calculateFreePercentage(nodeInfo.MemCapacity, memRequested)
+	return cpuPercent < thresholdPercent && memPercent < thresholdPercent
+}
+
+func formatFlag(b bool) string {
+	if b {
+		return "yes"
+	}
+	return ""
+}
+
 func calculateFreePercentage(capacity, requested *resource.Quantity) float64 {
 	if capacity == nil || capacity.IsZero() {
 		return 0
@@ -344,6 +1516,9 @@ func formatResource(q *resource.Quantity) string {
 	if q == nil {
 		return "0"
 	}
+	if opts.plainNumbers {
+		return fmt.Sprintf("%d", q.MilliValue())
+	}
 	return q.String()
 }
 
@@ -352,7 +1527,13 @@ func formatMemory(q *resource.Quantity) string {
 		return "0"
 	}
 
-	bytes := q.Value()
+	return formatBytes(q.Value())
+}
+
+func formatBytes(bytes int64) string {
+	if opts.plainNumbers {
+		return fmt.Sprintf("%d", bytes)
+	}
 
 	// Convert to largest unit >= 1
 	if bytes >= 1024*1024*1024*1024 { // Ti
@@ -371,26 +1552,93 @@ func formatMemory(q *resource.Quantity) string {
 	return fmt.Sprintf("%d", bytes)
 }
 
-func getASGCapacities(client *autoscaling.Client) (map[string]string, error) {
-	result, err := client.DescribeAutoScalingGroups(context.TODO(), &autoscaling.DescribeAutoScalingGroupsInput{})
+// formatUsage formats an optional metrics-server value, "-" when it is unavailable
+func formatUsage(q *resource.Quantity, format func(*resource.Quantity) string) string {
+	if q == nil {
+		return "-"
+	}
+	return format(q)
+}
+
+// overcommitRatio is the larger of the CPU and memory limits relative to allocatable; above 1
+// the node cannot give every pod its limits at once, risking CPU throttling or OOM kills
+func overcommitRatio(n NodeInfo) float64 {
+	ratio := 0.0
+	for _, pair := range [][2]*resource.Quantity{{n.CPULimits, n.CPUCapacity}, {n.MemLimits, n.MemCapacity}} {
+		limits, capacity := pair[0], pair[1]
+		if limits == nil || capacity == nil || capacity.IsZero() {
+			continue
+		}
+		if r := float64(limits.MilliValue()) / float64(capacity.MilliValue()); r > ratio {
+			ratio = r
+		}
+	}
+	return ratio
+}
+
+func formatRatio(r float64) string {
+	if opts.plainNumbers {
+		return fmt.Sprintf("%.2f", r)
+	}
+	return fmt.Sprintf("%.2fx", r)
+}
+
+func formatPercent(p float64) string {
+	if opts.plainNumbers {
+		return fmt.Sprintf("%.1f", p)
+	}
+	return fmt.Sprintf("%.1f%%", p)
+}
+
+func getASGCapacities(ctx context.Context, client *autoscaling.Client) (map[string]ASGCapacity, error) {
+	groups, err := getAutoScalingGroups(ctx, client)
 	if err != nil {
 		return nil, err
 	}
 
-	asgMap := make(map[string]string)
-	for _, asg := range result.AutoScalingGroups {
+	asgMap := make(map[string]ASGCapacity)
+	for _, asg := range groups {
 		if asg.AutoScalingGroupName != nil {
-			capacity := fmt.Sprintf("%d/%d/%d", 
-				*asg.MinSize, 
-				*asg.MaxSize, 
-				*asg.DesiredCapacity)
-			asgMap[*asg.AutoScalingGroupName] = capacity
+			asgMap[*asg.AutoScalingGroupName] = ASGCapacity{
+				Min:     *asg.MinSize,
+				Max:     *asg.MaxSize,
+				Desired: *asg.DesiredCapacity,
+			}
 		}
 	}
 
 	return asgMap, nil
 }
 
+// runOpen is --open and --open-asg as subcommands, taking the node kubectl-style (node/NAME)
+func runOpen(command string, args []string) {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s NODE_NAME\n\n", os.Args[0], command)
+		if command == "open-asg" {
+			fmt.Fprintf(os.Stderr, "Open the AWS console page of the Auto Scaling Group that launched the node.\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Open the AWS console page of the node's EC2 instance.\n")
+		}
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	nodeName, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if command == "open-asg" {
+		openNodeASGInBrowser(nodeName)
+	} else {
+		openNodeInBrowser(nodeName)
+	}
+}
+
 func openNodeInBrowser(nodeName string) {
 	// Get Kubernetes client
 	config, err := getKubeConfig()
@@ -420,24 +1668,22 @@ func openNodeInBrowser(nodeName string) {
 	}
 
 	// Initialize AWS client to get region
-	awsConfig, err := awsconfig.LoadDefaultConfig(context.TODO())
+	awsConfig, err := loadAWSConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Build AWS console URL
-	region := awsConfig.Region
-	url := fmt.Sprintf("https://%s.console.aws.amazon.com/ec2/home?region=%s#InstanceDetails:instanceId=%s", 
-		region, region, instanceID)
+	// Build AWS console URL in the instance's own region
+	url := ec2ConsoleURL(nodeRegion(*node, awsConfig.Region), instanceID)
+
+	infof("Opening AWS console for node '%s' (instance: %s)...\n", nodeName, instanceID)
 
-	fmt.Printf("Opening AWS console for node '%s' (instance: %s)...\n", nodeName, instanceID)
-	
 	// Open browser
 	err = openURL(url)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
-		fmt.Printf("Please open this URL manually: %s\n", url)
+		fmt.Fprintf(os.Stderr, "Please open this URL manually: %s\n", url)
 		os.Exit(1)
 	}
 }
@@ -470,17 +1716,18 @@ func openNodeASGInBrowser(nodeName string) {
 		os.Exit(1)
 	}
 
-	// Initialize AWS clients
-	awsConfig, err := awsconfig.LoadDefaultConfig(context.TODO())
+	// Initialize AWS clients in the instance's own region
+	awsConfig, err := loadAWSConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
 		os.Exit(1)
 	}
+	awsConfig.Region = nodeRegion(*node, awsConfig.Region)
 
 	ec2Client := ec2.NewFromConfig(awsConfig)
 
 	// Get EC2 instance to find ASG
-	instanceMap, err := getEC2Instances(ec2Client)
+	instanceMap, err := getEC2Instances(context.TODO(), ec2Client, []string{instanceID})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
 		os.Exit(1)
@@ -492,25 +1739,23 @@ func openNodeASGInBrowser(nodeName string) {
 		os.Exit(1)
 	}
 
-	// Get ASG name from instance tags
-	asgName := getASGFromTags(instance.Tags)
+	// Only the ASG that launched the instance has a console page; --group-tag labels may not
+	asgName := instanceASG(instance)
 	if asgName == "" {
-		fmt.Fprintf(os.Stderr, "Error: Could not find Auto Scaling Group for node '%s'\n", nodeName)
+		fmt.Fprintf(os.Stderr, "Error: node '%s' is not in an Auto Scaling Group\n", nodeName)
 		os.Exit(1)
 	}
 
 	// Build AWS console URL for ASG
-	region := awsConfig.Region
-	url := fmt.Sprintf("https://%s.console.aws.amazon.com/ec2/home?region=%s#AutoScalingGroupDetails:id=%s", 
-		region, region, asgName)
+	url := asgConsoleURL(awsConfig.Region, asgName)
+
+	infof("Opening ASG console for node '%s' (ASG: %s)...\n", nodeName, asgName)
 
-	fmt.Printf("Opening ASG console for node '%s' (ASG: %s)...\n", nodeName, asgName)
-	
 	// Open browser
 	err = openURL(url)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error opening browser: %v\n", err)
-		fmt.Printf("Please open this URL manually: %s\n", url)
+		fmt.Fprintf(os.Stderr, "Please open this URL manually: %s\n", url)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodResources(t *testing.T) {
+	always := v1.ContainerRestartPolicyAlways
+	tests := []struct {
+		name       string
+		spec       v1.PodSpec
+		wantCPU    string
+		wantMemory string
+	}{
+		{
+			name: "containers are summed",
+			spec: v1.PodSpec{Containers: []v1.Container{
+				{Resources: requestsOf("100m", "128Mi")},
+				{Resources: requestsOf("250m", "64Mi")},
+			}},
+			wantCPU:    "350m",
+			wantMemory: "192Mi",
+		},
+		{
+			name: "init container larger than the containers wins",
+			spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Resources: requestsOf("1", "64Mi")}},
+				Containers:     []v1.Container{{Resources: requestsOf("200m", "256Mi")}},
+			},
+			wantCPU:    "1",
+			wantMemory: "256Mi",
+		},
+		{
+			name: "sidecars add to the containers and to later init containers",
+			spec: v1.PodSpec{
+				InitContainers: []v1.Container{
+					{Resources: requestsOf("100m", "100Mi"), RestartPolicy: &always},
+					{Resources: requestsOf("500m", "50Mi")},
+				},
+				Containers: []v1.Container{{Resources: requestsOf("200m", "200Mi")}},
+			},
+			wantCPU:    "600m",
+			wantMemory: "300Mi",
+		},
+		{
+			name: "pod-level resources override the containers",
+			spec: v1.PodSpec{
+				Containers: []v1.Container{{Resources: requestsOf("100m", "100Mi")}},
+				Resources:  &v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")}},
+			},
+			wantCPU:    "2",
+			wantMemory: "100Mi",
+		},
+		{
+			name:       "no requests",
+			spec:       v1.PodSpec{Containers: []v1.Container{{}}},
+			wantCPU:    "0",
+			wantMemory: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := podResources(v1.Pod{Spec: tt.spec}, func(r v1.ResourceRequirements) v1.ResourceList { return r.Requests })
+			if cpu := got.Cpu(); cpu.Cmp(resource.MustParse(tt.wantCPU)) != 0 {
+				t.Errorf("CPU = %s, want %s", cpu, tt.wantCPU)
+			}
+			if memory := got.Memory(); memory.Cmp(resource.MustParse(tt.wantMemory)) != 0 {
+				t.Errorf("memory = %s, want %s", memory, tt.wantMemory)
+			}
+		})
+	}
+}
+
+func TestASGCapacity(t *testing.T) {
+	tests := []struct {
+		capacity  ASGCapacity
+		wantAtMax bool
+		want      string
+	}{
+		{ASGCapacity{Min: 1, Max: 10, Desired: 3}, false, "1/10/3"},
+		{ASGCapacity{Min: 1, Max: 10, Desired: 10}, true, "1/10/10!"},
+		{ASGCapacity{Min: 0, Max: 0, Desired: 0}, true, "0/0/0!"},
+		{ASGCapacity{Min: 2, Max: 5, Desired: 6}, true, "2/5/6!"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.capacity.AtMax(); got != tt.wantAtMax {
+			t.Errorf("%+v.AtMax() = %v, want %v", tt.capacity, got, tt.wantAtMax)
+		}
+		if got := tt.capacity.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.capacity, got, tt.want)
+		}
+	}
+}
+
+func TestNodeInfoASG(t *testing.T) {
+	tag := func(key, value string) types.Tag { return types.Tag{Key: aws.String(key), Value: aws.String(value)} }
+	asgMap := map[string]ASGCapacity{
+		"eks-workers-1a2b": {Min: 1, Max: 3, Desired: 3},
+		"workers":          {Min: 0, Max: 9, Desired: 1}, // an unrelated ASG named like the label
+		"batch":            {Min: 1, Max: 2, Desired: 1},
+	}
+	groupTagKeys := []string{"eks:nodegroup-name", asgNameTag}
+
+	tests := []struct {
+		name         string
+		tags         []types.Tag
+		wantGroup    string
+		wantASG      string
+		wantCapacity string
+	}{
+		{
+			name:         "group label differs from the ASG",
+			tags:         []types.Tag{tag("eks:nodegroup-name", "workers"), tag(asgNameTag, "eks-workers-1a2b")},
+			wantGroup:    "workers",
+			wantASG:      "eks-workers-1a2b",
+			wantCapacity: "1/3/3!",
+		},
+		{
+			name:      "group label without an ASG",
+			tags:      []types.Tag{tag("eks:nodegroup-name", "workers")},
+			wantGroup: "workers",
+		},
+		{
+			name:         "ASG only",
+			tags:         []types.Tag{tag(asgNameTag, "batch")},
+			wantGroup:    "batch",
+			wantASG:      "batch",
+			wantCapacity: "1/2/1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node"},
+				Spec:       v1.NodeSpec{ProviderID: "aws:///us-west-2a/i-0123"},
+			}
+			instanceMap := map[string]types.Instance{"i-0123": {InstanceId: aws.String("i-0123"), Tags: tt.tags}}
+			n := newNodeInfo(node, nil, instanceMap, asgMap, "us-west-2", groupTagKeys)
+			if n.ASG != tt.wantGroup || n.ASGName != tt.wantASG || n.ASGCapacity != tt.wantCapacity {
+				t.Errorf("ASG, ASGName, ASGCapacity = %q, %q, %q, want %q, %q, %q",
+					n.ASG, n.ASGName, n.ASGCapacity, tt.wantGroup, tt.wantASG, tt.wantCapacity)
+			}
+			if n.Links == nil {
+				t.Fatal("Links = nil, want console links")
+			}
+			if wantLink := (tt.wantASG != ""); (n.Links.ASG != "") != wantLink {
+				t.Errorf("Links.ASG = %q, want a link: %v", n.Links.ASG, wantLink)
+			} else if wantLink && n.Links.ASG != asgConsoleURL("us-west-2", tt.wantASG) {
+				t.Errorf("Links.ASG = %q, want the console page of %s", n.Links.ASG, tt.wantASG)
+			}
+		})
+	}
+}
+
+func TestIsOversized(t *testing.T) {
+	node := NodeInfo{
+		CPUCapacity: quantity("16"), CPURequested: quantity("1"),
+		MemCapacity: quantity("64Gi"), MemRequested: quantity("4Gi"),
+	}
+	tests := []struct {
+		name string
+		node NodeInfo
+		peak peakRequests
+		want bool
+	}{
+		{"low requests without history", node, peakRequests{}, true},
+		{"low peak", node, peakRequests{CPURequested: 2000, MemRequested: 8 << 30}, true},
+		{"busy CPU peak", node, peakRequests{CPURequested: 12000}, false},
+		{"busy memory peak", node, peakRequests{MemRequested: 48 << 30}, false},
+		{"peak below current requests", NodeInfo{
+			CPUCapacity: quantity("16"), CPURequested: quantity("12"),
+			MemCapacity: quantity("64Gi"), MemRequested: quantity("4Gi"),
+		}, peakRequests{CPURequested: 1000}, false},
+		{"small node", NodeInfo{
+			CPUCapacity: quantity("2"), CPURequested: quantity("100m"),
+			MemCapacity: quantity("8Gi"), MemRequested: quantity("128Mi"),
+		}, peakRequests{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isOversized(tt.node, tt.peak, 20, 8); got != tt.want {
+				t.Errorf("isOversized() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// nodeFilter selects nodes by their AWS-side attributes
+type nodeFilter struct {
+	asg          string
+	instanceType string
+}
+
+func (f nodeFilter) empty() bool {
+	return f.asg == "" && f.instanceType == ""
+}
+
+func runNodeEdit(command string, args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	var filter nodeFilter
+	fs.StringVar(&filter.asg, "asg", "", "Only edit nodes in this Auto Scaling Group (or node group)")
+	fs.StringVar(&filter.instanceType, "instance-type", "", "Only edit nodes of this EC2 instance type")
+	overwrite := fs.Bool("overwrite", false, "Allow changing existing label values or taints with the same key and effect")
+	dryRun := fs.Bool("dry-run", false, "Print the nodes that would be changed without changing them")
+	fs.Usage = func() {
+		if command == "label" {
+			fmt.Fprintf(os.Stderr, "Usage: %s label --asg NAME [--instance-type TYPE] KEY=VALUE|KEY- ...\n\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "Add, change (with --overwrite) or remove labels on all nodes matching the AWS filters.\n\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Usage: %s taint --asg NAME [--instance-type TYPE] KEY[=VALUE]:EFFECT|KEY[:EFFECT]- ...\n\n", os.Args[0])
+			fmt.Fprintf(os.Stderr, "Add, change (with --overwrite) or remove taints on all nodes matching the AWS filters.\n\n")
+		}
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if filter.empty() {
+		fmt.Fprintf(os.Stderr, "Error: %s requires --asg or --instance-type\n", command)
+		os.Exit(1)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	// Validate all changes before touching any node
+	var labelChanges map[string]*string
+	var taintChanges []taintChange
+	var err error
+	if command == "label" {
+		labelChanges, err = parseLabelChanges(fs.Args())
+	} else {
+		taintChanges, err = parseTaintChanges(fs.Args())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := findNodesByAWSFilter(clientset, filter, groupTagKeys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(nodes) == 0 {
+		fmt.Println("No nodes match the given filters")
+		return
+	}
+
+	failed := 0
+	for _, node := range nodes {
+		if *dryRun {
+			fmt.Printf("node/%s would be %sed (dry run)\n", node.Name, command)
+			continue
+		}
+		if command == "label" {
+			err = applyLabelChanges(clientset, node, labelChanges, *overwrite)
+		} else {
+			err = applyTaintChanges(clientset, node.Name, taintChanges, *overwrite)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: node/%s: %v\n", node.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("node/%s %sed\n", node.Name, command)
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// findNodesByAWSFilter returns the nodes whose EC2 instance matches the filter
+func findNodesByAWSFilter(clientset *kubernetes.Clientset, filter nodeFilter, groupTagKeys []string) ([]v1.Node, error) {
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		return nil, fmt.Errorf("getting EC2 instances: %w", err)
+	}
+
+	var matched []v1.Node
+	for _, node := range nodes.Items {
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists {
+			continue
+		}
+		if filter.asg != "" && getGroupFromTags(instance.Tags, groupTagKeys) != filter.asg {
+			continue
+		}
+		if filter.instanceType != "" && string(instance.InstanceType) != filter.instanceType {
+			continue
+		}
+		matched = append(matched, node)
+	}
+	return matched, nil
+}
+
+// parseLabelChanges parses KEY=VALUE (set) and KEY- (remove) arguments.
+// Removals map to a nil value.
+func parseLabelChanges(args []string) (map[string]*string, error) {
+	changes := make(map[string]*string)
+	for _, arg := range args {
+		if strings.HasSuffix(arg, "-") && !strings.Contains(arg, "=") {
+			changes[strings.TrimSuffix(arg, "-")] = nil
+			continue
+		}
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label '%s', expected KEY=VALUE or KEY-", arg)
+		}
+		changes[key] = &value
+	}
+	return changes, nil
+}
+
+func applyLabelChanges(clientset *kubernetes.Clientset, node v1.Node, changes map[string]*string, overwrite bool) error {
+	if !overwrite {
+		for key, value := range changes {
+			if current, exists := node.Labels[key]; exists && value != nil && current != *value {
+				return fmt.Errorf("label '%s' already has value '%s', use --overwrite to change it", key, current)
+			}
+		}
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": changes},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = clientset.CoreV1().Nodes().Patch(context.TODO(), node.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// taintChange adds Taint, or removes taints with its key (and effect, if set) when Remove is true
+type taintChange struct {
+	Taint  v1.Taint
+	Remove bool
+}
+
+// parseTaintChanges parses KEY[=VALUE]:EFFECT (add) and KEY[:EFFECT]- (remove) arguments
+func parseTaintChanges(args []string) ([]taintChange, error) {
+	var changes []taintChange
+	for _, arg := range args {
+		change := taintChange{}
+		spec := arg
+		if strings.HasSuffix(spec, "-") {
+			change.Remove = true
+			spec = strings.TrimSuffix(spec, "-")
+		}
+
+		keyValue, effect, hasEffect := strings.Cut(spec, ":")
+		key, value, _ := strings.Cut(keyValue, "=")
+		if key == "" || (!change.Remove && !hasEffect) {
+			return nil, fmt.Errorf("invalid taint '%s', expected KEY[=VALUE]:EFFECT or KEY[:EFFECT]-", arg)
+		}
+		if hasEffect {
+			switch v1.TaintEffect(effect) {
+			case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+			default:
+				return nil, fmt.Errorf("invalid taint effect '%s' in '%s'", effect, arg)
+			}
+		}
+		change.Taint = v1.Taint{Key: key, Value: value, Effect: v1.TaintEffect(effect)}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func applyTaintChanges(clientset *kubernetes.Clientset, nodeName string, changes []taintChange, overwrite bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		taints := node.Spec.Taints
+		for _, change := range changes {
+			var kept []v1.Taint
+			for _, taint := range taints {
+				sameKey := taint.Key == change.Taint.Key
+				sameEffect := change.Taint.Effect == "" || taint.Effect == change.Taint.Effect
+				if sameKey && sameEffect {
+					if !change.Remove && !overwrite && taint.Value != change.Taint.Value {
+						return fmt.Errorf("taint '%s:%s' already exists, use --overwrite to change it", taint.Key, taint.Effect)
+					}
+					continue
+				}
+				kept = append(kept, taint)
+			}
+			if !change.Remove {
+				kept = append(kept, change.Taint)
+			}
+			taints = kept
+		}
+
+		node.Spec.Taints = taints
+		_, err = clientset.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+		return err
+	})
+}
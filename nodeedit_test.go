@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseTaintChanges(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    taintChange
+		wantErr bool
+	}{
+		{arg: "dedicated=gpu:NoSchedule", want: taintChange{Taint: v1.Taint{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoSchedule}}},
+		{arg: "spot:PreferNoSchedule", want: taintChange{Taint: v1.Taint{Key: "spot", Effect: v1.TaintEffectPreferNoSchedule}}},
+		{arg: "maintenance=true:NoExecute", want: taintChange{Taint: v1.Taint{Key: "maintenance", Value: "true", Effect: v1.TaintEffectNoExecute}}},
+		{arg: "dedicated:NoSchedule-", want: taintChange{Taint: v1.Taint{Key: "dedicated", Effect: v1.TaintEffectNoSchedule}, Remove: true}},
+		{arg: "dedicated-", want: taintChange{Taint: v1.Taint{Key: "dedicated"}, Remove: true}},
+		{arg: "dedicated=gpu", wantErr: true},
+		{arg: ":NoSchedule", wantErr: true},
+		{arg: "dedicated=gpu:NoWhere", wantErr: true},
+		{arg: "-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseTaintChanges([]string{tt.arg})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTaintChanges(%q) = %+v, want an error", tt.arg, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTaintChanges(%q) failed: %v", tt.arg, err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("parseTaintChanges(%q) = %+v, want %+v", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v1 "k8s.io/api/core/v1"
+)
+
+// eksNodegroupLabel is set by EKS on the nodes of managed node groups
+const eksNodegroupLabel = "eks.amazonaws.com/nodegroup"
+
+// eksNodegroup is the part of EKS DescribeNodegroup shown for managed node groups. Only Name is
+// set when the node group could not be described.
+type eksNodegroup struct {
+	Name           string `json:"nodegroupName"`
+	Status         string `json:"status,omitempty"`
+	Version        string `json:"version,omitempty"`
+	ReleaseVersion string `json:"releaseVersion,omitempty"`
+	ScalingConfig  *struct {
+		MinSize     int32 `json:"minSize"`
+		MaxSize     int32 `json:"maxSize"`
+		DesiredSize int32 `json:"desiredSize"`
+	} `json:"scalingConfig,omitempty"`
+}
+
+// String formats the node group as its name followed by status, AMI release version and min/max/desired
+func (g *eksNodegroup) String() string {
+	if g == nil {
+		return ""
+	}
+	if g.Status == "" {
+		return g.Name
+	}
+	details := []string{g.Status, valueOrDash(g.ReleaseVersion)}
+	if g.ScalingConfig != nil {
+		details = append(details, fmt.Sprintf("%d/%d/%d", g.ScalingConfig.MinSize, g.ScalingConfig.MaxSize, g.ScalingConfig.DesiredSize))
+	}
+	return fmt.Sprintf("%s (%s)", g.Name, strings.Join(details, ", "))
+}
+
+func describeEKSNodegroup(ctx context.Context, client *awsJSONClient, clusterName, nodegroupName string) (*eksNodegroup, error) {
+	var output struct {
+		Nodegroup eksNodegroup `json:"nodegroup"`
+	}
+	path := "/clusters/" + url.PathEscape(clusterName) + "/node-groups/" + url.PathEscape(nodegroupName)
+	if err := client.get(ctx, "DescribeNodegroup", path, &output); err != nil {
+		return nil, err
+	}
+	return &output.Nodegroup, nil
+}
+
+// getEKSNodegroups describes the managed node groups the nodes belong to, by name
+func getEKSNodegroups(cfg aws.Config, clusterName string, nodes []v1.Node) (map[string]eksNodegroup, error) {
+	nodegroups := make(map[string]eksNodegroup)
+	client := newEKSClient(cfg)
+	for _, node := range nodes {
+		name := node.Labels[eksNodegroupLabel]
+		if name == "" {
+			continue
+		}
+		if _, exists := nodegroups[name]; exists {
+			continue
+		}
+		nodegroup, err := describeEKSNodegroup(context.TODO(), client, clusterName, name)
+		if err != nil {
+			return nodegroups, fmt.Errorf("describing node group '%s': %w", name, err)
+		}
+		nodegroups[name] = *nodegroup
+	}
+	return nodegroups, nil
+}
+
+// hasEKSNodegroups reports whether any node belongs to a managed node group
+func hasEKSNodegroups(nodes []v1.Node) bool {
+	for _, node := range nodes {
+		if node.Labels[eksNodegroupLabel] != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// runConcurrently runs the functions in parallel and waits for all of them, returning the
+// first error. The context they share is canceled on that error so the others stop early.
+func runConcurrently(ctx context.Context, fns ...func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for _, fn := range fns {
+		wg.Add(1)
+		go func(fn func(ctx context.Context) error) {
+			defer wg.Done()
+			if err := fn(ctx); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(fn)
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultNodeReadyTime is assumed for a replacement node when no node of the group has a known ready lag
+const defaultNodeReadyTime = 3 * time.Minute
+
+// amiFamily is an EKS-optimized AMI family, recognized by the AMI name
+type amiFamily struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Parameter is the SSM public parameter holding the recommended AMI ID; %s is the Kubernetes version
+	Parameter string
+}
+
+var amiFamilies = []amiFamily{
+	{"AL2023 x86_64", regexp.MustCompile(`^amazon-eks-node-al2023-x86_64-standard-`), "/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/standard/recommended/image_id"},
+	{"AL2023 arm64", regexp.MustCompile(`^amazon-eks-node-al2023-arm64-standard-`), "/aws/service/eks/optimized-ami/%s/amazon-linux-2023/arm64/standard/recommended/image_id"},
+	{"AL2023 NVIDIA", regexp.MustCompile(`^amazon-eks-node-al2023-x86_64-nvidia-`), "/aws/service/eks/optimized-ami/%s/amazon-linux-2023/x86_64/nvidia/recommended/image_id"},
+	{"AL2 arm64", regexp.MustCompile(`^amazon-eks-arm64-node-`), "/aws/service/eks/optimized-ami/%s/amazon-linux-2-arm64/recommended/image_id"},
+	{"AL2 GPU", regexp.MustCompile(`^amazon-eks-gpu-node-`), "/aws/service/eks/optimized-ami/%s/amazon-linux-2-gpu/recommended/image_id"},
+	{"AL2", regexp.MustCompile(`^amazon-eks-node-\d`), "/aws/service/eks/optimized-ami/%s/amazon-linux-2/recommended/image_id"},
+	{"Bottlerocket NVIDIA", regexp.MustCompile(`^bottlerocket-aws-k8s-[\d.]+-nvidia-x86_64-`), "/aws/service/bottlerocket/aws-k8s-%s-nvidia/x86_64/latest/image_id"},
+	{"Bottlerocket x86_64", regexp.MustCompile(`^bottlerocket-aws-k8s-[\d.]+-x86_64-`), "/aws/service/bottlerocket/aws-k8s-%s/x86_64/latest/image_id"},
+	{"Bottlerocket arm64", regexp.MustCompile(`^bottlerocket-aws-k8s-[\d.]+-aarch64-`), "/aws/service/bottlerocket/aws-k8s-%s/arm64/latest/image_id"},
+}
+
+// upgradeGroup collects the state of one node group relevant to its upgrade
+type upgradeGroup struct {
+	Region     string // of its first node, AMI IDs and SSM parameters are regional
+	Nodes      []v1.Node
+	Versions   map[string]bool
+	AMIs       map[string]bool
+	ReadyLags  []time.Duration
+	PDBsAtZero map[string]bool
+}
+
+func newSSMClient(cfg aws.Config) *awsJSONClient {
+	host := "ssm." + cfg.Region + ".amazonaws.com"
+	if strings.HasPrefix(cfg.Region, "cn-") {
+		host += ".cn"
+	}
+	return &awsJSONClient{
+		cfg:          cfg,
+		serviceID:    "SSM",
+		signingName:  "ssm",
+		region:       cfg.Region,
+		endpoint:     "https://" + host + "/",
+		targetPrefix: "AmazonSSM.",
+	}
+}
+
+// getSSMParameter returns the value of a parameter, "" if it does not exist
+func getSSMParameter(ctx context.Context, client *awsJSONClient, name string) (string, error) {
+	var output struct {
+		Parameter struct {
+			Value string `json:"Value"`
+		} `json:"Parameter"`
+	}
+	err := client.call(ctx, "GetParameter", map[string]interface{}{"Name": name}, &output)
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ParameterNotFound" {
+		return "", nil
+	}
+	return output.Parameter.Value, err
+}
+
+func runPlanUpgrade(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("plan-upgrade", flag.ExitOnError)
+	target := fs.String("to", "", "Target Kubernetes minor version (e.g. 1.30)")
+	maxSurge := fs.Int("max-surge", 1, "Nodes replaced at a time per group")
+	drainTime := fs.Duration("drain-time", 2*time.Minute, "Estimated time to drain a node, added to the replacement's launch-to-Ready time")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s plan-upgrade --to VERSION\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Plan a node group rotation to a new Kubernetes version: per group the current kubelet versions and\n")
+		fmt.Fprintf(os.Stderr, "AMIs, the recommended EKS-optimized AMI for the target version, the extra capacity the surge needs,\n")
+		fmt.Fprintf(os.Stderr, "PodDisruptionBudgets that currently allow no disruption and an estimated rotation duration.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if !regexp.MustCompile(`^1\.\d+$`).MatchString(*target) {
+		fmt.Fprintf(os.Stderr, "Error: --to must be a Kubernetes minor version such as 1.30\n")
+		os.Exit(1)
+	}
+	if *maxSurge < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --max-surge must be at least 1\n")
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.TODO()
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+	pdbs, err := clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing PodDisruptionBudgets: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The control plane is upgraded first; nodes may not be newer than it
+	if serverVersion, err := clientset.Discovery().ServerVersion(); err == nil {
+		current := serverVersion.Major + "." + strings.TrimSuffix(serverVersion.Minor, "+")
+		if compareMinorVersions(*target, current) > 0 {
+			fmt.Printf("Note: the control plane runs %s; upgrade it to %s before the nodes\n\n", current, *target)
+		}
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	instanceMap, err := getNodeInstances(ctx, awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+	asgMap, err := getNodeASGCapacities(ctx, awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting ASG capacities: %v\n", err)
+		os.Exit(1)
+	}
+
+	podsByNode := make(map[string][]v1.Pod)
+	for _, pod := range pods.Items {
+		if isDrainable(pod) {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+		}
+	}
+
+	groups := make(map[string]*upgradeGroup)
+	for _, node := range nodes.Items {
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists {
+			continue
+		}
+		name := getGroupFromTags(instance.Tags, groupTagKeys)
+		if name == "" {
+			name = "<none>"
+		}
+		if groups[name] == nil {
+			groups[name] = &upgradeGroup{Region: nodeRegion(node, awsConfig.Region), Versions: make(map[string]bool), AMIs: make(map[string]bool), PDBsAtZero: make(map[string]bool)}
+		}
+		group := groups[name]
+		group.Nodes = append(group.Nodes, node)
+		group.Versions[node.Status.NodeInfo.KubeletVersion] = true
+
+		group.AMIs[aws.ToString(instance.ImageId)] = true
+		if readyAt, ok := nodeReadySince(node); ok && instance.LaunchTime != nil {
+			group.ReadyLags = append(group.ReadyLags, readyAt.Sub(*instance.LaunchTime))
+		}
+
+		for _, pdb := range pdbs.Items {
+			if pdb.Status.DisruptionsAllowed == 0 && pdbCoveredPods(pdb, podsByNode[node.Name]) > 0 {
+				group.PDBsAtZero[pdb.Namespace+"/"+pdb.Name] = true
+			}
+		}
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No nodes with matching EC2 instances found")
+		return
+	}
+
+	images, err := getNodeImages(awsConfig, nodes.Items, instanceMap)
+	if err != nil {
+		warnf("could not describe AMIs, target AMIs are not looked up: %v\n", err)
+	}
+
+	targetAMIs := make(map[string]string) // by region and SSM parameter
+	var names []string
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tNODES\tVERSION\tCURRENT-AMI\tAMI-FAMILY\tTARGET-AMI\tSURGE\tPDBS-AT-LIMIT\tEST-DURATION")
+	for _, name := range names {
+		group := groups[name]
+
+		family, targetAMI := "custom", "-"
+		if len(group.AMIs) == 1 {
+			for imageID := range group.AMIs {
+				if f := detectAMIFamily(aws.ToString(images[imageID].Name)); f != nil {
+					family = f.Name
+					parameter := fmt.Sprintf(f.Parameter, *target)
+					key := group.Region + "/" + parameter
+					if _, looked := targetAMIs[key]; !looked {
+						value, err := getSSMParameter(ctx, newSSMClient(regionalConfig(awsConfig, group.Region)), parameter)
+						if err != nil {
+							warnf("could not read %s in %s: %v\n", parameter, group.Region, err)
+						}
+						targetAMIs[key] = value
+					}
+					targetAMI = targetAMIs[key]
+					if targetAMI == "" {
+						targetAMI = "not available"
+					} else if group.AMIs[targetAMI] {
+						targetAMI = "current"
+					}
+				}
+			}
+		} else {
+			family = "mixed"
+		}
+
+		// Rotation launches the surge first, so the group needs room above its desired capacity
+		surge := *maxSurge
+		if surge > len(group.Nodes) {
+			surge = len(group.Nodes)
+		}
+		surgeNote := fmt.Sprintf("+%d", surge)
+		if capacity, exists := asgMap[name]; exists && capacity.Desired+int32(surge) > capacity.Max {
+			surgeNote += fmt.Sprintf(" (raise max to %d)", capacity.Desired+int32(surge))
+		}
+
+		pdbNote := "-"
+		if len(group.PDBsAtZero) > 0 {
+			var blocked []string
+			for pdb := range group.PDBsAtZero {
+				blocked = append(blocked, pdb)
+			}
+			sort.Strings(blocked)
+			pdbNote = strings.Join(blocked, ",")
+		}
+
+		readyTime, ok := medianDuration(group.ReadyLags)
+		if !ok {
+			readyTime = defaultNodeReadyTime
+		}
+		batches := (len(group.Nodes) + surge - 1) / surge
+		duration := time.Duration(batches) * (readyTime + *drainTime)
+
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, len(group.Nodes), joinSet(group.Versions), joinSet(group.AMIs),
+			family, targetAMI, surgeNote, pdbNote, duration.Round(time.Minute))
+	}
+	w.Flush()
+}
+
+func detectAMIFamily(imageName string) *amiFamily {
+	for i := range amiFamilies {
+		if amiFamilies[i].Pattern.MatchString(imageName) {
+			return &amiFamilies[i]
+		}
+	}
+	return nil
+}
+
+// compareMinorVersions compares "1.29" style versions
+func compareMinorVersions(a, b string) int {
+	var aMajor, aMinor, bMajor, bMinor int
+	fmt.Sscanf(a, "%d.%d", &aMajor, &aMinor)
+	fmt.Sscanf(b, "%d.%d", &bMajor, &bMinor)
+	if aMajor != bMajor {
+		return aMajor - bMajor
+	}
+	return aMinor - bMinor
+}
+
+func joinSet(set map[string]bool) string {
+	var values []string
+	for value := range set {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return "-"
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
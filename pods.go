@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func runPods(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("pods", flag.ExitOnError)
+	sortBy := fs.String("sort-by", "cpu", "Sort pods by cpu or memory requests, largest first")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s pods [--sort-by cpu|memory] NODE_NAME\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List the pods on a node with their CPU and memory requests, largest first, below the node's\n")
+		fmt.Fprintf(os.Stderr, "EC2 instance and group, to see what a drain or recycle displaces. DRAIN marks the pods a\n")
+		fmt.Fprintf(os.Stderr, "drain evicts (not DaemonSet or static pods).\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *sortBy != "cpu" && *sortBy != "memory" {
+		fmt.Fprintf(os.Stderr, "Error: --sort-by must be cpu or memory, got '%s'\n", *sortBy)
+		os.Exit(1)
+	}
+	nodeName, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.TODO()
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting node '%s': %v\n", nodeName, err)
+		os.Exit(1)
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + node.Name})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The AWS identity is a header only, the pod list is printed without it
+	var instance *types.Instance
+	instanceID := getInstanceID(*node)
+	if strings.HasPrefix(instanceID, "i-") {
+		awsConfig, err := loadAWSConfig()
+		if err != nil {
+			warnf("could not load AWS config, the EC2 instance is not described: %v\n", err)
+		} else {
+			client := ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = nodeRegion(*node, awsConfig.Region) })
+			instances, err := getEC2Instances(ctx, client, []string{instanceID})
+			if err != nil {
+				warnf("could not describe instance %s: %v\n", instanceID, err)
+			} else if found, exists := instances[instanceID]; exists {
+				instance = &found
+			}
+		}
+	}
+
+	printNodeIdentity(os.Stdout, *node, instance, groupTagKeys)
+	fmt.Println()
+	printNodePods(os.Stdout, pods.Items, *sortBy)
+}
+
+// printNodeIdentity writes a one-line summary of the node and its instance
+func printNodeIdentity(out io.Writer, node v1.Node, instance *types.Instance, groupTagKeys []string) {
+	fmt.Fprintf(out, "Node:     %s (%s)\n", node.Name, getNodeStatus(node))
+	instanceID := valueOrDash(getInstanceID(node))
+	if instance == nil {
+		fmt.Fprintf(out, "Instance: %s %s %s\n", instanceID, valueOrDash(getInstanceType(node)), valueOrDash(getNodeZone(node)))
+		return
+	}
+	fmt.Fprintf(out, "Instance: %s %s %s %s\n", instanceID, instance.InstanceType, instanceLifecycle(*instance), valueOrDash(getNodeZone(node)))
+	fmt.Fprintf(out, "Group:    %s\n", valueOrDash(getGroupFromTags(instance.Tags, groupTagKeys)))
+}
+
+// printNodePods lists the node's running pods by CPU or memory requests, largest first
+func printNodePods(out io.Writer, pods []v1.Pod, sortBy string) {
+	var running []v1.Pod
+	requests := make(map[string]v1.ResourceList)
+	for _, pod := range pods {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		running = append(running, pod)
+		requests[pod.Namespace+"/"+pod.Name] = podRequests(pod)
+	}
+	if len(running) == 0 {
+		fmt.Fprintln(out, "No pods on the node")
+		return
+	}
+
+	sort.Slice(running, func(i, j int) bool {
+		a, b := requests[running[i].Namespace+"/"+running[i].Name], requests[running[j].Namespace+"/"+running[j].Name]
+		first, second := a.Cpu().Cmp(*b.Cpu()), a.Memory().Cmp(*b.Memory())
+		if sortBy == "memory" {
+			first, second = second, first
+		}
+		if first != 0 {
+			return first > 0
+		}
+		if second != 0 {
+			return second > 0
+		}
+		return running[i].Namespace+"/"+running[i].Name < running[j].Namespace+"/"+running[j].Name
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tNAME\tOWNER\tCPU-REQ\tMEM-REQ\tDRAIN")
+	for _, pod := range running {
+		podRequests := requests[pod.Namespace+"/"+pod.Name]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", pod.Namespace, pod.Name, podOwner(pod),
+			formatResource(podRequests.Cpu()), formatMemory(podRequests.Memory()), formatFlag(isDrainable(pod)))
+	}
+	w.Flush()
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// problem is a single finding of the problems command
+type problem struct {
+	Scope   string
+	Name    string
+	Problem string
+}
+
+// groupUsage sums allocatable and requested capacity of a node group's schedulable nodes
+type groupUsage struct {
+	Nodes        int
+	CPUCapacity  resource.Quantity
+	CPURequested resource.Quantity
+	MemCapacity  resource.Quantity
+	MemRequested resource.Quantity
+}
+
+func runProblems(args []string, cfg *Config, groupTagKeys []string) {
+	fs := flag.NewFlagSet("problems", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s problems\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List nodes that are not Ready, Ready nodes missing critical addon pods (NotServiceable),\n")
+		fmt.Fprintf(os.Stderr, "node groups violating the headroom policy of the config file, and capacity that both\n")
+		fmt.Fprintf(os.Stderr, "cluster-autoscaler and Karpenter manage. Exits with status 1 if any problem is found.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	var problems []problem
+	for _, node := range nodes.Items {
+		if status := getNodeStatus(node); status != "Ready" {
+			problems = append(problems, problem{"node", node.Name, status})
+		}
+	}
+
+	notServiceable, err := notServiceableProblems(clientset, nodes.Items, pods.Items, cfg.criticalDaemonSets())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing DaemonSets: %v\n", err)
+		os.Exit(1)
+	}
+	problems = append(problems, notServiceable...)
+
+	// Group headroom and autoscaler conflicts need AWS to map nodes to groups, so only check
+	// them when a policy is set or Karpenter manages nodes
+	karpenter := hasKarpenterNodes(nodes.Items)
+	if cfg.Headroom != nil || karpenter {
+		awsConfig, err := loadAWSConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+			os.Exit(1)
+		}
+		instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+			os.Exit(1)
+		}
+
+		if cfg.Headroom != nil {
+			usage := groupUsageByGroup(nodes.Items, pods.Items, instanceMap, groupTagKeys)
+			problems = append(problems, headroomProblems(usage, cfg)...)
+		}
+
+		if karpenter {
+			asgs, err := getNodeASGs(awsConfig, nodes.Items)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error getting Auto Scaling Groups: %v\n", err)
+				os.Exit(1)
+			}
+			caRunning, err := clusterAutoscalerRunning(clientset)
+			if err != nil {
+				warnf("could not look for cluster-autoscaler: %v\n", err)
+			}
+			problems = append(problems, autoscalerConflicts(nodes.Items, instanceMap, asgs, caRunning)...)
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Println("No problems found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SCOPE\tNAME\tPROBLEM")
+	for _, p := range problems {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", p.Scope, p.Name, p.Problem)
+	}
+	w.Flush()
+	os.Exit(1)
+}
+
+// groupUsageByGroup aggregates Ready, schedulable nodes per node group. Cordoned
+// nodes are left out since their free capacity can't absorb new pods.
+func groupUsageByGroup(nodes []v1.Node, pods []v1.Pod, instanceMap map[string]types.Instance, groupTagKeys []string) map[string]*groupUsage {
+	nodeResources := calculateNodeResources(nodes, pods)
+	usage := make(map[string]*groupUsage)
+	for _, node := range nodes {
+		if node.Spec.Unschedulable || getNodeStatus(node) != "Ready" {
+			continue
+		}
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists {
+			continue
+		}
+		group := getGroupFromTags(instance.Tags, groupTagKeys)
+		info := nodeResources[node.Name]
+		if group == "" || info == nil {
+			continue
+		}
+
+		if usage[group] == nil {
+			usage[group] = &groupUsage{}
+		}
+		u := usage[group]
+		u.Nodes++
+		addQuantity(&u.CPUCapacity, info.CPUCapacity)
+		addQuantity(&u.CPURequested, info.CPURequested)
+		addQuantity(&u.MemCapacity, info.MemCapacity)
+		addQuantity(&u.MemRequested, info.MemRequested)
+	}
+	return usage
+}
+
+func addQuantity(total *resource.Quantity, q *resource.Quantity) {
+	if q != nil {
+		total.Add(*q)
+	}
+}
+
+// headroomProblems reports groups whose free CPU or memory is below their configured headroom
+func headroomProblems(usage map[string]*groupUsage, cfg *Config) []problem {
+	var groups []string
+	for group := range usage {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var problems []problem
+	for _, group := range groups {
+		u := usage[group]
+		policy := cfg.headroomFor(group)
+		if policy.CPU != nil {
+			if free := calculateFreePercentage(&u.CPUCapacity, &u.CPURequested); free < *policy.CPU {
+				problems = append(problems, problem{"group", group,
+					fmt.Sprintf("CPU headroom %s below policy %s (%d nodes)", formatPercent(free), formatPercent(*policy.CPU), u.Nodes)})
+			}
+		}
+		if policy.Memory != nil {
+			if free := calculateFreePercentage(&u.MemCapacity, &u.MemRequested); free < *policy.Memory {
+				problems = append(problems, problem{"group", group,
+					fmt.Sprintf("memory headroom %s below policy %s (%d nodes)", formatPercent(free), formatPercent(*policy.Memory), u.Nodes)})
+			}
+		}
+	}
+	return problems
+}
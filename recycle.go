@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// evictionRetryInterval is how long to wait before retrying an eviction refused by a PodDisruptionBudget
+const evictionRetryInterval = 5 * time.Second
+
+func runRecycle(args []string) {
+	fs := flag.NewFlagSet("recycle", flag.ExitOnError)
+	decrement := fs.Bool("decrement", false, "Decrement the ASG desired capacity instead of letting it launch a replacement")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Maximum time to wait for the node's pods to be evicted")
+	gracePeriod := fs.Int64("grace-period", -1, "Termination grace period for evicted pods in seconds (-1 uses each pod's own)")
+	deleteEmptyDirData := fs.Bool("delete-emptydir-data", false, "Drain even if pods use emptyDir volumes, whose data is deleted with the pod")
+	ignoreDaemonSets := fs.Bool("ignore-daemonsets", false, "Drain even if DaemonSet pods are on the node and leave them running; without it, like kubectl drain, such nodes are refused")
+	force := fs.Bool("force", false, "Drain even if pods without a controller (bare pods) are on the node; they are lost for good")
+	skipWaitTimeout := fs.Int("skip-wait-for-delete-timeout", 0, "Stop waiting for pods whose deletion started more than this many seconds ago (0 waits for all)")
+	waitCheckpoint := fs.Bool("wait-for-checkpoint", false, "Before evicting, wait for Jobs and StatefulSets on the node annotated "+checkpointAnnotation+" to set it to "+checkpointSafe)
+	checkpointTimeout := fs.Duration("checkpoint-timeout", 30*time.Minute, "Maximum time to wait with --wait-for-checkpoint")
+	at := fs.String("at", "", "Wait until this time (e.g. 2024-07-01T02:00Z) before cordoning the node")
+	window := fs.Duration("window", 0, "Length of the maintenance window starting at --at; recycle refuses to start after it ends")
+	yes := fs.Bool("yes", false, "Don't ask for confirmation")
+	dryRun := fs.Bool("dry-run", false, "Show the disruption estimate without changing anything")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s recycle [--decrement] [--at TIME [--window DURATION]] NODE_NAME\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Cordon the node, evict its pods through the eviction API (respecting PodDisruptionBudgets)\n")
+		fmt.Fprintf(os.Stderr, "and terminate its instance through its Auto Scaling Group, which launches a replacement.\n")
+		fmt.Fprintf(os.Stderr, "The drain flags work like kubectl drain's.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	nodeName, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	maintenance, err := parseMaintenanceWindow(*at, *window)
+	if err == nil && maintenance != nil {
+		err = maintenance.check(time.Now())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting node '%s': %v\n", nodeName, err)
+		os.Exit(1)
+	}
+	instanceID := getInstanceID(*node)
+	if instanceID == "" {
+		fmt.Fprintf(os.Stderr, "Error: node '%s' has no EC2 instance ID in its providerID\n", nodeName)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Only ASG instances can be terminated through their group; anything else (e.g. Karpenter
+	// nodes) is refused before the node is cordoned and drained
+	asgClient := autoscaling.NewFromConfig(awsConfig, func(o *autoscaling.Options) { o.Region = nodeRegion(*node, awsConfig.Region) })
+	asgInstances, err := asgClient.DescribeAutoScalingInstances(context.TODO(), &autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error describing ASG membership of %s: %v\n", instanceID, classifyAWSError(err))
+		os.Exit(1)
+	}
+	if len(asgInstances.AutoScalingInstances) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: instance %s of node '%s' is not in an Auto Scaling Group, recycle only replaces ASG instances\n", instanceID, nodeName)
+		os.Exit(1)
+	}
+
+	estimate, err := estimateDisruption(clientset, nodeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error estimating disruption: %v\n", err)
+		os.Exit(1)
+	}
+	printDisruption(os.Stdout, estimate)
+	fmt.Println()
+
+	drain := drainOptions{
+		GracePeriod:              *gracePeriod,
+		Timeout:                  *timeout,
+		DeleteEmptyDirData:       *deleteEmptyDirData,
+		IgnoreDaemonSets:         *ignoreDaemonSets,
+		Force:                    *force,
+		SkipWaitForDeleteTimeout: time.Duration(*skipWaitTimeout) * time.Second,
+	}
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+	if err := drain.check(pods.Items); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *dryRun {
+		when := ""
+		if maintenance != nil {
+			when = " in the maintenance window " + maintenance.String()
+		}
+		fmt.Printf("node/%s (%s) would be drained and terminated%s (dry run)\n", nodeName, instanceID, when)
+		return
+	}
+
+	if !*yes {
+		if !isInteractive() {
+			fmt.Fprintf(os.Stderr, "Error: not a terminal, pass --yes to recycle without confirmation\n")
+			os.Exit(1)
+		}
+		action := "replaced"
+		if *decrement {
+			action = "removed, decrementing the ASG desired capacity"
+		}
+		fmt.Fprintf(os.Stderr, "Drain node '%s' and terminate instance %s, to be %s? [y/N] ", nodeName, instanceID, action)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			fmt.Println("Aborted")
+			return
+		}
+	}
+
+	// The pods checked above may have changed by the time the window opens
+	if maintenance != nil {
+		if err := maintenance.wait(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+			os.Exit(1)
+		}
+		if err := drain.check(pods.Items); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := setUnschedulable(clientset, nodeName, true); err != nil {
+		fmt.Fprintf(os.Stderr, "Error cordoning node: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("node/%s cordoned\n", nodeName)
+
+	// Cordoned first, so no new work lands on the node while its workloads checkpoint
+	if *waitCheckpoint {
+		if err := waitForCheckpoints(clientset, nodeName, *checkpointTimeout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "The node stays cordoned; uncordon it with 'kubectl uncordon %s'\n", nodeName)
+			os.Exit(1)
+		}
+	}
+
+	if err := drainNode(clientset, nodeName, drain); err != nil {
+		fmt.Fprintf(os.Stderr, "Error draining node: %v\n", err)
+		fmt.Fprintf(os.Stderr, "The node stays cordoned; uncordon it with 'kubectl uncordon %s'\n", nodeName)
+		os.Exit(1)
+	}
+	fmt.Printf("node/%s drained\n", nodeName)
+
+	result, err := asgClient.TerminateInstanceInAutoScalingGroup(context.TODO(), &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     aws.String(instanceID),
+		ShouldDecrementDesiredCapacity: aws.Bool(*decrement),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error terminating instance %s: %v\n", instanceID, classifyAWSError(err))
+		os.Exit(1)
+	}
+	if result.Activity != nil {
+		fmt.Printf("instance/%s terminating: %s\n", instanceID, aws.ToString(result.Activity.Description))
+	} else {
+		fmt.Printf("instance/%s terminating\n", instanceID)
+	}
+}
+
+// drainOptions mirrors the kubectl drain flags
+type drainOptions struct {
+	GracePeriod              int64 // seconds, -1 for each pod's own
+	Timeout                  time.Duration
+	DeleteEmptyDirData       bool
+	IgnoreDaemonSets         bool
+	Force                    bool
+	SkipWaitForDeleteTimeout time.Duration
+}
+
+// check refuses the drain like kubectl drain does, before anything is changed: for pods without
+// a controller unless Force, pods with emptyDir volumes unless DeleteEmptyDirData, and DaemonSet
+// pods unless IgnoreDaemonSets
+func (o drainOptions) check(pods []v1.Pod) error {
+	var unmanaged, localStorage, daemonSets []string
+	for _, pod := range pods {
+		if !o.IgnoreDaemonSets && isDaemonSetPod(pod) && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			daemonSets = append(daemonSets, pod.Namespace+"/"+pod.Name)
+		}
+		if !isDrainable(pod) {
+			continue
+		}
+		if !o.Force && metav1.GetControllerOf(&pod) == nil {
+			unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+		}
+		if o.DeleteEmptyDirData {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.EmptyDir != nil {
+				localStorage = append(localStorage, pod.Namespace+"/"+pod.Name)
+				break
+			}
+		}
+	}
+
+	var reasons []string
+	if len(unmanaged) > 0 {
+		reasons = append(reasons, "cannot delete Pods that declare no controller (use --force to override): "+strings.Join(unmanaged, ", "))
+	}
+	if len(daemonSets) > 0 {
+		reasons = append(reasons, "cannot delete DaemonSet-managed pods (use --ignore-daemonsets to ignore): "+strings.Join(daemonSets, ", "))
+	}
+	if len(localStorage) > 0 {
+		reasons = append(reasons, "cannot delete pods with local storage (use --delete-emptydir-data to override): "+strings.Join(localStorage, ", "))
+	}
+	if len(reasons) > 0 {
+		return fmt.Errorf("%s", strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// drainNode evicts the node's drainable pods and waits until they are gone. Evictions refused
+// by a PodDisruptionBudget are retried until the timeout.
+func drainNode(clientset *kubernetes.Clientset, nodeName string, drain drainOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), drain.Timeout)
+	defer cancel()
+
+	evicted := make(map[types.UID]bool)
+	for {
+		pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+		if err != nil {
+			return fmt.Errorf("listing pods: %w", err)
+		}
+
+		remaining := 0
+		for _, pod := range pods.Items {
+			if !isDrainable(pod) {
+				continue
+			}
+			// Pods stuck terminating (e.g. on an unreachable kubelet) are given up on like kubectl does
+			if pod.DeletionTimestamp != nil && drain.SkipWaitForDeleteTimeout > 0 && time.Since(pod.DeletionTimestamp.Time) > drain.SkipWaitForDeleteTimeout {
+				continue
+			}
+			remaining++
+			if evicted[pod.UID] {
+				continue
+			}
+			err := evictPod(ctx, clientset, pod, drain.GracePeriod)
+			switch {
+			case err == nil, apierrors.IsNotFound(err):
+				evicted[pod.UID] = true
+				fmt.Printf("pod/%s evicted (namespace %s)\n", pod.Name, pod.Namespace)
+			case apierrors.IsTooManyRequests(err):
+				// Refused by a PodDisruptionBudget, retried on the next round
+			default:
+				return fmt.Errorf("evicting pod %s/%s: %w", pod.Namespace, pod.Name, err)
+			}
+		}
+		if remaining == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out with %d pod(s) left on the node", remaining)
+		case <-time.After(evictionRetryInterval):
+		}
+	}
+}
+
+func evictPod(ctx context.Context, clientset *kubernetes.Clientset, pod v1.Pod, gracePeriod int64) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	}
+	if gracePeriod >= 0 {
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod}
+	}
+	return clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+}
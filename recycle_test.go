@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDrainOptionsCheck(t *testing.T) {
+	controller := true
+	pod := func(name, ownerKind string, modify func(*v1.Pod)) v1.Pod {
+		p := v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		if ownerKind != "" {
+			p.OwnerReferences = []metav1.OwnerReference{{Kind: ownerKind, Name: name + "-owner", Controller: &controller}}
+		}
+		if modify != nil {
+			modify(&p)
+		}
+		return p
+	}
+	emptyDir := func(p *v1.Pod) {
+		p.Spec.Volumes = []v1.Volume{{Name: "scratch", VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}}}
+	}
+
+	tests := []struct {
+		name    string
+		options drainOptions
+		pods    []v1.Pod
+		wantErr []string // substrings of the error, none for no error
+	}{
+		{
+			name:    "managed pods",
+			options: drainOptions{IgnoreDaemonSets: true},
+			pods:    []v1.Pod{pod("web", "ReplicaSet", nil), pod("agent", "DaemonSet", nil)},
+		},
+		{
+			name:    "DaemonSet pods are refused without --ignore-daemonsets",
+			options: drainOptions{},
+			pods:    []v1.Pod{pod("web", "ReplicaSet", nil), pod("agent", "DaemonSet", nil)},
+			wantErr: []string{"DaemonSet-managed pods", "--ignore-daemonsets", "default/agent"},
+		},
+		{
+			name:    "completed DaemonSet pods",
+			options: drainOptions{},
+			pods:    []v1.Pod{pod("agent", "DaemonSet", func(p *v1.Pod) { p.Status.Phase = v1.PodFailed })},
+		},
+		{
+			name:    "bare pods are refused and listed",
+			options: drainOptions{},
+			pods:    []v1.Pod{pod("web", "ReplicaSet", nil), pod("debug", "", nil), pod("scratch", "", nil)},
+			wantErr: []string{"declare no controller", "--force", "default/debug, default/scratch"},
+		},
+		{
+			name:    "owner that is not a controller",
+			options: drainOptions{},
+			pods: []v1.Pod{pod("orphan", "", func(p *v1.Pod) {
+				p.OwnerReferences = []metav1.OwnerReference{{Kind: "ConfigMap", Name: "settings"}}
+			})},
+			wantErr: []string{"default/orphan"},
+		},
+		{
+			name:    "bare pods with --force",
+			options: drainOptions{Force: true},
+			pods:    []v1.Pod{pod("debug", "", nil)},
+		},
+		{
+			name:    "completed bare pods",
+			options: drainOptions{},
+			pods:    []v1.Pod{pod("job", "", func(p *v1.Pod) { p.Status.Phase = v1.PodSucceeded })},
+		},
+		{
+			name:    "mirror pods",
+			options: drainOptions{},
+			pods: []v1.Pod{pod("kube-proxy", "", func(p *v1.Pod) {
+				p.Annotations = map[string]string{v1.MirrorPodAnnotationKey: "hash"}
+			})},
+		},
+		{
+			name:    "bare pod with emptyDir and --force",
+			options: drainOptions{Force: true},
+			pods:    []v1.Pod{pod("debug", "", emptyDir)},
+			wantErr: []string{"local storage", "default/debug"},
+		},
+		{
+			name:    "every reason",
+			options: drainOptions{},
+			pods:    []v1.Pod{pod("debug", "", nil), pod("agent", "DaemonSet", nil), pod("cache", "ReplicaSet", emptyDir)},
+			wantErr: []string{"default/debug", "DaemonSet-managed pods", "default/agent", "local storage", "default/cache"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.check(tt.pods)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("check() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("check() succeeded, want an error containing %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("check() = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
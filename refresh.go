@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+// activeRefreshStatuses are the instance refresh states in which instances are still being replaced
+var activeRefreshStatuses = map[astypes.InstanceRefreshStatus]bool{
+	astypes.InstanceRefreshStatusPending:            true,
+	astypes.InstanceRefreshStatusInProgress:         true,
+	astypes.InstanceRefreshStatusCancelling:         true,
+	astypes.InstanceRefreshStatusRollbackInProgress: true,
+	astypes.InstanceRefreshStatusBaking:             true,
+}
+
+// startInstanceRefresh starts a rolling replacement of the ASG's instances, e.g. to roll out a new
+// AMI from the launch template. The ASG's own refresh preferences (or the AWS defaults) apply.
+func startInstanceRefresh(asgName string, dryRun bool) {
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	_, region, err := locateASG(awsConfig, asgName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	client := autoscaling.NewFromConfig(awsConfig, func(o *autoscaling.Options) { o.Region = region })
+
+	// Only one refresh can run at a time, report the running one instead of failing
+	active, err := getActiveRefresh(context.TODO(), client, asgName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error describing instance refreshes of %s: %v\n", asgName, err)
+		os.Exit(1)
+	}
+	if active != "" {
+		fmt.Printf("asg/%s already has an instance refresh: %s\n", asgName, active)
+		return
+	}
+	if dryRun {
+		fmt.Printf("asg/%s instance refresh would start (dry run)\n", asgName)
+		return
+	}
+
+	result, err := client.StartInstanceRefresh(context.TODO(), &autoscaling.StartInstanceRefreshInput{
+		AutoScalingGroupName: aws.String(asgName),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting instance refresh of %s: %v\n", asgName, classifyAWSError(err))
+		os.Exit(1)
+	}
+	fmt.Printf("asg/%s instance refresh %s started, follow it with -o wide\n", asgName, aws.ToString(result.InstanceRefreshId))
+}
+
+// getInstanceRefreshes returns the active instance refresh of each ASG that has one, e.g.
+// "InProgress 45%"
+func getInstanceRefreshes(cfg aws.Config, asgRegions map[string]string) map[string]string {
+	refreshes := make(map[string]string)
+	for asgName, region := range asgRegions {
+		client := autoscaling.NewFromConfig(cfg, func(o *autoscaling.Options) { o.Region = region })
+		active, err := getActiveRefresh(context.TODO(), client, asgName)
+		if err != nil {
+			warnf("could not describe instance refreshes of ASG '%s': %v\n", asgName, err)
+			continue
+		}
+		if active != "" {
+			refreshes[asgName] = active
+		}
+	}
+	return refreshes
+}
+
+// getActiveRefresh describes the ASG's latest instance refresh, empty when it is not running
+func getActiveRefresh(ctx context.Context, client *autoscaling.Client, asgName string) (string, error) {
+	// Refreshes are returned newest first
+	result, err := client.DescribeInstanceRefreshes(ctx, &autoscaling.DescribeInstanceRefreshesInput{
+		AutoScalingGroupName: aws.String(asgName),
+		MaxRecords:           aws.Int32(1),
+	})
+	if err != nil {
+		return "", classifyAWSError(err)
+	}
+	if len(result.InstanceRefreshes) == 0 || !activeRefreshStatuses[result.InstanceRefreshes[0].Status] {
+		return "", nil
+	}
+	refresh := result.InstanceRefreshes[0]
+	if refresh.PercentageComplete == nil {
+		return string(refresh.Status), nil
+	}
+	return fmt.Sprintf("%s %d%%", refresh.Status, aws.ToInt32(refresh.PercentageComplete)), nil
+}
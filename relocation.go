@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// relocationIssue describes a pod that would have nowhere to go if its node were drained
+type relocationIssue struct {
+	Pod    v1.Pod
+	Reason string
+}
+
+func runDrainCheck(args []string) {
+	fs := flag.NewFlagSet("drain-check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s drain-check NODE_NAME\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "List pods on the node that no other schedulable node can take (nodeSelector,\n")
+		fmt.Fprintf(os.Stderr, "node affinity, taints or free requests), i.e. pods that would go Pending on drain.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	nodeName, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing pods: %v\n", err)
+		os.Exit(1)
+	}
+
+	found := false
+	for _, node := range nodes.Items {
+		if node.Name == nodeName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		fmt.Fprintf(os.Stderr, "Error: node '%s' not found\n", nodeName)
+		os.Exit(1)
+	}
+
+	issues := findRelocationIssues(nodeName, nodes.Items, pods.Items)
+	if len(issues) == 0 {
+		fmt.Printf("All pods on node '%s' have at least one other candidate node\n", nodeName)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tPOD\tOWNER\tREASON")
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Pod.Namespace, issue.Pod.Name, podOwner(issue.Pod), issue.Reason)
+	}
+	w.Flush()
+}
+
+// findRelocationIssues returns the pods on nodeName that could not be rescheduled on any
+// other Ready, schedulable node. DaemonSet and static pods are skipped since drains leave them.
+func findRelocationIssues(nodeName string, nodes []v1.Node, pods []v1.Pod) []relocationIssue {
+	nodeResources := calculateNodeResources(nodes, pods)
+
+	var candidates []v1.Node
+	for _, node := range nodes {
+		if node.Name != nodeName && !node.Spec.Unschedulable && getNodeStatus(node) == "Ready" {
+			candidates = append(candidates, node)
+		}
+	}
+
+	var issues []relocationIssue
+	for _, pod := range pods {
+		if pod.Spec.NodeName != nodeName || isDaemonSetPod(pod) || isMirrorPod(pod) ||
+			pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		if reason := relocationBlocker(pod, candidates, nodeResources); reason != "" {
+			issues = append(issues, relocationIssue{Pod: pod, Reason: reason})
+		}
+	}
+	return issues
+}
+
+// relocationBlocker explains why no candidate node fits the pod, or returns "" if one does
+func relocationBlocker(pod v1.Pod, candidates []v1.Node, nodeResources map[string]*NodeInfo) string {
+	if len(candidates) == 0 {
+		return "no other Ready, schedulable nodes"
+	}
+
+	requests := podRequests(pod)
+	selectorMatches, tolerated := 0, 0
+	for _, node := range candidates {
+		if !matchesNodeSelector(pod, node) || !matchesNodeAffinity(pod, node) {
+			continue
+		}
+		selectorMatches++
+		if !toleratesNodeTaints(pod, node) {
+			continue
+		}
+		tolerated++
+		if fitsFreeResources(requests, nodeResources[node.Name]) {
+			return ""
+		}
+	}
+
+	switch {
+	case selectorMatches == 0:
+		return "no other node matches nodeSelector/node affinity"
+	case tolerated == 0:
+		return fmt.Sprintf("%d matching node(s), none tolerate their taints", selectorMatches)
+	default:
+		return fmt.Sprintf("insufficient free cpu/memory on %d matching node(s)", tolerated)
+	}
+}
+
+func fitsFreeResources(requests v1.ResourceList, nodeInfo *NodeInfo) bool {
+	if nodeInfo == nil {
+		return false
+	}
+	return fits(requests.Cpu(), nodeInfo.CPUCapacity, nodeInfo.CPURequested) &&
+		fits(requests.Memory(), nodeInfo.MemCapacity, nodeInfo.MemRequested)
+}
+
+func fits(request, capacity, requested *resource.Quantity) bool {
+	if request == nil || request.IsZero() {
+		return true
+	}
+	if capacity == nil {
+		return false
+	}
+	free := capacity.DeepCopy()
+	if requested != nil {
+		free.Sub(*requested)
+	}
+	return request.Cmp(free) <= 0
+}
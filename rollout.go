@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func runRolloutStatus(args []string) {
+	fs := flag.NewFlagSet("rollout-status", flag.ExitOnError)
+	asgName := fs.String("asg", "", "Auto Scaling Group to report on")
+	watch := fs.Bool("watch", false, "Keep polling until every node runs the current launch template version")
+	timeout := fs.Duration("timeout", 30*time.Minute, "Maximum time to wait with --watch")
+	interval := fs.Duration("interval", 15*time.Second, "Polling interval with --watch")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s rollout-status --asg NAME [--watch]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Show how many nodes of an ASG run the current launch template version.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if *asgName == "" {
+		fmt.Fprintf(os.Stderr, "Error: rollout-status requires --asg\n")
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	_, region, err := locateASG(awsConfig, *asgName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	ec2Client := ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = region })
+	asgClient := autoscaling.NewFromConfig(awsConfig, func(o *autoscaling.Options) { o.Region = region })
+
+	deadline := time.Now().Add(*timeout)
+	for {
+		done, err := printRolloutStatus(clientset, ec2Client, asgClient, *asgName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if done || !*watch {
+			return
+		}
+		if time.Now().Add(*interval).After(deadline) {
+			fmt.Fprintf(os.Stderr, "Error: timed out after %s waiting for rollout of '%s'\n", *timeout, *asgName)
+			os.Exit(1)
+		}
+		time.Sleep(*interval)
+		fmt.Println()
+	}
+}
+
+// printRolloutStatus prints one status report and returns true once the rollout is complete
+func printRolloutStatus(clientset *kubernetes.Clientset, ec2Client *ec2.Client, asgClient *autoscaling.Client, asgName string) (bool, error) {
+	asg, err := describeASG(asgClient, asgName)
+	if err != nil {
+		return false, err
+	}
+
+	spec := asgLaunchTemplate(*asg)
+	if spec == nil {
+		return false, fmt.Errorf("ASG '%s' does not use a launch template", asgName)
+	}
+	current, err := resolveLaunchTemplateVersion(ec2Client, spec)
+	if err != nil {
+		return false, err
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("listing nodes: %w", err)
+	}
+	nodeByInstance := make(map[string]v1.Node)
+	for _, node := range nodes.Items {
+		nodeByInstance[getInstanceID(node)] = node
+	}
+
+	fmt.Printf("Launch template: %s version %s\n", launchTemplateLabel(spec), current)
+
+	updatedReady, total := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE-ID\tNODE\tVERSION\tLIFECYCLE\tSTATUS\tUPDATED")
+	for _, instance := range asg.Instances {
+		total++
+		instanceID := aws.ToString(instance.InstanceId)
+		version := instanceLaunchTemplateVersion(instance)
+		updated := version == current
+
+		nodeName, status := "<not registered>", "-"
+		if node, exists := nodeByInstance[instanceID]; exists {
+			nodeName = node.Name
+			status = getNodeStatus(node)
+			if updated && status == "Ready" {
+				updatedReady++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n",
+			instanceID, nodeName, version, instance.LifecycleState, status, updated)
+	}
+	w.Flush()
+
+	if updatedReady == total {
+		fmt.Printf("ASG \"%s\" successfully rolled out: %d of %d nodes on version %s and Ready\n", asgName, updatedReady, total, current)
+		return true, nil
+	}
+	fmt.Printf("Waiting for ASG \"%s\" rollout to finish: %d of %d nodes on version %s and Ready\n", asgName, updatedReady, total, current)
+	return false, nil
+}
+
+func describeASG(client *autoscaling.Client, name string) (*astypes.AutoScalingGroup, error) {
+	result, err := client.DescribeAutoScalingGroups(context.TODO(), &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{name},
+	})
+	if err != nil {
+		return nil, classifyAWSError(err)
+	}
+	if len(result.AutoScalingGroups) == 0 {
+		return nil, fmt.Errorf("ASG '%s' not found", name)
+	}
+	return &result.AutoScalingGroups[0], nil
+}
+
+// asgLaunchTemplate returns the launch template of an ASG, including mixed instances policies
+func asgLaunchTemplate(asg astypes.AutoScalingGroup) *astypes.LaunchTemplateSpecification {
+	if asg.LaunchTemplate != nil {
+		return asg.LaunchTemplate
+	}
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		return asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	return nil
+}
+
+func instanceLaunchTemplateVersion(instance astypes.Instance) string {
+	if instance.LaunchTemplate == nil {
+		return "-"
+	}
+	return aws.ToString(instance.LaunchTemplate.Version)
+}
+
+func launchTemplateLabel(spec *astypes.LaunchTemplateSpecification) string {
+	if name := aws.ToString(spec.LaunchTemplateName); name != "" {
+		return fmt.Sprintf("%s (%s)", name, aws.ToString(spec.LaunchTemplateId))
+	}
+	return aws.ToString(spec.LaunchTemplateId)
+}
+
+// resolveLaunchTemplateVersion turns $Latest/$Default into the concrete version number
+func resolveLaunchTemplateVersion(client *ec2.Client, spec *astypes.LaunchTemplateSpecification) (string, error) {
+	version := aws.ToString(spec.Version)
+	if version != "" && version != "$Latest" && version != "$Default" {
+		return version, nil
+	}
+
+	input := &ec2.DescribeLaunchTemplatesInput{}
+	if spec.LaunchTemplateId != nil {
+		input.LaunchTemplateIds = []string{*spec.LaunchTemplateId}
+	} else {
+		input.LaunchTemplateNames = []string{aws.ToString(spec.LaunchTemplateName)}
+	}
+	result, err := client.DescribeLaunchTemplates(context.TODO(), input)
+	if err != nil {
+		return "", classifyAWSError(err)
+	}
+	if len(result.LaunchTemplates) == 0 {
+		return "", fmt.Errorf("launch template %s not found", launchTemplateLabel(spec))
+	}
+
+	lt := result.LaunchTemplates[0]
+	if version == "$Latest" {
+		return strconv.FormatInt(aws.ToInt64(lt.LatestVersionNumber), 10), nil
+	}
+	return strconv.FormatInt(aws.ToInt64(lt.DefaultVersionNumber), 10), nil
+}
+
+// getLaunchTemplateDrift compares the launch template version of each instance in the ASGs with
+// the version the ASG launches now, returning e.g. "v4→v7" for the outdated instances by ID
+func getLaunchTemplateDrift(cfg aws.Config, asgRegions map[string]string) map[string]string {
+	drift := make(map[string]string)
+	for asgName, region := range asgRegions {
+		asgClient := autoscaling.NewFromConfig(cfg, func(o *autoscaling.Options) { o.Region = region })
+		ec2Client := ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+		asg, err := describeASG(asgClient, asgName)
+		if err != nil {
+			warnf("could not describe ASG '%s' for launch template drift: %v\n", asgName, err)
+			continue
+		}
+		spec := asgLaunchTemplate(*asg)
+		if spec == nil {
+			continue
+		}
+		current, err := resolveLaunchTemplateVersion(ec2Client, spec)
+		if err != nil {
+			warnf("could not resolve the launch template version of ASG '%s': %v\n", asgName, err)
+			continue
+		}
+		for _, instance := range asg.Instances {
+			version := instanceLaunchTemplateVersion(instance)
+			if version == current {
+				continue
+			}
+			// Instances from before the ASG used a launch template have no version
+			if version != "-" {
+				version = "v" + version
+			}
+			drift[aws.ToString(instance.InstanceId)] = version + "→v" + current
+		}
+	}
+	return drift
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go"
+)
+
+// Uploads only need PutObject, so like the Health API, S3 is called through a
+// SigV4-signed request instead of pulling in the S3 SDK package.
+
+// parseS3URL splits s3://bucket/prefix into bucket and prefix (without trailing slash)
+func parseS3URL(s3URL string) (bucket, prefix string, err error) {
+	u, err := url.Parse(s3URL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid S3 URL '%s', expected s3://bucket/prefix", s3URL)
+	}
+	return u.Host, strings.Trim(u.Path, "/"), nil
+}
+
+// s3ObjectURL returns the URL of bucket/key. Buckets with dots in their name use path-style
+// addressing, since their virtual-hosted name doesn't match the *.s3 TLS certificate.
+func s3ObjectURL(bucket, region, key string) string {
+	host := "amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		host = "amazonaws.com.cn"
+	}
+	if strings.Contains(bucket, ".") {
+		return fmt.Sprintf("https://s3.%s.%s/%s/%s", region, host, s3EscapePath(bucket), s3EscapePath(key))
+	}
+	return fmt.Sprintf("https://%s.s3.%s.%s/%s", bucket, region, host, s3EscapePath(key))
+}
+
+// s3EscapePath escapes each segment of key as SigV4 expects: the signer is set not to escape
+// the path again, so the path is sent and signed as is.
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		var b strings.Builder
+		for _, c := range []byte(segment) {
+			if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+				b.WriteByte(c)
+			} else {
+				fmt.Fprintf(&b, "%%%02X", c)
+			}
+		}
+		segments[i] = b.String()
+	}
+	return strings.Join(segments, "/")
+}
+
+// putS3Object uploads body to bucket/key. Buckets in another region than the
+// AWS config are retried once in the region S3 reports for the bucket.
+func putS3Object(ctx context.Context, cfg aws.Config, bucket, key string, body []byte, contentType, contentEncoding string) error {
+	region := cfg.Region
+	for attempt := 0; ; attempt++ {
+		bucketRegion, err := tryPutS3Object(ctx, cfg, region, bucket, key, body, contentType, contentEncoding)
+		if err != nil && attempt == 0 && bucketRegion != "" && bucketRegion != region {
+			region = bucketRegion
+			continue
+		}
+		return err
+	}
+}
+
+func tryPutS3Object(ctx context.Context, cfg aws.Config, region, bucket, key string, body []byte, contentType, contentEncoding string) (bucketRegion string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s3ObjectURL(bucket, region, key), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", classifyAWSError(fmt.Errorf("failed to retrieve credentials: %w", err))
+	}
+	signer := v4.NewSigner(func(o *v4.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	if err := signer.SignHTTP(ctx, creds, req, payloadHash, "s3", region, time.Now()); err != nil {
+		return "", err
+	}
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return "", nil
+	}
+	data, _ := io.ReadAll(resp.Body)
+	var apiErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.Unmarshal(data, &apiErr)
+	if apiErr.Code == "" {
+		apiErr.Code = resp.Status
+	}
+	return resp.Header.Get("X-Amz-Bucket-Region"), classifyAWSError(&smithy.OperationError{
+		ServiceID:     "S3",
+		OperationName: "PutObject",
+		Err:           &smithy.GenericAPIError{Code: apiErr.Code, Message: apiErr.Message},
+	})
+}
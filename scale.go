@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+// scaleTarget is the desired capacity asked for with --desired, --bump or --to-min
+type scaleTarget struct {
+	desired int32 // -1 when unset
+	bump    int32
+	toMin   bool
+}
+
+// count returns how many of --desired, --bump and --to-min are set
+func (t scaleTarget) count() int {
+	n := 0
+	for _, set := range []bool{t.desired >= 0, t.bump != 0, t.toMin} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// resolve returns the desired capacity the target asks for, given the ASG's current capacity
+func (t scaleTarget) resolve(capacity ASGCapacity) int32 {
+	switch {
+	case t.toMin:
+		return capacity.Min
+	case t.bump != 0:
+		return capacity.Desired + t.bump
+	default:
+		return t.desired
+	}
+}
+
+// scaleASG sets the ASG's desired capacity. Values outside min/max are refused rather than
+// changing the limits, and scaling the ASG of an EKS managed node group is warned about since
+// EKS owns its size.
+func scaleASG(asgName string, target scaleTarget, dryRun bool) {
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	asg, region, err := locateASG(awsConfig, asgName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error describing ASG %s: %v\n", asgName, err)
+		os.Exit(1)
+	}
+	client := autoscaling.NewFromConfig(awsConfig, func(o *autoscaling.Options) { o.Region = region })
+	capacity := ASGCapacity{
+		Min:     aws.ToInt32(asg.MinSize),
+		Max:     aws.ToInt32(asg.MaxSize),
+		Desired: aws.ToInt32(asg.DesiredCapacity),
+	}
+	desired := target.resolve(capacity)
+
+	if desired < capacity.Min || desired > capacity.Max {
+		fmt.Fprintf(os.Stderr, "Error: desired capacity %d is outside the ASG's min/max of %d/%d, change its limits first\n",
+			desired, capacity.Min, capacity.Max)
+		os.Exit(1)
+	}
+	if nodegroup := asgTagValue(*asg, "eks:nodegroup-name"); nodegroup != "" {
+		warnf("%s belongs to EKS managed node group %s, which may reset its size; prefer\n"+
+			"  aws eks update-nodegroup-config --cluster-name %s --nodegroup-name %s --scaling-config desiredSize=%d\n",
+			asgName, nodegroup, valueOrDash(asgTagValue(*asg, "eks:cluster-name")), nodegroup, desired)
+	}
+	if desired == capacity.Desired {
+		fmt.Printf("asg/%s already at desired capacity %d (%s)\n", asgName, desired, capacity)
+		return
+	}
+	if dryRun {
+		fmt.Printf("asg/%s desired capacity would change %d -> %d (dry run)\n", asgName, capacity.Desired, desired)
+		return
+	}
+
+	_, err = client.SetDesiredCapacity(context.TODO(), &autoscaling.SetDesiredCapacityInput{
+		AutoScalingGroupName: aws.String(asgName),
+		DesiredCapacity:      aws.Int32(desired),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting desired capacity of %s: %v\n", asgName, classifyAWSError(err))
+		os.Exit(1)
+	}
+	capacity.Desired = desired
+	fmt.Printf("asg/%s scaled (min/max/desired %s)\n", asgName, capacity)
+}
+
+func asgTagValue(asg astypes.AutoScalingGroup, key string) string {
+	for _, tag := range asg.Tags {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// This file holds a small subset of the scheduler's node filtering, enough to
+// tell whether a pod could land on a node. It ignores inter-pod affinity,
+// topology spread and volume constraints.
+
+// toleratesNodeTaints reports whether the pod tolerates every NoSchedule/NoExecute taint of the node
+func toleratesNodeTaints(pod v1.Pod, node v1.Node) bool {
+	return untoleratedTaint(pod, node) == nil
+}
+
+// untoleratedTaint returns the first NoSchedule/NoExecute taint the pod does not tolerate
+func untoleratedTaint(pod v1.Pod, node v1.Node) *v1.Taint {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range pod.Spec.Tolerations {
+			if pod.Spec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return taint
+		}
+	}
+	return nil
+}
+
+// matchesNodeSelector checks spec.nodeSelector
+func matchesNodeSelector(pod v1.Pod, node v1.Node) bool {
+	for key, value := range pod.Spec.NodeSelector {
+		if node.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesNodeAffinity checks requiredDuringSchedulingIgnoredDuringExecution node affinity
+func matchesNodeAffinity(pod v1.Pod, node v1.Node) bool {
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	// Terms are ORed, requirements within a term are ANDed
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	for _, term := range terms {
+		if matchesNodeSelectorTerm(term, node) {
+			return true
+		}
+	}
+	return len(terms) == 0
+}
+
+func matchesNodeSelectorTerm(term v1.NodeSelectorTerm, node v1.Node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, req := range term.MatchExpressions {
+		value, exists := node.Labels[req.Key]
+		if !matchesRequirement(req, value, exists) {
+			return false
+		}
+	}
+	for _, req := range term.MatchFields {
+		// metadata.name is the only supported field
+		if req.Key != "metadata.name" || !matchesRequirement(req, node.Name, true) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesRequirement(req v1.NodeSelectorRequirement, value string, exists bool) bool {
+	switch req.Operator {
+	case v1.NodeSelectorOpIn:
+		return exists && containsString(req.Values, value)
+	case v1.NodeSelectorOpNotIn:
+		return !exists || !containsString(req.Values, value)
+	case v1.NodeSelectorOpExists:
+		return exists
+	case v1.NodeSelectorOpDoesNotExist:
+		return !exists
+	case v1.NodeSelectorOpGt, v1.NodeSelectorOpLt:
+		if !exists || len(req.Values) != 1 {
+			return false
+		}
+		actual, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		limit, err := strconv.ParseInt(req.Values[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if req.Operator == v1.NodeSelectorOpGt {
+			return actual > limit
+		}
+		return actual < limit
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isDaemonSetPod(pod v1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// podOwner returns "Kind/name" of the pod's controller, or "<none>" for bare pods
+func podOwner(pod v1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller {
+			return owner.Kind + "/" + owner.Name
+		}
+	}
+	return "<none>"
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Ports the control plane and nodes must reach each other on
+const (
+	kubeletPort   = 10250 // control plane to kubelet: logs, exec, port-forward
+	webhookPort   = 443   // control plane to webhook pods with the default port
+	apiServerPort = 443   // nodes to the API server endpoint ENIs
+)
+
+// eksClusterVPCConfig is the part of EKS DescribeCluster used here
+type eksClusterVPCConfig struct {
+	ClusterSecurityGroupID string   `json:"clusterSecurityGroupId"`
+	SecurityGroupIDs       []string `json:"securityGroupIds"`
+}
+
+func newEKSClient(cfg aws.Config) *awsJSONClient {
+	host := "eks." + cfg.Region + ".amazonaws.com"
+	if strings.HasPrefix(cfg.Region, "cn-") {
+		host += ".cn"
+	}
+	return &awsJSONClient{
+		cfg:         cfg,
+		serviceID:   "EKS",
+		signingName: "eks",
+		region:      cfg.Region,
+		endpoint:    "https://" + host + "/",
+	}
+}
+
+func describeEKSClusterVPCConfig(ctx context.Context, client *awsJSONClient, clusterName string) (*eksClusterVPCConfig, error) {
+	var output struct {
+		Cluster struct {
+			ResourcesVpcConfig eksClusterVPCConfig `json:"resourcesVpcConfig"`
+		} `json:"cluster"`
+	}
+	if err := client.get(ctx, "DescribeCluster", "/clusters/"+url.PathEscape(clusterName), &output); err != nil {
+		return nil, err
+	}
+	return &output.Cluster.ResourcesVpcConfig, nil
+}
+
+func runSGCheck(args []string) {
+	fs := flag.NewFlagSet("sg-check", flag.ExitOnError)
+	clusterName := fs.String("cluster", "", "EKS cluster name (default: detected from the nodes' kubernetes.io/cluster/ tag)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s sg-check [--cluster NAME]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Check that each node can talk to the EKS control plane: either the node has the cluster security\n")
+		fmt.Fprintf(os.Stderr, "group, or its security groups allow the control plane to reach the kubelet (%d) and webhooks (%d)\n", kubeletPort, webhookPort)
+		fmt.Fprintf(os.Stderr, "and the control plane security groups allow the node on %d. Exits with status 1 if a node is flagged.\n\n", apiServerPort)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *clusterName == "" {
+		registered := make(map[string]bool)
+		for _, id := range nodeInstanceIDs(nodes.Items) {
+			registered[id] = true
+		}
+		*clusterName = detectClusterName(instanceMap, registered)
+		if *clusterName == "" {
+			fmt.Fprintf(os.Stderr, "Error: could not detect the cluster name from instance tags, pass --cluster\n")
+			os.Exit(1)
+		}
+	}
+
+	ctx := context.TODO()
+	vpcConfig, err := describeEKSClusterVPCConfig(ctx, newEKSClient(awsConfig), *clusterName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error describing EKS cluster '%s': %v\n", *clusterName, err)
+		os.Exit(1)
+	}
+
+	// The control plane ENIs carry the cluster security group and the additional ones, in the
+	// cluster's region; the nodes' groups are described in the region of each instance
+	controlPlaneSGs := append([]string{vpcConfig.ClusterSecurityGroupID}, vpcConfig.SecurityGroupIDs...)
+	sgIDsByRegion := map[string]map[string]bool{awsConfig.Region: {}}
+	for _, id := range controlPlaneSGs {
+		sgIDsByRegion[awsConfig.Region][id] = true
+	}
+	for _, node := range nodes.Items {
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists {
+			continue
+		}
+		region := nodeRegion(node, awsConfig.Region)
+		if sgIDsByRegion[region] == nil {
+			sgIDsByRegion[region] = make(map[string]bool)
+		}
+		for _, group := range instance.SecurityGroups {
+			sgIDsByRegion[region][aws.ToString(group.GroupId)] = true
+		}
+	}
+	securityGroups := make(map[string]types.SecurityGroup)
+	for region, sgIDs := range sgIDsByRegion {
+		client := ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = region })
+		regionGroups, err := getSecurityGroups(client, sgIDs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting security groups in %s: %v\n", region, err)
+			os.Exit(1)
+		}
+		for id, group := range regionGroups {
+			securityGroups[id] = group
+		}
+	}
+
+	flagged := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NODE\tINSTANCE-ID\tSECURITY-GROUPS\tCLUSTER-SG\tSTATUS")
+	sort.Slice(nodes.Items, func(i, j int) bool { return nodes.Items[i].Name < nodes.Items[j].Name })
+	for _, node := range nodes.Items {
+		instanceID := getInstanceID(node)
+		instance, exists := instanceMap[instanceID]
+		if !exists {
+			continue
+		}
+
+		var nodeSGs []string
+		hasClusterSG := false
+		for _, group := range instance.SecurityGroups {
+			id := aws.ToString(group.GroupId)
+			nodeSGs = append(nodeSGs, id)
+			if id == vpcConfig.ClusterSecurityGroupID {
+				hasClusterSG = true
+			}
+		}
+
+		status := "ok"
+		if !hasClusterSG {
+			var issues []string
+			if !sgAllows(securityGroups, nodeSGs, controlPlaneSGs, kubeletPort) {
+				issues = append(issues, fmt.Sprintf("no ingress from control plane on %d (logs/exec)", kubeletPort))
+			}
+			if !sgAllows(securityGroups, nodeSGs, controlPlaneSGs, webhookPort) {
+				issues = append(issues, fmt.Sprintf("no ingress from control plane on %d (webhooks)", webhookPort))
+			}
+			if !sgAllows(securityGroups, controlPlaneSGs, nodeSGs, apiServerPort) {
+				issues = append(issues, fmt.Sprintf("control plane has no ingress from node on %d (API)", apiServerPort))
+			}
+			if len(issues) > 0 {
+				flagged++
+				status = strings.Join(issues, "; ")
+			} else {
+				status = "ok (rules)"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", node.Name, instanceID, strings.Join(nodeSGs, ","), formatFlag(hasClusterSG), status)
+	}
+	w.Flush()
+
+	if flagged > 0 {
+		fmt.Printf("\n%d node(s) lack the cluster security group %s and rules to reach the control plane\n", flagged, vpcConfig.ClusterSecurityGroupID)
+		os.Exit(1)
+	}
+}
+
+func getSecurityGroups(client *ec2.Client, ids map[string]bool) (map[string]types.SecurityGroup, error) {
+	var groupIDs []string
+	for id := range ids {
+		if id != "" {
+			groupIDs = append(groupIDs, id)
+		}
+	}
+
+	groups := make(map[string]types.SecurityGroup)
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(client, &ec2.DescribeSecurityGroupsInput{GroupIds: groupIDs})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, classifyAWSError(err)
+		}
+		for _, group := range result.SecurityGroups {
+			groups[aws.ToString(group.GroupId)] = group
+		}
+	}
+	return groups, nil
+}
+
+// sgAllows reports whether any of the target security groups allows TCP port from one of the
+// source security groups. Rules that reference sources by CIDR are counted as allowing it, since
+// the control plane ENI addresses are not known here.
+func sgAllows(groups map[string]types.SecurityGroup, targets, sources []string, port int32) bool {
+	sourceSet := make(map[string]bool)
+	for _, id := range sources {
+		sourceSet[id] = true
+	}
+	for _, id := range targets {
+		for _, permission := range groups[id].IpPermissions {
+			if !permissionCoversPort(permission, port) {
+				continue
+			}
+			if len(permission.IpRanges) > 0 || len(permission.Ipv6Ranges) > 0 {
+				return true
+			}
+			for _, pair := range permission.UserIdGroupPairs {
+				if sourceSet[aws.ToString(pair.GroupId)] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func permissionCoversPort(permission types.IpPermission, port int32) bool {
+	switch aws.ToString(permission.IpProtocol) {
+	case "-1":
+		return true
+	case "tcp", "6":
+		return aws.ToInt32(permission.FromPort) <= port && port <= aws.ToInt32(permission.ToPort)
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// nodeSortKeys are the --sort-by keys, each ordering nodes ascending
+var nodeSortKeys = map[string]func(a, b NodeInfo) bool{
+	"name": func(a, b NodeInfo) bool { return a.Name < b.Name },
+	// Oldest first, like sorting by .metadata.creationTimestamp in kubectl
+	"age": func(a, b NodeInfo) bool { return a.Created.Before(b.Created) },
+	"cpu-free": func(a, b NodeInfo) bool {
+		return calculateFreePercentage(a.CPUCapacity, a.CPURequested) < calculateFreePercentage(b.CPUCapacity, b.CPURequested)
+	},
+	"mem-free": func(a, b NodeInfo) bool {
+		return calculateFreePercentage(a.MemCapacity, a.MemRequested) < calculateFreePercentage(b.MemCapacity, b.MemRequested)
+	},
+	"eph-free": func(a, b NodeInfo) bool {
+		return calculateFreePercentage(a.EphCapacity, a.EphRequested) < calculateFreePercentage(b.EphCapacity, b.EphRequested)
+	},
+	"overcommit":    func(a, b NodeInfo) bool { return overcommitRatio(a) < overcommitRatio(b) },
+	"pods":          func(a, b NodeInfo) bool { return a.PodCount < b.PodCount },
+	"instance-type": func(a, b NodeInfo) bool { return a.InstanceType < b.InstanceType },
+	"asg":           func(a, b NodeInfo) bool { return a.ASG < b.ASG },
+}
+
+// nodeSortKeyNames lists the --sort-by keys for help and error messages
+const nodeSortKeyNames = "name, age, cpu-free, mem-free, eph-free, overcommit, pods, instance-type, asg"
+
+func validateSortKey(key string) error {
+	if _, exists := nodeSortKeys[strings.ToLower(key)]; !exists {
+		return fmt.Errorf("unknown sort key '%s', supported: %s", key, nodeSortKeyNames)
+	}
+	return nil
+}
+
+// sortNodeInfos orders the nodes by a --sort-by key, keeping the name order between equal nodes
+func sortNodeInfos(nodes []NodeInfo, key string) {
+	less := nodeSortKeys[strings.ToLower(key)]
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if less(nodes[i], nodes[j]) {
+			return true
+		}
+		if less(nodes[j], nodes[i]) {
+			return false
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSortNodeInfos(t *testing.T) {
+	now := time.Now()
+	nodes := []NodeInfo{
+		{Name: "node-c", Created: now.Add(-1 * time.Hour), PodCount: 5, InstanceType: "m5.large", ASG: "workers",
+			CPUCapacity: quantity("2"), CPURequested: quantity("1")},
+		{Name: "node-a", Created: now.Add(-3 * time.Hour), PodCount: 5, InstanceType: "c5.xlarge", ASG: "workers",
+			CPUCapacity: quantity("4"), CPURequested: quantity("3")},
+		{Name: "node-b", Created: now.Add(-2 * time.Hour), PodCount: 2, InstanceType: "m5.large", ASG: "batch",
+			CPUCapacity: quantity("2"), CPURequested: quantity("200m")},
+	}
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"name", []string{"node-a", "node-b", "node-c"}},
+		{"age", []string{"node-a", "node-b", "node-c"}},
+		// Least free first
+		{"cpu-free", []string{"node-a", "node-c", "node-b"}},
+		// Ties keep the name order
+		{"pods", []string{"node-b", "node-a", "node-c"}},
+		{"instance-type", []string{"node-a", "node-b", "node-c"}},
+		{"asg", []string{"node-b", "node-a", "node-c"}},
+		{"ASG", []string{"node-b", "node-a", "node-c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			sorted := append([]NodeInfo(nil), nodes...)
+			sortNodeInfos(sorted, tt.key)
+			var got []string
+			for _, n := range sorted {
+				got = append(got, n.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortNodeInfos(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSortKey(t *testing.T) {
+	for _, key := range []string{"name", "Cpu-Free", "overcommit"} {
+		if err := validateSortKey(key); err != nil {
+			t.Errorf("validateSortKey(%q) failed: %v", key, err)
+		}
+	}
+	if err := validateSortKey("cpu"); err == nil {
+		t.Error("validateSortKey(\"cpu\") succeeded, want an error")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// spotPoolUsage is the spot capacity of one group: the instance type and zone pools its nodes run in
+type spotPoolUsage struct {
+	Nodes int
+	Pools map[string]bool // "m5.large/us-east-1a"
+}
+
+func runSpotPools(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("spot-pools", flag.ExitOnError)
+	minPools := fs.Int("min-pools", 6, "Flag groups configured with fewer instance type and zone pools than this")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s spot-pools [--min-pools N]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "For each group running spot nodes, count the spot capacity pools (instance type and zone pairs)\n")
+		fmt.Fprintf(os.Stderr, "its nodes run in against the pools its ASG is configured for. Few pools mean one capacity\n")
+		fmt.Fprintf(os.Stderr, "shortage interrupts many nodes at once.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error listing nodes: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodes.Items)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting EC2 instances: %v\n", err)
+		os.Exit(1)
+	}
+
+	groups := make(map[string]*spotPoolUsage)
+	asgOfGroup := make(map[string]string)
+	for _, node := range nodes.Items {
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists || instanceLifecycle(instance) != "spot" {
+			continue
+		}
+		name := getGroupFromTags(instance.Tags, groupTagKeys)
+		if name == "" {
+			name = "<none>"
+		}
+		if groups[name] == nil {
+			groups[name] = &spotPoolUsage{Pools: make(map[string]bool)}
+		}
+		groups[name].Nodes++
+		groups[name].Pools[string(instance.InstanceType)+"/"+getNodeZone(node)] = true
+		if asgName := instanceASG(instance); asgName != "" {
+			asgOfGroup[name] = asgName
+		}
+	}
+
+	if len(groups) == 0 {
+		fmt.Println("No spot nodes found")
+		return
+	}
+
+	asgs, err := getNodeASGs(awsConfig, nodes.Items)
+	if err != nil {
+		warnf("could not describe Auto Scaling Groups, configured pools are not shown: %v\n", err)
+	}
+
+	var names []string
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tSPOT-NODES\tPOOLS-IN-USE\tPOOLS-CONFIGURED\tTYPES\tZONES\tSTRATEGY\tLOW-DIVERSITY")
+	for _, name := range names {
+		usage := groups[name]
+		configured, types, zones, strategy := "-", "-", "-", "-"
+		// Karpenter and self-managed groups without an ASG only show what is in use
+		lowDiversity := len(usage.Pools) < *minPools
+		if asg, exists := asgs[asgOfGroup[name]]; exists {
+			// Launch templates are described in the ASG's own region
+			region := awsConfig.Region
+			if len(asg.AvailabilityZones) > 0 {
+				region = regionOfZone(asg.AvailabilityZones[0], awsConfig.Region)
+			}
+			instanceTypes := asgInstanceTypes(ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = region }), asg)
+			pools := len(instanceTypes) * len(asg.AvailabilityZones)
+			configured = fmt.Sprintf("%d", pools)
+			types = fmt.Sprintf("%d", len(instanceTypes))
+			zones = fmt.Sprintf("%d", len(asg.AvailabilityZones))
+			lowDiversity = pools < *minPools
+			if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.InstancesDistribution != nil {
+				strategy = valueOrDash(aws.ToString(asg.MixedInstancesPolicy.InstancesDistribution.SpotAllocationStrategy))
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", name, usage.Nodes, len(usage.Pools),
+			configured, types, zones, strategy, formatFlag(lowDiversity))
+	}
+	w.Flush()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// startSSMSession opens a shell on the node's instance through SSM Session Manager by running
+// 'aws ssm start-session', which needs the AWS CLI and the session-manager-plugin
+func startSSMSession(nodeName string) {
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting node '%s': %v\n", nodeName, err)
+		os.Exit(1)
+	}
+
+	instanceID := getInstanceID(*node)
+	if instanceID == "" {
+		fmt.Fprintf(os.Stderr, "Error: Could not find instance ID for node '%s'\n", nodeName)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if _, err := exec.LookPath("aws"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: the AWS CLI ('aws') is required for --ssm: https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html\n")
+		os.Exit(1)
+	}
+	if _, err := exec.LookPath("session-manager-plugin"); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: the Session Manager plugin is required for --ssm: https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html\n")
+		os.Exit(1)
+	}
+
+	infof("Starting SSM session to node '%s' (instance: %s)...\n", nodeName, instanceID)
+
+	// Without --profile, the profile is passed through AWS_PROFILE in the environment
+	cliArgs := []string{"ssm", "start-session", "--target", instanceID, "--region", nodeRegion(*node, awsConfig.Region)}
+	if opts.awsProfile != "" {
+		cliArgs = append(cliArgs, "--profile", opts.awsProfile)
+	}
+	cmd := exec.Command("aws", cliArgs...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Ctrl-C belongs to the remote shell, not to us
+	signal.Ignore(os.Interrupt)
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "Error starting SSM session: %v\n", err)
+		os.Exit(1)
+	}
+}
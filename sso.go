@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+)
+
+// awsProfile returns the name of the AWS profile in use: --profile, then AWS_PROFILE
+func awsProfile() string {
+	if opts.awsProfile != "" {
+		return opts.awsProfile
+	}
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+func ssoLoginCommand() string {
+	if profile := awsProfile(); profile != "default" {
+		return "aws sso login --profile " + profile
+	}
+	return "aws sso login"
+}
+
+// usesSSO reports whether the active profile gets its credentials from AWS SSO
+func usesSSO(ctx context.Context) bool {
+	shared, err := awsconfig.LoadSharedConfigProfile(ctx, awsProfile())
+	if err != nil {
+		return false
+	}
+	return shared.SSOSessionName != "" || shared.SSOStartURL != ""
+}
+
+func isInteractive() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// ensureSSOSession checks an SSO-backed profile for an expired session and,
+// when running in a terminal, offers to run 'aws sso login'. It returns true
+// if a login was performed and the config should be reloaded.
+func ensureSSOSession(ctx context.Context, cfg aws.Config) bool {
+	if !isInteractive() || !usesSSO(ctx) {
+		return false
+	}
+
+	_, err := cfg.Credentials.Retrieve(ctx)
+	var ssoToken *ssocreds.InvalidTokenError
+	if !errors.As(err, &ssoToken) {
+		return false
+	}
+
+	command := ssoLoginCommand()
+	fmt.Fprintf(os.Stderr, "The AWS SSO session for profile '%s' has expired. Run '%s' now? [y/N] ", awsProfile(), command)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		return false
+	}
+
+	fields := strings.Fields(command)
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		warnf("'%s' failed: %v\n", command, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+// statsSummary is the subset of the kubelet /stats/summary response we use
+type statsSummary struct {
+	Node struct {
+		Fs      *fsStats `json:"fs"`
+		Runtime *struct {
+			ImageFs *fsStats `json:"imageFs"`
+		} `json:"runtime"`
+	} `json:"node"`
+}
+
+type fsStats struct {
+	AvailableBytes *uint64 `json:"availableBytes"`
+	CapacityBytes  *uint64 `json:"capacityBytes"`
+	UsedBytes      *uint64 `json:"usedBytes"`
+}
+
+func getNodeStatsSummary(clientset *kubernetes.Clientset, nodeName string) (*statsSummary, error) {
+	// Query the kubelet through the API server node proxy
+	data, err := clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("stats/summary").
+		DoRaw(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	var summary statsSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (s *statsSummary) nodeFs() *fsStats {
+	if s == nil {
+		return nil
+	}
+	return s.Node.Fs
+}
+
+func (s *statsSummary) imageFs() *fsStats {
+	if s == nil || s.Node.Runtime == nil {
+		return nil
+	}
+	return s.Node.Runtime.ImageFs
+}
+
+func formatFsUsage(fs *fsStats) (used, capacity, usedPercent string) {
+	if fs == nil || fs.UsedBytes == nil || fs.CapacityBytes == nil || *fs.CapacityBytes == 0 {
+		return "-", "-", "-"
+	}
+	used = formatBytes(int64(*fs.UsedBytes))
+	capacity = formatBytes(int64(*fs.CapacityBytes))
+	usedPercent = formatPercent(float64(*fs.UsedBytes) / float64(*fs.CapacityBytes) * 100)
+	return used, capacity, usedPercent
+}
+
+// Kubelet defaults used when the node's configz endpoint is not readable
+const (
+	defaultImageGCHighThresholdPercent = 85
+	defaultNodeFsEvictionHard          = "10%"
+	defaultImageFsEvictionHard         = "15%"
+	// diskWarningMargin is how many percentage points below a threshold a node counts as close to it
+	diskWarningMargin = 5
+)
+
+// kubeletDiskThresholds holds the kubelet's image GC and hard eviction settings for disk
+type kubeletDiskThresholds struct {
+	ImageGCHighThresholdPercent float64
+	NodeFsAvailable             string
+	ImageFsAvailable            string
+}
+
+func defaultKubeletDiskThresholds() kubeletDiskThresholds {
+	return kubeletDiskThresholds{
+		ImageGCHighThresholdPercent: defaultImageGCHighThresholdPercent,
+		NodeFsAvailable:             defaultNodeFsEvictionHard,
+		ImageFsAvailable:            defaultImageFsEvictionHard,
+	}
+}
+
+// getKubeletConfigz reads the running kubelet configuration through the node proxy configz endpoint
+func getKubeletConfigz(clientset *kubernetes.Clientset, nodeName string) ([]byte, error) {
+	return clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(nodeName).
+		SubResource("proxy").
+		Suffix("configz").
+		DoRaw(context.TODO())
+}
+
+// getKubeletDiskThresholds reads the node's disk thresholds from the kubelet configz.
+// Settings missing from it keep the kubelet defaults.
+func getKubeletDiskThresholds(clientset *kubernetes.Clientset, nodeName string) (kubeletDiskThresholds, error) {
+	thresholds := defaultKubeletDiskThresholds()
+	data, err := getKubeletConfigz(clientset, nodeName)
+	if err != nil {
+		return thresholds, err
+	}
+
+	var configz struct {
+		KubeletConfig struct {
+			ImageGCHighThresholdPercent *int32            `json:"imageGCHighThresholdPercent"`
+			EvictionHard                map[string]string `json:"evictionHard"`
+		} `json:"kubeletconfig"`
+	}
+	if err := json.Unmarshal(data, &configz); err != nil {
+		return thresholds, err
+	}
+	if p := configz.KubeletConfig.ImageGCHighThresholdPercent; p != nil {
+		thresholds.ImageGCHighThresholdPercent = float64(*p)
+	}
+	if v, exists := configz.KubeletConfig.EvictionHard["nodefs.available"]; exists {
+		thresholds.NodeFsAvailable = v
+	}
+	if v, exists := configz.KubeletConfig.EvictionHard["imagefs.available"]; exists {
+		thresholds.ImageFsAvailable = v
+	}
+	return thresholds, nil
+}
+
+func fsUsedPercent(fs *fsStats) (float64, bool) {
+	if fs == nil || fs.UsedBytes == nil || fs.CapacityBytes == nil || *fs.CapacityBytes == 0 {
+		return 0, false
+	}
+	return float64(*fs.UsedBytes) / float64(*fs.CapacityBytes) * 100, true
+}
+
+// diskUsedPercent returns the fuller of the node and image filesystems
+func (s *statsSummary) diskUsedPercent() (float64, bool) {
+	nodeFs, okNode := fsUsedPercent(s.nodeFs())
+	imageFs, okImage := fsUsedPercent(s.imageFs())
+	if imageFs > nodeFs {
+		return imageFs, okImage
+	}
+	return nodeFs, okNode
+}
+
+// evictionUsedPercent converts an "available" eviction threshold (e.g. "10%" or "5Gi")
+// into the used percentage of the filesystem at which the kubelet starts evicting
+func evictionUsedPercent(available string, fs *fsStats) (float64, bool) {
+	if strings.HasSuffix(available, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(available, "%"), 64)
+		if err != nil {
+			return 0, false
+		}
+		return 100 - p, true
+	}
+	if fs == nil || fs.CapacityBytes == nil || *fs.CapacityBytes == 0 {
+		return 0, false
+	}
+	q, err := resource.ParseQuantity(available)
+	if err != nil {
+		return 0, false
+	}
+	return 100 - float64(q.Value())/float64(*fs.CapacityBytes)*100, true
+}
+
+// diskWarning flags filesystems that reached, or are within diskWarningMargin of,
+// the image GC or hard eviction thresholds
+func diskWarning(s *statsSummary, thresholds kubeletDiskThresholds) string {
+	var warnings []string
+	check := func(name string, fs *fsStats, threshold float64) {
+		used, ok := fsUsedPercent(fs)
+		switch {
+		case !ok:
+		case used >= threshold:
+			warnings = append(warnings, fmt.Sprintf("%s (%s >= %s)", name, formatPercent(used), formatPercent(threshold)))
+		case used >= threshold-diskWarningMargin:
+			warnings = append(warnings, fmt.Sprintf("near %s (%s)", name, formatPercent(used)))
+		}
+	}
+
+	// Without a dedicated image filesystem, images live on the node filesystem
+	imageFs := s.imageFs()
+	if imageFs == nil {
+		imageFs = s.nodeFs()
+	}
+	check("image GC", imageFs, thresholds.ImageGCHighThresholdPercent)
+	if threshold, ok := evictionUsedPercent(thresholds.NodeFsAvailable, s.nodeFs()); ok {
+		check("nodefs eviction", s.nodeFs(), threshold)
+	}
+	if s.imageFs() != nil {
+		if threshold, ok := evictionUsedPercent(thresholds.ImageFsAvailable, s.imageFs()); ok {
+			check("imagefs eviction", s.imageFs(), threshold)
+		}
+	}
+	return strings.Join(warnings, ", ")
+}
+
+// nodeMetricsList is the subset of the metrics.k8s.io NodeMetricsList we use
+type nodeMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Usage v1.ResourceList `json:"usage"`
+	} `json:"items"`
+}
+
+// getNodeUsage returns current node usage from metrics-server, keyed by node name
+func getNodeUsage(clientset *kubernetes.Clientset) (map[string]v1.ResourceList, error) {
+	data, err := clientset.Discovery().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/nodes").
+		DoRaw(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics nodeMetricsList
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, err
+	}
+	usage := make(map[string]v1.ResourceList)
+	for _, item := range metrics.Items {
+		usage[item.Metadata.Name] = item.Usage
+	}
+	return usage, nil
+}
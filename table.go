@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// nodeTableColumns mirrors the wide and top views. Priority 0 columns are shown
+// by default, priority 1 columns only in wide mode, as with server-side printing.
+var nodeTableColumns = []metav1.TableColumnDefinition{
+	{Name: "Name", Type: "string", Format: "name", Description: "Node name"},
+	{Name: "Status", Type: "string", Description: "Node Ready condition"},
+	{Name: "Age", Type: "string", Description: "Time since the node registered"},
+	{Name: "Version", Type: "string", Description: "Kubelet version"},
+	{Name: "Instance-ID", Type: "string", Description: "EC2 instance ID"},
+	{Name: "Instance-Type", Type: "string", Description: "EC2 instance type"},
+	{Name: "Lifecycle", Type: "string", Priority: 1, Description: "on-demand or spot"},
+	{Name: "Taints", Type: "string", Priority: 1, Description: "Node taints"},
+	{Name: "ASG", Type: "string", Description: "Auto Scaling Group or node group"},
+	{Name: "ASG-Capacity", Type: "string", Priority: 1, Description: "ASG min/max/desired, ! when at max"},
+	{Name: "Pods", Type: "integer", Description: "Pods on the node, excluding static (mirror) pods"},
+	{Name: "CPU-Req", Type: "string", Priority: 1, Description: "CPU requested by pods"},
+	{Name: "CPU-Free%", Type: "number", Description: "Percentage of allocatable CPU not requested"},
+	{Name: "Mem-Req", Type: "string", Priority: 1, Description: "Memory requested by pods"},
+	{Name: "Mem-Free%", Type: "number", Description: "Percentage of allocatable memory not requested"},
+}
+
+// printTable writes the nodes as a meta.k8s.io/v1 Table, the format of server-side
+// printing, with each row carrying the node's PartialObjectMetadata like kubectl requests
+func printTable(out io.Writer, nodeInfos []NodeInfo, nodes []v1.Node) error {
+	objects := make(map[string]v1.Node)
+	for _, node := range nodes {
+		objects[node.Name] = node
+	}
+
+	table := metav1.Table{
+		TypeMeta:          metav1.TypeMeta{Kind: "Table", APIVersion: "meta.k8s.io/v1"},
+		ColumnDefinitions: nodeTableColumns,
+		Rows:              []metav1.TableRow{},
+	}
+	for _, n := range nodeInfos {
+		row := metav1.TableRow{Cells: nodeTableCells(n)}
+
+		if node, exists := objects[n.Name]; exists {
+			meta := metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{Kind: "PartialObjectMetadata", APIVersion: "meta.k8s.io/v1"},
+				ObjectMeta: *node.ObjectMeta.DeepCopy(),
+			}
+			meta.ManagedFields = nil
+			raw, err := json.Marshal(meta)
+			if err != nil {
+				return err
+			}
+			row.Object = runtime.RawExtension{Raw: raw}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(table)
+}
+
+// nodeTableCells returns the values of nodeTableColumns for a node
+func nodeTableCells(n NodeInfo) []interface{} {
+	return []interface{}{
+		n.Name, n.Status, n.Age, n.Version, n.InstanceID, n.InstanceType, n.Lifecycle, n.Taints, n.ASG, n.ASGCapacity,
+		n.PodCount,
+		formatResource(n.CPURequested), roundPercent(calculateFreePercentage(n.CPUCapacity, n.CPURequested)),
+		formatMemory(n.MemRequested), roundPercent(calculateFreePercentage(n.MemCapacity, n.MemRequested)),
+	}
+}
+
+func roundPercent(p float64) float64 {
+	return math.Round(p*10) / 10
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"golang.org/x/term"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// tuiColumn is a column of the interactive node list
+type tuiColumn struct {
+	Name  string
+	Value func(n NodeInfo) string
+	// Less orders the column; string order of Value when nil
+	Less func(a, b NodeInfo) bool
+}
+
+var tuiColumns = []tuiColumn{
+	{Name: "NAME", Value: func(n NodeInfo) string { return n.Name }},
+	{Name: "STATUS", Value: func(n NodeInfo) string { return n.Status }},
+	{
+		Name:  "AGE",
+		Value: func(n NodeInfo) string { return n.Age },
+		Less:  func(a, b NodeInfo) bool { return a.Created.After(b.Created) },
+	},
+	{Name: "INSTANCE-ID", Value: func(n NodeInfo) string { return n.InstanceID }},
+	{Name: "INSTANCE-TYPE", Value: func(n NodeInfo) string { return n.InstanceType }},
+	{Name: "LIFECYCLE", Value: func(n NodeInfo) string { return n.Lifecycle }},
+	{Name: "ZONE", Value: func(n NodeInfo) string { return n.Zone }},
+	{Name: "ASG", Value: func(n NodeInfo) string { return n.ASG }},
+	{
+		Name:  "PODS",
+		Value: func(n NodeInfo) string { return fmt.Sprintf("%d", n.PodCount) },
+		Less:  func(a, b NodeInfo) bool { return a.PodCount < b.PodCount },
+	},
+	{
+		Name:  "CPU-FREE%",
+		Value: func(n NodeInfo) string { return formatPercent(calculateFreePercentage(n.CPUCapacity, n.CPURequested)) },
+		Less: func(a, b NodeInfo) bool {
+			return calculateFreePercentage(a.CPUCapacity, a.CPURequested) < calculateFreePercentage(b.CPUCapacity, b.CPURequested)
+		},
+	},
+	{
+		Name:  "MEM-FREE%",
+		Value: func(n NodeInfo) string { return formatPercent(calculateFreePercentage(n.MemCapacity, n.MemRequested)) },
+		Less: func(a, b NodeInfo) bool {
+			return calculateFreePercentage(a.MemCapacity, a.MemRequested) < calculateFreePercentage(b.MemCapacity, b.MemRequested)
+		},
+	},
+}
+
+// tui is the state of the interactive mode
+type tui struct {
+	clientset    *kubernetes.Clientset
+	awsConfig    aws.Config
+	groupTagKeys []string
+
+	nodes      []NodeInfo
+	podsByNode map[string][]v1.Pod
+	visible    []NodeInfo
+
+	sortColumn int
+	sortDesc   bool
+	filter     string
+	editing    bool // typing a filter
+	cursor     int
+	offset     int
+	details    []string // the details view when set
+	status     string
+}
+
+const tuiHelp = "↑/↓ j/k move  enter details  </> sort column  r reverse  / filter  R reload  q quit"
+
+func runInteractive(groupTagKeys []string) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "Error: --interactive requires a terminal\n")
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	t := &tui{clientset: clientset, awsConfig: awsConfig, groupTagKeys: groupTagKeys}
+	if err := t.load(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		os.Exit(1)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up terminal: %v\n", err)
+		os.Exit(1)
+	}
+	// Alternate screen with hidden cursor, restored on exit
+	fmt.Print("\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Print("\033[?25h\033[?1049l")
+		term.Restore(fd, state)
+	}()
+
+	buf := make([]byte, 16)
+	for {
+		t.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		if !t.handleKey(string(buf[:n])) {
+			return
+		}
+	}
+}
+
+// load fetches the node inventory and pods
+func (t *tui) load() error {
+	nodes, pods, err := collectInventoryAndPods(t.clientset, t.awsConfig, t.groupTagKeys)
+	if err != nil {
+		return err
+	}
+	t.nodes = nodes
+	t.podsByNode = make(map[string][]v1.Pod)
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			t.podsByNode[pod.Spec.NodeName] = append(t.podsByNode[pod.Spec.NodeName], pod)
+		}
+	}
+	t.refresh()
+	return nil
+}
+
+// refresh applies the filter and sort order
+func (t *tui) refresh() {
+	t.visible = nil
+	filter := strings.ToLower(t.filter)
+	for _, n := range t.nodes {
+		if filter == "" || t.matches(n, filter) {
+			t.visible = append(t.visible, n)
+		}
+	}
+
+	column := tuiColumns[t.sortColumn]
+	sort.SliceStable(t.visible, func(i, j int) bool {
+		a, b := t.visible[i], t.visible[j]
+		if t.sortDesc {
+			a, b = b, a
+		}
+		if column.Less != nil {
+			return column.Less(a, b)
+		}
+		return column.Value(a) < column.Value(b)
+	})
+
+	if t.cursor >= len(t.visible) {
+		t.cursor = len(t.visible) - 1
+	}
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+}
+
+// matches reports whether any column of the node contains the lowercase filter
+func (t *tui) matches(n NodeInfo, filter string) bool {
+	for _, column := range tuiColumns {
+		if strings.Contains(strings.ToLower(column.Value(n)), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleKey updates the state for a key press; it returns false to quit
+func (t *tui) handleKey(key string) bool {
+	t.status = ""
+
+	if t.editing {
+		switch key {
+		case "\r", "\n":
+			t.editing = false
+		case "\033":
+			t.editing = false
+			t.filter = ""
+		case "\x7f", "\b":
+			if len(t.filter) > 0 {
+				t.filter = t.filter[:len(t.filter)-1]
+			}
+		case "\x03":
+			return false
+		default:
+			if len(key) == 1 && key[0] >= ' ' {
+				t.filter += key
+			}
+		}
+		t.refresh()
+		return true
+	}
+
+	if t.details != nil {
+		switch key {
+		case "q", "\033", "\r", "\n":
+			t.details = nil
+			t.offset = 0
+		case "j", "\033[B":
+			t.offset++
+		case "k", "\033[A":
+			if t.offset > 0 {
+				t.offset--
+			}
+		case "\x03":
+			return false
+		}
+		return true
+	}
+
+	switch key {
+	case "q", "\x03":
+		return false
+	case "j", "\033[B":
+		if t.cursor < len(t.visible)-1 {
+			t.cursor++
+		}
+	case "k", "\033[A":
+		if t.cursor > 0 {
+			t.cursor--
+		}
+	case "g", "\033[H":
+		t.cursor = 0
+	case "G", "\033[F":
+		t.cursor = len(t.visible) - 1
+		if t.cursor < 0 {
+			t.cursor = 0
+		}
+	case ">", "\033[C":
+		t.sortColumn = (t.sortColumn + 1) % len(tuiColumns)
+		t.refresh()
+	case "<", "\033[D":
+		t.sortColumn = (t.sortColumn + len(tuiColumns) - 1) % len(tuiColumns)
+		t.refresh()
+	case "r":
+		t.sortDesc = !t.sortDesc
+		t.refresh()
+	case "/":
+		t.editing = true
+	case "\033":
+		t.filter = ""
+		t.refresh()
+	case "R":
+		if err := t.load(); err != nil {
+			t.status = "Error " + err.Error()
+		} else {
+			t.status = "Reloaded"
+		}
+	case "\r", "\n":
+		if t.cursor >= 0 && t.cursor < len(t.visible) {
+			t.details = t.nodeDetails(t.visible[t.cursor])
+			t.offset = 0
+		}
+	}
+	return true
+}
+
+// nodeDetails renders the details view: the node's AWS and resource data, the other
+// members of its ASG and its pods
+func (t *tui) nodeDetails(n NodeInfo) []string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	fields := [][2]string{
+		{"Name", n.Name},
+		{"Status", n.Status},
+		{"Age", n.Age},
+		{"Version", n.Version},
+		{"Instance ID", n.InstanceID},
+		{"Instance type", n.InstanceType},
+		{"Lifecycle", n.Lifecycle},
+		{"Zone", n.Zone},
+		{"ASG", n.ASG},
+		{"ASG capacity", n.ASGCapacity},
+		{"Taints", n.Taints},
+		{"CPU", formatResource(n.CPURequested) + " requested of " + formatResource(n.CPUCapacity)},
+		{"Memory", formatMemory(n.MemRequested) + " requested of " + formatMemory(n.MemCapacity)},
+	}
+	if n.Links != nil {
+		fields = append(fields, [2]string{"EC2 console", n.Links.EC2}, [2]string{"ASG console", n.Links.ASG})
+	}
+	for _, field := range fields {
+		value := field[1]
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%s:\t%s\n", field[0], value)
+	}
+	w.Flush()
+
+	if n.ASG != "" {
+		fmt.Fprintf(&buf, "\nASG members:\n")
+		for _, member := range t.nodes {
+			if member.ASG == n.ASG {
+				marker := " "
+				if member.Name == n.Name {
+					marker = "*"
+				}
+				fmt.Fprintf(&buf, " %s %s (%s, %s)\n", marker, member.Name, member.InstanceID, member.Status)
+			}
+		}
+	}
+
+	fmt.Fprintf(&buf, "\nPods:\n")
+	w = tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  NAMESPACE\tNAME\tPHASE")
+	for _, pod := range t.podsByNode[n.Name] {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", pod.Namespace, pod.Name, pod.Status.Phase)
+	}
+	w.Flush()
+
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+}
+
+// draw redraws the whole screen
+func (t *tui) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		// Some terminals (serial consoles, emulators not reporting a size) have no size
+		width, height = 80, 24
+	}
+	// At least the title and the footer
+	if height < 2 {
+		height = 2
+	}
+	// Title and footer take two lines each
+	rows := height - 4
+	if rows < 1 {
+		rows = 1
+	}
+
+	var lines []string
+	title := fmt.Sprintf("kubectl aws-nodes: %d/%d nodes", len(t.visible), len(t.nodes))
+	if t.filter != "" || t.editing {
+		title += fmt.Sprintf("  filter: %s", t.filter)
+	}
+	lines = append(lines, "\033[1m"+truncate(title, width)+"\033[0m", "")
+
+	if t.details != nil {
+		if t.offset > len(t.details)-1 {
+			t.offset = len(t.details) - 1
+		}
+		for i := t.offset; i < len(t.details) && i < t.offset+rows+1; i++ {
+			lines = append(lines, truncate(t.details[i], width))
+		}
+	} else {
+		lines = append(lines, t.listLines(width, rows)...)
+	}
+
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+	footer := tuiHelp
+	if t.editing {
+		footer = "type to filter  enter apply  esc clear"
+	} else if t.details != nil {
+		footer = "↑/↓ j/k scroll  enter/esc/q back"
+	}
+	if t.status != "" {
+		footer = t.status
+	}
+	lines = append(lines[:height-1], "\033[7m"+truncate(footer, width)+"\033[0m")
+
+	// Raw mode doesn't translate newlines
+	fmt.Print("\033[H\033[2J" + strings.Join(lines, "\r\n"))
+}
+
+// listLines renders the node table, scrolled so the cursor is visible
+func (t *tui) listLines(width, rows int) []string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 3, ' ', 0)
+	var header []string
+	for i, column := range tuiColumns {
+		name := column.Name
+		if i == t.sortColumn {
+			if t.sortDesc {
+				name += "▼"
+			} else {
+				name += "▲"
+			}
+		}
+		header = append(header, name)
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, n := range t.visible {
+		var row []string
+		for _, column := range tuiColumns {
+			value := column.Value(n)
+			if value == "" {
+				value = "-"
+			}
+			row = append(row, value)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	table := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+
+	if t.cursor < t.offset {
+		t.offset = t.cursor
+	}
+	if t.cursor >= t.offset+rows {
+		t.offset = t.cursor - rows + 1
+	}
+
+	lines := []string{"\033[1m" + truncate(table[0], width) + "\033[0m"}
+	for i := t.offset; i < len(t.visible) && i < t.offset+rows; i++ {
+		line := truncate(table[i+1], width)
+		if i == t.cursor {
+			line = "\033[7m" + line + "\033[0m"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// truncate cuts s to the terminal width
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
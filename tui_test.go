@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestTUIKeysWithoutVisibleNodes(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{"G then enter", []string{"G", "\r"}},
+		{"end then enter", []string{"\033[F", "\n"}},
+		{"up, down and enter", []string{"k", "j", "\r"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ui := &tui{}
+			for _, key := range tt.keys {
+				if !ui.handleKey(key) {
+					t.Fatalf("handleKey(%q) quit", key)
+				}
+			}
+			if ui.cursor != 0 {
+				t.Errorf("cursor = %d, want 0", ui.cursor)
+			}
+			if ui.details != nil {
+				t.Errorf("details opened without a node: %v", ui.details)
+			}
+		})
+	}
+}
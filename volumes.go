@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ebsVolume is an EBS volume attached to a node's instance
+type ebsVolume struct {
+	ID         string `json:"volumeID"`
+	Device     string `json:"device"`
+	Type       string `json:"type"`
+	SizeGiB    int32  `json:"sizeGiB"`
+	IOPS       int32  `json:"iops,omitempty"`
+	Throughput int32  `json:"throughputMiBps,omitempty"`
+	Root       bool   `json:"root"`
+}
+
+// String summarizes the volume as type, size and performance, e.g. "gp2 20Gi 100iops"
+func (v *ebsVolume) String() string {
+	if v == nil {
+		return ""
+	}
+	parts := []string{v.Type, fmt.Sprintf("%dGi", v.SizeGiB)}
+	if v.IOPS > 0 {
+		parts = append(parts, fmt.Sprintf("%diops", v.IOPS))
+	}
+	if v.Throughput > 0 {
+		parts = append(parts, fmt.Sprintf("%dMiB/s", v.Throughput))
+	}
+	return strings.Join(parts, " ")
+}
+
+// getNodeVolumes describes the EBS volumes attached to the nodes' instances, by instance ID,
+// in the region each node lives in. The root volume is the one on the instance's root device.
+func getNodeVolumes(cfg aws.Config, nodes []v1.Node, instanceMap map[string]types.Instance) (map[string][]ebsVolume, error) {
+	volumes := make(map[string][]ebsVolume)
+	for region, ids := range nodeInstanceIDsByRegion(nodes, cfg.Region) {
+		client := ec2.NewFromConfig(cfg, func(o *ec2.Options) { o.Region = region })
+		for start := 0; start < len(ids); start += describeInstancesChunkSize {
+			end := start + describeInstancesChunkSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+			paginator := ec2.NewDescribeVolumesPaginator(client, &ec2.DescribeVolumesInput{
+				Filters: []types.Filter{{Name: aws.String("attachment.instance-id"), Values: ids[start:end]}},
+			})
+			for paginator.HasMorePages() {
+				result, err := paginator.NextPage(context.TODO())
+				if err != nil {
+					return nil, fmt.Errorf("in %s: %w", region, classifyAWSError(err))
+				}
+				for _, volume := range result.Volumes {
+					for _, attachment := range volume.Attachments {
+						instanceID := aws.ToString(attachment.InstanceId)
+						device := aws.ToString(attachment.Device)
+						volumes[instanceID] = append(volumes[instanceID], ebsVolume{
+							ID:         aws.ToString(volume.VolumeId),
+							Device:     device,
+							Type:       string(volume.VolumeType),
+							SizeGiB:    aws.ToInt32(volume.Size),
+							IOPS:       aws.ToInt32(volume.Iops),
+							Throughput: aws.ToInt32(volume.Throughput),
+							Root:       device == aws.ToString(instanceMap[instanceID].RootDeviceName),
+						})
+					}
+				}
+			}
+		}
+	}
+	return volumes, nil
+}
+
+// rootVolume returns the root volume among an instance's volumes, or nil
+func rootVolume(volumes []ebsVolume) *ebsVolume {
+	for i := range volumes {
+		if volumes[i].Root {
+			return &volumes[i]
+		}
+	}
+	return nil
+}
+
+// hasDiskPressure reports whether the kubelet set the node's DiskPressure condition
+func hasDiskPressure(node v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeDiskPressure {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
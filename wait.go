@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func runWait(args []string, groupTagKeys []string) {
+	fs := flag.NewFlagSet("wait", flag.ExitOnError)
+	asgName := fs.String("asg", "", "Auto Scaling Group (or node group) whose nodes to wait for")
+	ready := fs.Int("ready", 1, "Number of Ready and schedulable nodes to wait for")
+	timeout := fs.Duration("timeout", 10*time.Minute, "Maximum time to wait")
+	interval := fs.Duration("interval", 10*time.Second, "Polling interval")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s wait --asg NAME [--ready N] [--timeout 10m]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Block until N nodes of a group are Ready and schedulable. Exits non-zero on timeout.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	parseCommandFlags(fs, args)
+
+	if *asgName == "" {
+		fmt.Fprintf(os.Stderr, "Error: wait requires --asg\n")
+		os.Exit(1)
+	}
+	if *ready < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --ready must be at least 1\n")
+		os.Exit(1)
+	}
+
+	clientset, err := getClientset()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating Kubernetes client: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+
+	deadline := time.Now().Add(*timeout)
+	for {
+		count, total, err := countReadyGroupNodes(clientset, awsConfig, *asgName, groupTagKeys)
+		if err != nil {
+			warnf("%v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %d/%d nodes ready and schedulable (%d registered)\n",
+				*asgName, count, *ready, total)
+			if count >= *ready {
+				return
+			}
+		}
+
+		if time.Now().Add(*interval).After(deadline) {
+			fmt.Fprintf(os.Stderr, "Error: timed out after %s waiting for %d ready nodes in '%s'\n", *timeout, *ready, *asgName)
+			os.Exit(1)
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// countReadyGroupNodes returns how many registered nodes of the group are Ready and
+// schedulable, and how many are registered in total.
+func countReadyGroupNodes(clientset *kubernetes.Clientset, awsConfig aws.Config, group string, groupTagKeys []string) (int, int, error) {
+	nodeList, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	instanceMap, err := getNodeInstances(context.TODO(), awsConfig, nodeList.Items)
+	if err != nil {
+		return 0, 0, fmt.Errorf("getting EC2 instances: %w", err)
+	}
+
+	ready, total := 0, 0
+	for _, node := range nodeList.Items {
+		instance, exists := instanceMap[getInstanceID(node)]
+		if !exists || getGroupFromTags(instance.Tags, groupTagKeys) != group {
+			continue
+		}
+		total++
+		if getNodeStatus(node) == "Ready" && !node.Spec.Unschedulable {
+			ready++
+		}
+	}
+	return ready, total, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeWatchRetryDelay is how long to wait before re-establishing a failed node watch
+const nodeWatchRetryDelay = 5 * time.Second
+
+// nodeWatchDebounce is how long node changes are collected before redrawing, so a burst of
+// changes (a node group scaling out, a rollout cordoning nodes) causes a single redraw
+const nodeWatchDebounce = 2 * time.Second
+
+// watchNodes redraws the output like `watch kubectl get nodes`: every interval, with fresh
+// AWS data, and whenever a node is added, removed or changes, with the cached AWS data.
+func watchNodes(clientset *kubernetes.Clientset, selector string, interval time.Duration, render func(out io.Writer, refreshAWS bool) error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	changes := make(chan struct{}, 1)
+	go watchNodeChanges(ctx, clientset, selector, changes)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	refreshAWS := true
+	var pending <-chan time.Time
+	for {
+		// Render off-screen first so the previous output stays visible while fetching
+		var buf bytes.Buffer
+		err := render(&buf, refreshAWS)
+		fmt.Print("\033[H\033[2J")
+		fmt.Printf("Every %s: %s   %s\n\n", interval, strings.Join(os.Args[1:], " "), time.Now().Format(time.RFC1123))
+		if err != nil {
+			fmt.Printf("Error %v\n", err)
+		}
+		os.Stdout.Write(buf.Bytes())
+
+		pending = nil
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// The refresh also shows any pending change
+				refreshAWS = true
+				break wait
+			case <-changes:
+				if pending == nil {
+					pending = time.After(nodeWatchDebounce)
+				}
+			case <-pending:
+				refreshAWS = false
+				break wait
+			}
+		}
+	}
+}
+
+// watchNodeChanges signals on changes whenever a node matching selector is added, removed or
+// changes in a way the output shows. Changes that arrive while a redraw is pending are coalesced
+// into it.
+func watchNodeChanges(ctx context.Context, clientset *kubernetes.Clientset, selector string, changes chan<- struct{}) {
+	for ctx.Err() == nil {
+		if err := watchNodeEvents(ctx, clientset, selector, changes); err != nil && ctx.Err() == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(nodeWatchRetryDelay):
+			}
+		}
+	}
+}
+
+func watchNodeEvents(ctx context.Context, clientset *kubernetes.Clientset, selector string, changes chan<- struct{}) error {
+	// Start from the current nodes and resource version so the initial state doesn't count as a change
+	list, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+	states := make(map[string]string, len(list.Items))
+	for _, node := range list.Items {
+		states[node.Name] = nodeWatchState(node)
+	}
+	watcher, err := clientset.CoreV1().Nodes().Watch(ctx, metav1.ListOptions{LabelSelector: selector, ResourceVersion: list.ResourceVersion})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		node, ok := event.Object.(*v1.Node)
+		switch {
+		case event.Type == watch.Error || !ok:
+			return fmt.Errorf("node watch failed")
+		case event.Type == watch.Deleted:
+			delete(states, node.Name)
+		default:
+			// The kubelet updates its node status every few seconds with fresh heartbeats,
+			// so only signal changes to what is shown
+			state := nodeWatchState(*node)
+			if states[node.Name] == state {
+				continue
+			}
+			states[node.Name] = state
+		}
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// nodeWatchState summarizes the parts of a node the output depends on: labels, scheduling, taints,
+// capacity and condition statuses, leaving out heartbeat and transition times, images and volumes
+func nodeWatchState(node v1.Node) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v|%v|%s|%v|", node.Labels, node.Spec.Unschedulable, node.Spec.ProviderID, node.Spec.Taints)
+	var resources []string
+	for name, quantity := range node.Status.Allocatable {
+		resources = append(resources, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	sort.Strings(resources)
+	b.WriteString(strings.Join(resources, ","))
+	for _, condition := range node.Status.Conditions {
+		fmt.Fprintf(&b, "|%s=%s", condition.Type, condition.Status)
+	}
+	return b.String()
+}
+
+// hasInstances reports whether all instance IDs are in instanceMap
+func hasInstances(instanceMap map[string]types.Instance, instanceIDs []string) bool {
+	for _, id := range instanceIDs {
+		if _, exists := instanceMap[id]; !exists {
+			return false
+		}
+	}
+	return true
+}
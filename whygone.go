@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxActivityPages bounds how far back the ASG activity history is searched
+const maxActivityPages = 5
+
+// errInstanceNotFound is returned by findInstance when no region has the instance
+var errInstanceNotFound = errors.New("no EC2 instance found")
+
+// privateDNSRegion matches the region in EC2 private hostnames, ip-10-0-1-5.us-west-2.compute.internal
+// or i-0123456789abcdef0.us-west-2.compute.internal; us-east-1 uses ip-10-0-1-5.ec2.internal
+var privateDNSRegion = regexp.MustCompile(`\.([a-z]{2}(?:-[a-z]+)+-\d+)\.compute\.internal$`)
+
+// hostnameRegion returns the region of an EC2 private hostname, empty if it has none
+func hostnameRegion(hostname string) string {
+	if strings.HasSuffix(hostname, ".ec2.internal") {
+		return "us-east-1"
+	}
+	if match := privateDNSRegion.FindStringSubmatch(hostname); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func runWhyGone(args []string) {
+	fs := flag.NewFlagSet("why-gone", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s why-gone NODE_NAME|INSTANCE_ID\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Explain why a recently removed node or instance is gone, using EC2 state, spot\n")
+		fmt.Fprintf(os.Stderr, "interruption records, ASG activities and Kubernetes events.\n")
+	}
+	parseCommandFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	target, err := parseNodeArg(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	awsConfig, err := loadAWSConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
+		os.Exit(1)
+	}
+	// The node may be gone, so its region comes from its hostname or else the cluster's regions are searched
+	var instance *types.Instance
+	var region string
+	regions := clusterRegions(awsConfig)
+	if hostRegion := hostnameRegion(target); hostRegion != "" {
+		regions = []string{hostRegion}
+	}
+	for _, region = range regions {
+		instance, err = findInstance(ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = region }), target)
+		if instance != nil || !errors.Is(err, errInstanceNotFound) {
+			break
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	ec2Client := ec2.NewFromConfig(awsConfig, func(o *ec2.Options) { o.Region = region })
+	asgClient := autoscaling.NewFromConfig(awsConfig, func(o *autoscaling.Options) { o.Region = region })
+	instanceID := aws.ToString(instance.InstanceId)
+	nodeName := aws.ToString(instance.PrivateDnsName)
+	if !instanceIDPattern.MatchString(target) {
+		nodeName = target
+	}
+
+	var reasons []string
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Node:\t%s\n", nodeName)
+	fmt.Fprintf(w, "Instance:\t%s (%s)\n", instanceID, instance.InstanceType)
+	if instance.State != nil {
+		fmt.Fprintf(w, "State:\t%s\n", instance.State.Name)
+	}
+	if instance.LaunchTime != nil {
+		fmt.Fprintf(w, "Launched:\t%s\n", instance.LaunchTime.Format(time.RFC3339))
+	}
+	if reason := aws.ToString(instance.StateTransitionReason); reason != "" {
+		fmt.Fprintf(w, "Transition:\t%s\n", reason)
+		reasons = append(reasons, reason)
+	}
+	if instance.StateReason != nil {
+		reason := fmt.Sprintf("%s: %s", aws.ToString(instance.StateReason.Code), aws.ToString(instance.StateReason.Message))
+		fmt.Fprintf(w, "State reason:\t%s\n", reason)
+		reasons = append(reasons, reason)
+	}
+
+	// Spot interruptions are the most specific explanation, so they go first
+	if requestID := aws.ToString(instance.SpotInstanceRequestId); requestID != "" {
+		status, err := getSpotRequestStatus(ec2Client, requestID)
+		if err != nil {
+			warnf("could not get spot request %s: %v\n", requestID, err)
+		} else if status != nil {
+			reason := fmt.Sprintf("spot %s: %s", aws.ToString(status.Code), aws.ToString(status.Message))
+			fmt.Fprintf(w, "Spot request:\t%s %s\n", requestID, reason)
+			if strings.Contains(aws.ToString(status.Code), "terminated") || strings.Contains(aws.ToString(status.Code), "marked-for") {
+				reasons = append([]string{reason}, reasons...)
+			}
+		}
+	}
+
+	if asgName := instanceASG(*instance); asgName != "" {
+		fmt.Fprintf(w, "ASG:\t%s\n", asgName)
+		activity, err := findInstanceActivity(asgClient, asgName, instanceID)
+		if err != nil {
+			warnf("could not get ASG activities for %s: %v\n", asgName, err)
+		} else if activity != nil {
+			fmt.Fprintf(w, "ASG activity:\t%s %s\n", aws.ToTime(activity.StartTime).Format(time.RFC3339), aws.ToString(activity.Description))
+			fmt.Fprintf(w, "ASG cause:\t%s\n", aws.ToString(activity.Cause))
+			reasons = append([]string{aws.ToString(activity.Cause)}, reasons...)
+		}
+	}
+	w.Flush()
+
+	if clientset, err := getClientset(); err == nil {
+		printNodeEvents(clientset, nodeName)
+	}
+
+	fmt.Println()
+	if len(reasons) == 0 {
+		fmt.Println("Likely reason: unknown (no termination records found; the instance may still exist or the records have expired)")
+		return
+	}
+	fmt.Printf("Likely reason: %s\n", reasons[0])
+}
+
+// findInstance looks up an instance by ID or by the node's private DNS name, which is the
+// IP-based or, e.g. on IPv6 clusters, the resource-based hostname.
+// Terminated instances remain visible to DescribeInstances for about an hour.
+func findInstance(client *ec2.Client, target string) (*types.Instance, error) {
+	input := &ec2.DescribeInstancesInput{}
+	if instanceIDPattern.MatchString(target) {
+		input.InstanceIds = []string{target}
+	} else {
+		input.Filters = []types.Filter{{Name: aws.String("private-dns-name"), Values: []string{target}}}
+	}
+
+	notFound := fmt.Errorf("%w for '%s' (records of terminated instances expire after about an hour)", errInstanceNotFound, target)
+	result, err := client.DescribeInstances(context.TODO(), input)
+	if err != nil {
+		// Unlike a filter, an instance ID that isn't in the region is an error, so the next region is tried
+		if isInstanceIDNotFound(err) {
+			return nil, notFound
+		}
+		return nil, classifyAWSError(err)
+	}
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			return &instance, nil
+		}
+	}
+	return nil, notFound
+}
+
+// isInstanceIDNotFound reports whether DescribeInstances rejected the instance ID as unknown in
+// the region or as malformed, either way meaning there is no such instance there
+func isInstanceIDNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode() == "InvalidInstanceID.NotFound" || apiErr.ErrorCode() == "InvalidInstanceID.Malformed")
+}
+
+func getSpotRequestStatus(client *ec2.Client, requestID string) (*types.SpotInstanceStatus, error) {
+	result, err := client.DescribeSpotInstanceRequests(context.TODO(), &ec2.DescribeSpotInstanceRequestsInput{
+		SpotInstanceRequestIds: []string{requestID},
+	})
+	if err != nil {
+		return nil, classifyAWSError(err)
+	}
+	if len(result.SpotInstanceRequests) == 0 {
+		return nil, nil
+	}
+	return result.SpotInstanceRequests[0].Status, nil
+}
+
+// findInstanceActivity returns the most recent ASG activity mentioning the instance
+func findInstanceActivity(client *autoscaling.Client, asgName, instanceID string) (*astypes.Activity, error) {
+	paginator := autoscaling.NewDescribeScalingActivitiesPaginator(client, &autoscaling.DescribeScalingActivitiesInput{
+		AutoScalingGroupName: aws.String(asgName),
+	})
+	for page := 0; page < maxActivityPages && paginator.HasMorePages(); page++ {
+		result, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, classifyAWSError(err)
+		}
+		for _, activity := range result.Activities {
+			if strings.Contains(aws.ToString(activity.Description), instanceID) &&
+				strings.HasPrefix(aws.ToString(activity.Description), "Terminating") {
+				return &activity, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func printNodeEvents(clientset *kubernetes.Clientset, nodeName string) {
+	selector := fields.SelectorFromSet(fields.Set{"involvedObject.kind": "Node", "involvedObject.name": nodeName})
+	events, err := clientset.CoreV1().Events("").List(context.TODO(), metav1.ListOptions{FieldSelector: selector.String()})
+	if err != nil || len(events.Items) == 0 {
+		return
+	}
+
+	sort.Slice(events.Items, func(i, j int) bool {
+		return eventTime(events.Items[i]).Before(eventTime(events.Items[j]))
+	})
+
+	fmt.Println()
+	fmt.Println("Kubernetes events:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  TIME\tREASON\tSOURCE\tMESSAGE")
+	for _, event := range events.Items {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", eventTime(event).Format(time.RFC3339), event.Reason,
+			event.Source.Component, strings.TrimSpace(event.Message))
+	}
+	w.Flush()
+}
+
+func eventTime(event v1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
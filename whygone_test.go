@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestIsInstanceIDNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound"}, true},
+		{&smithy.GenericAPIError{Code: "InvalidInstanceID.Malformed"}, true},
+		{fmt.Errorf("operation error EC2: DescribeInstances: %w", &smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound"}), true},
+		{&smithy.GenericAPIError{Code: "UnauthorizedOperation"}, false},
+		{errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isInstanceIDNotFound(tt.err); got != tt.want {
+			t.Errorf("isInstanceIDNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
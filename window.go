@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// windowTimeLayouts are accepted by --at, with or without seconds
+var windowTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
+
+// maintenanceWindow is when a node action may start, from --at and --window. A zero Length leaves
+// the window open-ended.
+type maintenanceWindow struct {
+	Start  time.Time
+	Length time.Duration
+}
+
+// parseMaintenanceWindow returns nil when no --at was given
+func parseMaintenanceWindow(at string, length time.Duration) (*maintenanceWindow, error) {
+	if at == "" {
+		if length != 0 {
+			return nil, fmt.Errorf("--window requires --at")
+		}
+		return nil, nil
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("--window must not be negative")
+	}
+	for _, layout := range windowTimeLayouts {
+		if start, err := time.Parse(layout, at); err == nil {
+			return &maintenanceWindow{Start: start, Length: length}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid --at '%s', expected a time like 2024-07-01T02:00Z", at)
+}
+
+// check fails once the window is over, so a late start doesn't run outside of it
+func (w *maintenanceWindow) check(now time.Time) error {
+	if w.Length > 0 && !now.Before(w.Start.Add(w.Length)) {
+		return fmt.Errorf("the maintenance window %s ended at %s", w, w.Start.Add(w.Length).Local().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// wait sleeps until the window starts, then checks it hasn't ended
+func (w *maintenanceWindow) wait() error {
+	if err := w.check(time.Now()); err != nil {
+		return err
+	}
+	if delay := time.Until(w.Start); delay > 0 {
+		fmt.Printf("waiting %s for the maintenance window %s\n", delay.Round(time.Second), w)
+		time.Sleep(delay)
+	}
+	return w.check(time.Now())
+}
+
+func (w *maintenanceWindow) String() string {
+	start := w.Start.Local().Format(time.RFC3339)
+	if w.Length == 0 {
+		return "from " + start
+	}
+	return fmt.Sprintf("%s + %s", start, w.Length)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	start := time.Date(2024, 7, 1, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at      string
+		window  time.Duration
+		want    *maintenanceWindow
+		wantErr bool
+	}{
+		{"", 0, nil, false},
+		{"2024-07-01T02:00Z", 0, &maintenanceWindow{Start: start}, false},
+		{"2024-07-01T02:00:00Z", 2 * time.Hour, &maintenanceWindow{Start: start, Length: 2 * time.Hour}, false},
+		{"2024-07-01T04:00+02:00", time.Hour, &maintenanceWindow{Start: start, Length: time.Hour}, false},
+		{"", time.Hour, nil, true},
+		{"2024-07-01T02:00Z", -time.Hour, nil, true},
+		{"tonight", 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMaintenanceWindow(tt.at, tt.window)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMaintenanceWindow(%q, %s) error = %v, wantErr %v", tt.at, tt.window, err, tt.wantErr)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && (!got.Start.Equal(tt.want.Start) || got.Length != tt.want.Length)) {
+			t.Errorf("parseMaintenanceWindow(%q, %s) = %v, want %v", tt.at, tt.window, got, tt.want)
+		}
+	}
+}
+
+func TestMaintenanceWindowCheck(t *testing.T) {
+	start := time.Date(2024, 7, 1, 2, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		window  maintenanceWindow
+		now     time.Time
+		wantErr bool
+	}{
+		{"before the window", maintenanceWindow{Start: start, Length: time.Hour}, start.Add(-time.Hour), false},
+		{"in the window", maintenanceWindow{Start: start, Length: time.Hour}, start.Add(30 * time.Minute), false},
+		{"window over", maintenanceWindow{Start: start, Length: time.Hour}, start.Add(time.Hour), true},
+		{"open-ended", maintenanceWindow{Start: start}, start.Add(24 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.window.check(tt.now); (err != nil) != tt.wantErr {
+			t.Errorf("%s: check() = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
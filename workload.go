@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// workloadNodes returns the names of the nodes running pods of a workload reference
+// in the form [NAMESPACE/]KIND/NAME, e.g. deployment/my-app or prod/sts/db.
+func workloadNodes(clientset *kubernetes.Clientset, ref string) (map[string]bool, error) {
+	parts := strings.Split(ref, "/")
+	namespace := currentNamespace()
+	switch len(parts) {
+	case 2:
+	case 3:
+		namespace = parts[0]
+		parts = parts[1:]
+	default:
+		return nil, fmt.Errorf("invalid workload '%s', expected [NAMESPACE/]KIND/NAME", ref)
+	}
+	kind, name := strings.ToLower(parts[0]), parts[1]
+
+	ctx := context.TODO()
+	apps := clientset.AppsV1()
+	var selector *metav1.LabelSelector
+	switch kind {
+	case "pod", "pods", "po":
+		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]bool{pod.Spec.NodeName: pod.Spec.NodeName != ""}, nil
+	case "deployment", "deployments", "deploy":
+		obj, err := apps.Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = obj.Spec.Selector
+	case "statefulset", "statefulsets", "sts":
+		obj, err := apps.StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = obj.Spec.Selector
+	case "daemonset", "daemonsets", "ds":
+		obj, err := apps.DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = obj.Spec.Selector
+	case "replicaset", "replicasets", "rs":
+		obj, err := apps.ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = obj.Spec.Selector
+	case "job", "jobs":
+		obj, err := clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = obj.Spec.Selector
+	default:
+		return nil, fmt.Errorf("unsupported workload kind '%s' (use pod, deployment, statefulset, daemonset, replicaset or job)", parts[0])
+	}
+
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	if podSelector.Empty() {
+		// An empty selector would match every pod in the namespace
+		return map[string]bool{}, nil
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: podSelector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]bool)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" {
+			nodes[pod.Spec.NodeName] = true
+		}
+	}
+	return nodes, nil
+}
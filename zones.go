@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// zoneSummary aggregates the nodes of one Availability Zone
+type zoneSummary struct {
+	nodes        int
+	ready        int
+	subnets      map[string]bool
+	cpuCapacity  resource.Quantity
+	cpuRequested resource.Quantity
+	memCapacity  resource.Quantity
+	memRequested resource.Quantity
+}
+
+// printZoneSummary writes one row per Availability Zone with its node count, subnets and the total
+// capacity and requests of its nodes, followed by a warning when the zones are unbalanced
+func printZoneSummary(out io.Writer, nodes []NodeInfo) {
+	byZone := make(map[string]*zoneSummary)
+	for _, n := range nodes {
+		zone := n.Zone
+		if zone == "" {
+			zone = "<none>"
+		}
+		summary := byZone[zone]
+		if summary == nil {
+			summary = &zoneSummary{subnets: make(map[string]bool)}
+			byZone[zone] = summary
+		}
+		summary.nodes++
+		if n.Status == "Ready" {
+			summary.ready++
+		}
+		if n.Subnet != "" {
+			summary.subnets[n.Subnet] = true
+		}
+		addQuantity(&summary.cpuCapacity, n.CPUCapacity)
+		addQuantity(&summary.cpuRequested, n.CPURequested)
+		addQuantity(&summary.memCapacity, n.MemCapacity)
+		addQuantity(&summary.memRequested, n.MemRequested)
+	}
+
+	var zones []string
+	for zone := range byZone {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "ZONE\tNODES\tREADY\tSUBNETS\tCPU-CAP\tCPU-REQ\tCPU-FREE%\tMEM-CAP\tMEM-REQ\tMEM-FREE%")
+	fewest, most := "", ""
+	for _, zone := range zones {
+		s := byZone[zone]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			zone, s.nodes, s.ready, joinSet(s.subnets),
+			formatResource(&s.cpuCapacity), formatResource(&s.cpuRequested), formatPercent(calculateFreePercentage(&s.cpuCapacity, &s.cpuRequested)),
+			formatMemory(&s.memCapacity), formatMemory(&s.memRequested), formatPercent(calculateFreePercentage(&s.memCapacity, &s.memRequested)))
+
+		if zone == "<none>" {
+			continue
+		}
+		if fewest == "" || s.nodes < byZone[fewest].nodes {
+			fewest = zone
+		}
+		if most == "" || s.nodes > byZone[most].nodes {
+			most = zone
+		}
+	}
+	w.Flush()
+
+	// Zonal ASGs rebalance to within one node, so a larger gap means a zone is missing capacity
+	if fewest != "" && byZone[most].nodes-byZone[fewest].nodes > 1 {
+		fmt.Fprintf(out, "\nWarning: zones are unbalanced: %s has %d node(s), %s has %d\n",
+			most, byZone[most].nodes, fewest, byZone[fewest].nodes)
+	}
+}